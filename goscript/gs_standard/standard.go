@@ -0,0 +1,10 @@
+// Package gs_standard embeds the goscript standard library, so the compiler does not depend on its location on disk.
+package gs_standard
+
+import "embed"
+
+// FS contains the modules of the standard library, every directory is a module. The go files of this package are
+// embedded as well, the compiler ignores files outside of module directories.
+//
+//go:embed *
+var FS embed.FS
@@ -0,0 +1,20 @@
+import "std/math"
+
+func main() {
+    let unused: u64 = 5
+    println(first(1, 2))
+    return 0
+    println(99)
+}
+
+func first(a: u64, b: u64) => u64 {
+    return a
+}
+
+func deadA() => u64 {
+    return deadB()
+}
+
+func deadB() => u64 {
+    return 1
+}
\ No newline at end of file
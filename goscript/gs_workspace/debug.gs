@@ -0,0 +1,13 @@
+// a program that fails at runtime inside of a call
+func main() {
+    let divisor: u64 = 0
+
+    // the call fails since the divisor is zero
+    let a: u64 = divide(10, divisor)
+    return a
+}
+
+func divide(a: u64, b: u64) => u64 {
+    let c: u64 = a / b
+    return c
+}
\ No newline at end of file
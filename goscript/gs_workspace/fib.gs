@@ -0,0 +1,12 @@
+application fib
+
+func main() {
+    return fib(20)
+}
+
+func fib(n: u64) => u64 {
+    for let i: u64 = 0; n > 1; i++ {
+        return fib(n - 1) + fib(n - 2)
+    }
+    return n
+}
\ No newline at end of file
@@ -0,0 +1,12 @@
+import "std/math"
+
+func main() {
+    let a: u64 = 3
+    let b: u64 = math.add(a, math.mult(a, 2))
+    return square(b)
+}
+
+func square(x: u64) => u64 {
+    let y: u64 = x * x
+    return y
+}
\ No newline at end of file
@@ -0,0 +1,11 @@
+func main() {
+    for let i: u64 = 0; i < 10; i++ {
+        println(i)
+        continue
+        println(99)
+    }
+    for let j: u64 = 0; j < 10; j++ {
+        break
+    }
+    return 0
+}
\ No newline at end of file
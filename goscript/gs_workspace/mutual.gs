@@ -0,0 +1,17 @@
+func main() {
+    return isOdd(1001)
+}
+
+func isEven(n: u64) => bool {
+    for let i: u64 = 0; n > 0; i++ {
+        return isOdd(n - 1)
+    }
+    return true
+}
+
+func isOdd(n: u64) => bool {
+    for let i: u64 = 0; n > 0; i++ {
+        return isEven(n - 1)
+    }
+    return false
+}
\ No newline at end of file
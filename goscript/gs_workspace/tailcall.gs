@@ -0,0 +1,18 @@
+func main() {
+    let a: u64 = countdown(1000000)
+    return sum(1000000, a)
+}
+
+func countdown(n: u64) => u64 {
+    for let i: u64 = 0; n > 0; i++ {
+        return countdown(n - 1)
+    }
+    return n
+}
+
+func sum(n: u64, acc: u64) => u64 {
+    for let i: u64 = 0; n > 0; i++ {
+        return sum(n - 1, acc + n)
+    }
+    return acc
+}
\ No newline at end of file
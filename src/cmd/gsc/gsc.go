@@ -1,31 +1,95 @@
 package main
 
 import (
+	"crypto/ed25519"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/Yoshi-Exeler/goscript/goscript/gs_standard"
 	"github.com/Yoshi-Exeler/goscript/src/pkg/goscript"
 )
 
 func main() {
-	vendor := flag.String("vendor", goscript.VENDORPATH, "the path to the vendor directory")
+	vendor := flag.String("vendor", "../../../goscript/gs_vendor", "the path to the vendor directory")
 	workspace := flag.String("workspace", "../../tests/", "the path to the root of the workspace")
-	standard := flag.String("standard", goscript.STDPATH, "the path to the standard library")
+	standard := flag.String("standard", "", "the path to a standard library that replaces the embedded one, for developing the standard library")
+	moduleCache := flag.String("modcache", defaultModuleCache(), "the directory into which externals that are not vendored are fetched using git (disabled if empty)")
 	file := flag.String("file", "", "path to the file to compile")
+	dumpFQSC := flag.String("dump-fqsc", "", "path to which the generated fqsc is written for debugging (disabled if empty)")
+	inline := flag.Int("inline", 0, "functions with at most this many operations are inlined (disabled if 0)")
+	verbose := flag.Bool("verbose", false, "report optimization decisions")
+	strip := flag.Bool("strip", false, "omit the debug info from the program for production builds")
+	compression := flag.String("compression", "none", "compress the program with none, flate, zlib or gzip")
+	codecName := flag.String("codec", goscript.DEFAULT_CODEC, fmt.Sprintf("encode the program with one of %v", goscript.Codecs()))
+	sign := flag.String("sign", "", "path to an ed25519 private key in PEM format with which the program is signed (unsigned if empty)")
+	genKey := flag.String("genkey", "", "generate a signing key at this path and its public key at the path with the suffix .pub, then exit")
+
+	cfgDOT := flag.String("cfg-dot", "", "write the control flow graph of every function as a Graphviz DOT file into this directory (disabled if empty)")
+	callGraphDOT := flag.String("callgraph-dot", "", "write the call graph as a Graphviz DOT file to this path (disabled if empty)")
+	depsJSON := flag.String("deps-json", "", "write the module dependency graph as JSON to this path (disabled if empty)")
+	depsDOT := flag.String("deps-dot", "", "write the module dependency graph as a Graphviz DOT file to this path (disabled if empty)")
 
 	flag.Parse()
 
-	fmt.Printf(`Goscript Compiler 0.1`)
+	fmt.Printf("Goscript Compiler %v\n", goscript.COMPILER_VERSION)
+
+	if flag.NArg() > 0 {
+		if flag.NArg() == 2 && flag.Arg(0) == "std" && flag.Arg(1) == "list" {
+			if err := listStandard(*standard); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		log.Fatalf("unknown command %v, the only command is std list", strings.Join(flag.Args(), " "))
+	}
+
+	if *genKey != "" {
+		public, err := goscript.GenerateSigningKey(*genKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("generated signing key %v with public key %x\n", *genKey, []byte(public))
+		return
+	}
+
+	compressionType, err := goscript.CompressionByName(*compression)
+	if err != nil {
+		log.Fatal(err)
+	}
+	codec, err := goscript.CodecByName(*codecName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var signingKey ed25519.PrivateKey
+	if *sign != "" {
+		if signingKey, err = goscript.LoadSigningKey(*sign); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// the default vendor and workspace directories only exist in a checkout of this repository, explicitly specified
+	// directories must exist
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
 
 	comp := goscript.NewCompiler()
 
 	prog, err := comp.Compile(goscript.CompileJob{
 		MainFilePath:       *file,
-		VendorPath:         *vendor,
-		LocalWorkspaceRoot: *workspace,
+		VendorPath:         defaultRoot(*vendor, explicit["vendor"]),
+		LocalWorkspaceRoot: defaultRoot(*workspace, explicit["workspace"]),
 		StandardLibPath:    *standard,
+		ModuleCachePath:    *moduleCache,
+		FQSCDumpPath:       *dumpFQSC,
+		InlineThreshold:    *inline,
+		Verbose:            *verbose,
+		StripDebugInfo:     *strip,
 	})
 
 	if err != nil {
@@ -34,13 +98,92 @@ func main() {
 
 	fmt.Println(prog.String())
 
-	pb, err := goscript.EncodeProgram(prog)
+	if *cfgDOT != "" {
+		paths, err := prog.WriteControlFlowDOT(*cfgDOT)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("wrote %v control flow graphs to %v\n", len(paths), *cfgDOT)
+	}
+	if *callGraphDOT != "" {
+		if err := os.WriteFile(*callGraphDOT, []byte(prog.CallGraphDOT()), 0644); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("wrote call graph to %v\n", *callGraphDOT)
+	}
+	if *depsJSON != "" {
+		buff, err := comp.ModuleGraph().JSON()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*depsJSON, buff, 0644); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("wrote module graph to %v\n", *depsJSON)
+	}
+	if *depsDOT != "" {
+		if err := os.WriteFile(*depsDOT, []byte(comp.ModuleGraph().DOT()), 0644); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("wrote module graph to %v\n", *depsDOT)
+	}
+
+	pb, err := goscript.EncodeContainer(comp.Application(), prog, goscript.ContainerOptions{
+		Codec:       codec,
+		Compression: compressionType,
+		SigningKey:  signingKey,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	hash, err := goscript.HashProgram(prog)
 	if err != nil {
 		panic(err)
 	}
+	fmt.Printf("program hash %x\n", hash)
 
 	err = os.WriteFile("out.pb", pb, 0600)
 	if err != nil {
 		panic(err)
 	}
 }
+
+// defaultModuleCache returns the module cache in the cache directory of the user, fetching is disabled if there is none
+func defaultModuleCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "goscript", "modules")
+}
+
+// defaultRoot returns the directory, or no directory if it is a default that does not exist
+func defaultRoot(dir string, explicit bool) string {
+	if explicit {
+		return dir
+	}
+	if _, err := os.Stat(dir); err != nil {
+		fmt.Printf("ignoring the default directory %v, which does not exist\n", dir)
+		return ""
+	}
+	return dir
+}
+
+// listStandard prints the modules of the standard library and the functions they declare
+func listStandard(path string) error {
+	var fsys fs.FS = gs_standard.FS
+	if path != "" {
+		fsys = os.DirFS(path)
+	}
+	modules, err := goscript.ListModules(fsys, "std")
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "MODULE\tFILES\tFUNCTIONS\n")
+	for _, module := range modules {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", module.ImportPath, strings.Join(module.Files, " "), strings.Join(module.Functions, " "))
+	}
+	return w.Flush()
+}
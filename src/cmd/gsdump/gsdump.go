@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/goscript"
+)
+
+func main() {
+	file := flag.String("file", "out.pb", "the compiled program to inspect")
+	asJSON := flag.Bool("json", false, "print the full program as json instead of the report")
+	asm := flag.Bool("asm", false, "print the listing in the assembly syntax")
+
+	cfgDOT := flag.String("cfg-dot", "", "write the control flow graph of every function as a Graphviz DOT file into this directory (disabled if empty)")
+	callGraphDOT := flag.String("callgraph-dot", "", "write the call graph as a Graphviz DOT file to this path (disabled if empty)")
+
+	flag.Parse()
+
+	buff, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the header is inspected first, so containers that cannot be decoded can still be debugged
+	inspection, err := goscript.InspectContainer(buff)
+	if err != nil {
+		log.Fatal(err)
+	}
+	container, err := goscript.DecodeContainer(buff)
+	if err != nil {
+		out := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+		printHeader(out, *file, len(buff), inspection)
+		out.Flush()
+		fmt.Fprintf(os.Stderr, "\nfailed to decode the program with error %v\n", err)
+		os.Exit(1)
+	}
+	prog := container.Program
+
+	if *cfgDOT != "" {
+		paths, err := prog.WriteControlFlowDOT(*cfgDOT)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "wrote %v control flow graphs to %v\n", len(paths), *cfgDOT)
+	}
+	if *callGraphDOT != "" {
+		if err := os.WriteFile(*callGraphDOT, []byte(prog.CallGraphDOT()), 0644); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "wrote call graph to %v\n", *callGraphDOT)
+	}
+
+	if *asJSON {
+		encoded, err := goscript.ProgramJSON(prog)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(encoded))
+		return
+	}
+
+	hash, err := goscript.HashProgram(prog)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	printHeader(out, *file, len(buff), inspection)
+	fmt.Fprintf(out, "program hash\t%x\n", hash)
+	fmt.Fprintf(out, "operations\t%v\n", len(prog.Operations))
+	fmt.Fprintf(out, "symbol table\t%v symbols\n", prog.SymbolTableSize)
+	fmt.Fprintf(out, "debug info\t%v\n", prog.Debug != nil)
+	out.Flush()
+
+	fmt.Println("\nFUNCTIONS")
+	out = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(out, "BASE\tSIZE\t\tNAME\n")
+	for _, function := range prog.FunctionSizes() {
+		fmt.Fprintf(out, "%v\t%v\t\t%v\n", function.Base, function.Size, function.Name)
+	}
+	out.Flush()
+
+	fmt.Println("\nCONSTANTS")
+	out = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(out, "USES\t\tCONSTANT\n")
+	for _, usage := range prog.ConstantUsage() {
+		fmt.Fprintf(out, "%v\t\t%v\n", usage.Count, usage.Constant)
+	}
+	out.Flush()
+
+	fmt.Println()
+	if *asm {
+		listing, err := goscript.Disassemble(prog)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(listing)
+		return
+	}
+	fmt.Print(prog.String())
+}
+
+// printHeader prints the preamble and the header of the container together with the result of its verification
+func printHeader(out *tabwriter.Writer, file string, size int, inspection *goscript.ContainerInspection) {
+	container := inspection.Container
+	fmt.Fprintf(out, "file\t%v (%v bytes)\n", file, size)
+	if container.FormatVersion == goscript.BYTECODE_FORMAT_VERSION {
+		fmt.Fprintf(out, "format version\t%v\n", container.FormatVersion)
+	} else {
+		fmt.Fprintf(out, "format version\t%v (this runtime requires %v)\n", container.FormatVersion, goscript.BYTECODE_FORMAT_VERSION)
+	}
+	fmt.Fprintf(out, "compiler\tgsc %v\n", container.CompilerVersion)
+	fmt.Fprintf(out, "application\t%v\n", container.Application)
+	fmt.Fprintf(out, "flags\t%v\n", container.Flags)
+	fmt.Fprintf(out, "codec\t%v\n", container.Codec)
+	if container.Compression != goscript.CT_NONE {
+		fmt.Fprintf(out, "compression\t%v (%v bytes uncompressed)\n", container.Compression, inspection.UncompressedSize)
+	} else {
+		fmt.Fprintf(out, "compression\t%v\n", container.Compression)
+	}
+	fmt.Fprintf(out, "payload\t%v bytes, sha256 %x\n", container.PayloadSize, container.Checksum)
+	if inspection.ChecksumError != nil {
+		fmt.Fprintf(out, "checksum\tINVALID: %v\n", inspection.ChecksumError)
+	} else {
+		fmt.Fprintf(out, "checksum\tvalid\n")
+	}
+	switch {
+	case container.Signer == nil:
+		fmt.Fprintf(out, "signed by\tunsigned\n")
+	case inspection.SignatureError != nil:
+		fmt.Fprintf(out, "signed by\t%x\n", []byte(container.Signer))
+		fmt.Fprintf(out, "signature\tINVALID: %v\n", inspection.SignatureError)
+	default:
+		fmt.Fprintf(out, "signed by\t%x\n", []byte(container.Signer))
+		fmt.Fprintf(out, "signature\tvalid\n")
+	}
+}
@@ -1,39 +1,61 @@
 package main
 
 import (
-	"encoding/gob"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/Yoshi-Exeler/goscript/src/pkg/goscript"
 )
 
 func main() {
 	file := flag.String("file", "", "the file to load")
+	codec := flag.String("codec", "", fmt.Sprintf("only run programs encoded with this codec, one of %v (any if empty)", goscript.Codecs()))
+	trust := flag.String("trust", "", "comma separated paths to PEM files of trusted ed25519 public keys, signed programs must be signed by one of them")
+	requireSignature := flag.Bool("require-signature", false, "refuse to run programs that are not signed by a trusted key")
 
 	flag.Parse()
 
+	policy := goscript.TrustPolicy{RequireSignature: *requireSignature}
+	if *trust != "" {
+		for _, path := range strings.Split(*trust, ",") {
+			keys, err := goscript.LoadTrustedKeys(path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			policy.TrustedKeys = append(policy.TrustedKeys, keys...)
+		}
+	}
+
 	rt := goscript.NewRuntime()
 
-	f, err := os.OpenFile(*file, os.O_RDWR, 0600)
+	buff, err := os.ReadFile(*file)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	gob.Register(goscript.BT_ANY)
-	gob.Register(goscript.Expression{})
-	dec := gob.NewDecoder(f)
-
-	var prog goscript.Program
-
-	err = dec.Decode(&prog)
+	container, err := goscript.DecodeTrustedContainer(buff, policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *codec != "" && container.Codec != *codec {
+		log.Fatalf("the program is encoded with codec %v but this runtime only accepts %v", container.Codec, *codec)
+	}
+	err = goscript.Verify(container.Program)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("running application %v compiled by gsc %v\n", container.Application, container.CompilerVersion)
+	if container.Signer != nil {
+		fmt.Printf("signed by %x\n", []byte(container.Signer))
+	}
+	fmt.Println(container.Program.String())
+	v, err := rt.Exec(*container.Program)
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println(prog.String())
-	v := rt.Exec(prog)
 
 	fmt.Println(v)
 }
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.0
+// 	protoc-gen-go v1.28.1
 // 	protoc        v3.21.5
 // source: goscript.proto
 
@@ -9,7 +9,6 @@ package encoding
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
-	anypb "google.golang.org/protobuf/types/known/anypb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -26,12 +25,12 @@ type Expression struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Left     *Expression       `protobuf:"bytes,1,opt,name=Left,proto3" json:"Left,omitempty"`
-	Right    *Expression       `protobuf:"bytes,2,opt,name=Right,proto3" json:"Right,omitempty"`
-	Value    *BinaryTypedValue `protobuf:"bytes,3,opt,name=Value,proto3" json:"Value,omitempty"`
-	Ref      uint64            `protobuf:"varint,4,opt,name=Ref,proto3" json:"Ref,omitempty"`
-	Operator uint32            `protobuf:"varint,5,opt,name=Operator,proto3" json:"Operator,omitempty"`
-	Args     []*anypb.Any      `protobuf:"bytes,6,rep,name=Args,proto3" json:"Args,omitempty"`
+	Left     *Expression         `protobuf:"bytes,1,opt,name=Left,proto3" json:"Left,omitempty"`
+	Right    *Expression         `protobuf:"bytes,2,opt,name=Right,proto3" json:"Right,omitempty"`
+	Value    *BinaryTypedValue   `protobuf:"bytes,3,opt,name=Value,proto3" json:"Value,omitempty"`
+	Ref      uint64              `protobuf:"varint,4,opt,name=Ref,proto3" json:"Ref,omitempty"`
+	Operator uint32              `protobuf:"varint,5,opt,name=Operator,proto3" json:"Operator,omitempty"`
+	Args     []*FunctionArgument `protobuf:"bytes,6,rep,name=Args,proto3" json:"Args,omitempty"`
 }
 
 func (x *Expression) Reset() {
@@ -101,7 +100,7 @@ func (x *Expression) GetOperator() uint32 {
 	return 0
 }
 
-func (x *Expression) GetArgs() []*anypb.Any {
+func (x *Expression) GetArgs() []*FunctionArgument {
 	if x != nil {
 		return x.Args
 	}
@@ -113,8 +112,17 @@ type BinaryTypedValue struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Type  uint32     `protobuf:"varint,1,opt,name=Type,proto3" json:"Type,omitempty"`
-	Value *anypb.Any `protobuf:"bytes,2,opt,name=Value,proto3" json:"Value,omitempty"`
+	Type uint32 `protobuf:"varint,1,opt,name=Type,proto3" json:"Type,omitempty"`
+	// Types that are assignable to Value:
+	//	*BinaryTypedValue_Signed
+	//	*BinaryTypedValue_Unsigned
+	//	*BinaryTypedValue_Float
+	//	*BinaryTypedValue_Bool
+	//	*BinaryTypedValue_Char
+	//	*BinaryTypedValue_String_
+	//	*BinaryTypedValue_List
+	//	*BinaryTypedValue_Expression
+	Value isBinaryTypedValue_Value `protobuf_oneof:"Value"`
 }
 
 func (x *BinaryTypedValue) Reset() {
@@ -156,20 +164,128 @@ func (x *BinaryTypedValue) GetType() uint32 {
 	return 0
 }
 
-func (x *BinaryTypedValue) GetValue() *anypb.Any {
-	if x != nil {
-		return x.Value
+func (m *BinaryTypedValue) GetValue() isBinaryTypedValue_Value {
+	if m != nil {
+		return m.Value
 	}
 	return nil
 }
 
+func (x *BinaryTypedValue) GetSigned() int64 {
+	if x, ok := x.GetValue().(*BinaryTypedValue_Signed); ok {
+		return x.Signed
+	}
+	return 0
+}
+
+func (x *BinaryTypedValue) GetUnsigned() uint64 {
+	if x, ok := x.GetValue().(*BinaryTypedValue_Unsigned); ok {
+		return x.Unsigned
+	}
+	return 0
+}
+
+func (x *BinaryTypedValue) GetFloat() float64 {
+	if x, ok := x.GetValue().(*BinaryTypedValue_Float); ok {
+		return x.Float
+	}
+	return 0
+}
+
+func (x *BinaryTypedValue) GetBool() bool {
+	if x, ok := x.GetValue().(*BinaryTypedValue_Bool); ok {
+		return x.Bool
+	}
+	return false
+}
+
+func (x *BinaryTypedValue) GetChar() int32 {
+	if x, ok := x.GetValue().(*BinaryTypedValue_Char); ok {
+		return x.Char
+	}
+	return 0
+}
+
+func (x *BinaryTypedValue) GetString_() string {
+	if x, ok := x.GetValue().(*BinaryTypedValue_String_); ok {
+		return x.String_
+	}
+	return ""
+}
+
+func (x *BinaryTypedValue) GetList() *ListContainer {
+	if x, ok := x.GetValue().(*BinaryTypedValue_List); ok {
+		return x.List
+	}
+	return nil
+}
+
+func (x *BinaryTypedValue) GetExpression() *Expression {
+	if x, ok := x.GetValue().(*BinaryTypedValue_Expression); ok {
+		return x.Expression
+	}
+	return nil
+}
+
+type isBinaryTypedValue_Value interface {
+	isBinaryTypedValue_Value()
+}
+
+type BinaryTypedValue_Signed struct {
+	Signed int64 `protobuf:"zigzag64,2,opt,name=Signed,proto3,oneof"`
+}
+
+type BinaryTypedValue_Unsigned struct {
+	Unsigned uint64 `protobuf:"varint,3,opt,name=Unsigned,proto3,oneof"`
+}
+
+type BinaryTypedValue_Float struct {
+	Float float64 `protobuf:"fixed64,4,opt,name=Float,proto3,oneof"`
+}
+
+type BinaryTypedValue_Bool struct {
+	Bool bool `protobuf:"varint,5,opt,name=Bool,proto3,oneof"`
+}
+
+type BinaryTypedValue_Char struct {
+	Char int32 `protobuf:"zigzag32,6,opt,name=Char,proto3,oneof"`
+}
+
+type BinaryTypedValue_String_ struct {
+	String_ string `protobuf:"bytes,7,opt,name=String,proto3,oneof"`
+}
+
+type BinaryTypedValue_List struct {
+	List *ListContainer `protobuf:"bytes,8,opt,name=List,proto3,oneof"`
+}
+
+type BinaryTypedValue_Expression struct {
+	Expression *Expression `protobuf:"bytes,9,opt,name=Expression,proto3,oneof"`
+}
+
+func (*BinaryTypedValue_Signed) isBinaryTypedValue_Value() {}
+
+func (*BinaryTypedValue_Unsigned) isBinaryTypedValue_Value() {}
+
+func (*BinaryTypedValue_Float) isBinaryTypedValue_Value() {}
+
+func (*BinaryTypedValue_Bool) isBinaryTypedValue_Value() {}
+
+func (*BinaryTypedValue_Char) isBinaryTypedValue_Value() {}
+
+func (*BinaryTypedValue_String_) isBinaryTypedValue_Value() {}
+
+func (*BinaryTypedValue_List) isBinaryTypedValue_Value() {}
+
+func (*BinaryTypedValue_Expression) isBinaryTypedValue_Value() {}
+
 type BinaryOperation struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Type uint32       `protobuf:"varint,1,opt,name=Type,proto3" json:"Type,omitempty"`
-	Args []*anypb.Any `protobuf:"bytes,2,rep,name=Args,proto3" json:"Args,omitempty"`
+	Type uint32      `protobuf:"varint,1,opt,name=Type,proto3" json:"Type,omitempty"`
+	Args []*Argument `protobuf:"bytes,2,rep,name=Args,proto3" json:"Args,omitempty"`
 }
 
 func (x *BinaryOperation) Reset() {
@@ -211,13 +327,107 @@ func (x *BinaryOperation) GetType() uint32 {
 	return 0
 }
 
-func (x *BinaryOperation) GetArgs() []*anypb.Any {
+func (x *BinaryOperation) GetArgs() []*Argument {
 	if x != nil {
 		return x.Args
 	}
 	return nil
 }
 
+type Argument struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Value:
+	//	*Argument_Int
+	//	*Argument_Type
+	//	*Argument_Expression
+	Value isArgument_Value `protobuf_oneof:"Value"`
+}
+
+func (x *Argument) Reset() {
+	*x = Argument{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_goscript_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Argument) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Argument) ProtoMessage() {}
+
+func (x *Argument) ProtoReflect() protoreflect.Message {
+	mi := &file_goscript_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Argument.ProtoReflect.Descriptor instead.
+func (*Argument) Descriptor() ([]byte, []int) {
+	return file_goscript_proto_rawDescGZIP(), []int{3}
+}
+
+func (m *Argument) GetValue() isArgument_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *Argument) GetInt() int64 {
+	if x, ok := x.GetValue().(*Argument_Int); ok {
+		return x.Int
+	}
+	return 0
+}
+
+func (x *Argument) GetType() uint32 {
+	if x, ok := x.GetValue().(*Argument_Type); ok {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *Argument) GetExpression() *Expression {
+	if x, ok := x.GetValue().(*Argument_Expression); ok {
+		return x.Expression
+	}
+	return nil
+}
+
+type isArgument_Value interface {
+	isArgument_Value()
+}
+
+type Argument_Int struct {
+	Int int64 `protobuf:"zigzag64,1,opt,name=Int,proto3,oneof"`
+}
+
+type Argument_Type struct {
+	Type uint32 `protobuf:"varint,2,opt,name=Type,proto3,oneof"`
+}
+
+type Argument_Expression struct {
+	Expression *Expression `protobuf:"bytes,3,opt,name=Expression,proto3,oneof"`
+}
+
+func (*Argument_Int) isArgument_Value() {}
+
+func (*Argument_Type) isArgument_Value() {}
+
+func (*Argument_Expression) isArgument_Value() {}
+
 type Program struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -225,12 +435,14 @@ type Program struct {
 
 	SymbolTableSize uint64             `protobuf:"varint,1,opt,name=SymbolTableSize,proto3" json:"SymbolTableSize,omitempty"`
 	Operations      []*BinaryOperation `protobuf:"bytes,2,rep,name=Operations,proto3" json:"Operations,omitempty"`
+	Hash            []byte             `protobuf:"bytes,3,opt,name=Hash,proto3" json:"Hash,omitempty"`
+	Debug           *DebugInfo         `protobuf:"bytes,4,opt,name=Debug,proto3" json:"Debug,omitempty"`
 }
 
 func (x *Program) Reset() {
 	*x = Program{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_goscript_proto_msgTypes[3]
+		mi := &file_goscript_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -243,7 +455,7 @@ func (x *Program) String() string {
 func (*Program) ProtoMessage() {}
 
 func (x *Program) ProtoReflect() protoreflect.Message {
-	mi := &file_goscript_proto_msgTypes[3]
+	mi := &file_goscript_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -256,7 +468,7 @@ func (x *Program) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Program.ProtoReflect.Descriptor instead.
 func (*Program) Descriptor() ([]byte, []int) {
-	return file_goscript_proto_rawDescGZIP(), []int{3}
+	return file_goscript_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Program) GetSymbolTableSize() uint64 {
@@ -273,6 +485,20 @@ func (x *Program) GetOperations() []*BinaryOperation {
 	return nil
 }
 
+func (x *Program) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *Program) GetDebug() *DebugInfo {
+	if x != nil {
+		return x.Debug
+	}
+	return nil
+}
+
 type FunctionArgument struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -285,7 +511,7 @@ type FunctionArgument struct {
 func (x *FunctionArgument) Reset() {
 	*x = FunctionArgument{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_goscript_proto_msgTypes[4]
+		mi := &file_goscript_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -298,7 +524,7 @@ func (x *FunctionArgument) String() string {
 func (*FunctionArgument) ProtoMessage() {}
 
 func (x *FunctionArgument) ProtoReflect() protoreflect.Message {
-	mi := &file_goscript_proto_msgTypes[4]
+	mi := &file_goscript_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -311,7 +537,7 @@ func (x *FunctionArgument) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FunctionArgument.ProtoReflect.Descriptor instead.
 func (*FunctionArgument) Descriptor() ([]byte, []int) {
-	return file_goscript_proto_rawDescGZIP(), []int{4}
+	return file_goscript_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *FunctionArgument) GetExpression() *Expression {
@@ -328,31 +554,31 @@ func (x *FunctionArgument) GetSymbolRef() uint64 {
 	return 0
 }
 
-type U64Container struct {
+type ListContainer struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Value uint64 `protobuf:"varint,1,opt,name=Value,proto3" json:"Value,omitempty"`
+	Values []*BinaryTypedValue `protobuf:"bytes,1,rep,name=Values,proto3" json:"Values,omitempty"`
 }
 
-func (x *U64Container) Reset() {
-	*x = U64Container{}
+func (x *ListContainer) Reset() {
+	*x = ListContainer{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_goscript_proto_msgTypes[5]
+		mi := &file_goscript_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *U64Container) String() string {
+func (x *ListContainer) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*U64Container) ProtoMessage() {}
+func (*ListContainer) ProtoMessage() {}
 
-func (x *U64Container) ProtoReflect() protoreflect.Message {
-	mi := &file_goscript_proto_msgTypes[5]
+func (x *ListContainer) ProtoReflect() protoreflect.Message {
+	mi := &file_goscript_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -363,43 +589,45 @@ func (x *U64Container) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use U64Container.ProtoReflect.Descriptor instead.
-func (*U64Container) Descriptor() ([]byte, []int) {
-	return file_goscript_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use ListContainer.ProtoReflect.Descriptor instead.
+func (*ListContainer) Descriptor() ([]byte, []int) {
+	return file_goscript_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *U64Container) GetValue() uint64 {
+func (x *ListContainer) GetValues() []*BinaryTypedValue {
 	if x != nil {
-		return x.Value
+		return x.Values
 	}
-	return 0
+	return nil
 }
 
-type StringContainer struct {
+type DebugInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Value string `protobuf:"bytes,1,opt,name=Value,proto3" json:"Value,omitempty"`
+	Functions []*FunctionInfo `protobuf:"bytes,1,rep,name=Functions,proto3" json:"Functions,omitempty"`
+	Lines     []*LineInfo     `protobuf:"bytes,2,rep,name=Lines,proto3" json:"Lines,omitempty"`
+	Symbols   []*SymbolInfo   `protobuf:"bytes,3,rep,name=Symbols,proto3" json:"Symbols,omitempty"`
 }
 
-func (x *StringContainer) Reset() {
-	*x = StringContainer{}
+func (x *DebugInfo) Reset() {
+	*x = DebugInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_goscript_proto_msgTypes[6]
+		mi := &file_goscript_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *StringContainer) String() string {
+func (x *DebugInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StringContainer) ProtoMessage() {}
+func (*DebugInfo) ProtoMessage() {}
 
-func (x *StringContainer) ProtoReflect() protoreflect.Message {
-	mi := &file_goscript_proto_msgTypes[6]
+func (x *DebugInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_goscript_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -410,43 +638,137 @@ func (x *StringContainer) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StringContainer.ProtoReflect.Descriptor instead.
-func (*StringContainer) Descriptor() ([]byte, []int) {
-	return file_goscript_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use DebugInfo.ProtoReflect.Descriptor instead.
+func (*DebugInfo) Descriptor() ([]byte, []int) {
+	return file_goscript_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *StringContainer) GetValue() string {
+func (x *DebugInfo) GetFunctions() []*FunctionInfo {
 	if x != nil {
-		return x.Value
+		return x.Functions
+	}
+	return nil
+}
+
+func (x *DebugInfo) GetLines() []*LineInfo {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *DebugInfo) GetSymbols() []*SymbolInfo {
+	if x != nil {
+		return x.Symbols
+	}
+	return nil
+}
+
+type FunctionInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Base uint64 `protobuf:"varint,2,opt,name=Base,proto3" json:"Base,omitempty"`
+	End  uint64 `protobuf:"varint,3,opt,name=End,proto3" json:"End,omitempty"`
+	File string `protobuf:"bytes,4,opt,name=File,proto3" json:"File,omitempty"`
+	Line uint64 `protobuf:"varint,5,opt,name=Line,proto3" json:"Line,omitempty"`
+}
+
+func (x *FunctionInfo) Reset() {
+	*x = FunctionInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_goscript_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FunctionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FunctionInfo) ProtoMessage() {}
+
+func (x *FunctionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_goscript_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FunctionInfo.ProtoReflect.Descriptor instead.
+func (*FunctionInfo) Descriptor() ([]byte, []int) {
+	return file_goscript_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *FunctionInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FunctionInfo) GetBase() uint64 {
+	if x != nil {
+		return x.Base
+	}
+	return 0
+}
+
+func (x *FunctionInfo) GetEnd() uint64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *FunctionInfo) GetFile() string {
+	if x != nil {
+		return x.File
 	}
 	return ""
 }
 
-type F64Container struct {
+func (x *FunctionInfo) GetLine() uint64 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+type LineInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Value float64 `protobuf:"fixed64,1,opt,name=Value,proto3" json:"Value,omitempty"`
+	PC   uint64 `protobuf:"varint,1,opt,name=PC,proto3" json:"PC,omitempty"`
+	Line uint64 `protobuf:"varint,2,opt,name=Line,proto3" json:"Line,omitempty"`
 }
 
-func (x *F64Container) Reset() {
-	*x = F64Container{}
+func (x *LineInfo) Reset() {
+	*x = LineInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_goscript_proto_msgTypes[7]
+		mi := &file_goscript_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *F64Container) String() string {
+func (x *LineInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*F64Container) ProtoMessage() {}
+func (*LineInfo) ProtoMessage() {}
 
-func (x *F64Container) ProtoReflect() protoreflect.Message {
-	mi := &file_goscript_proto_msgTypes[7]
+func (x *LineInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_goscript_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -457,43 +779,52 @@ func (x *F64Container) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use F64Container.ProtoReflect.Descriptor instead.
-func (*F64Container) Descriptor() ([]byte, []int) {
-	return file_goscript_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use LineInfo.ProtoReflect.Descriptor instead.
+func (*LineInfo) Descriptor() ([]byte, []int) {
+	return file_goscript_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *F64Container) GetValue() float64 {
+func (x *LineInfo) GetPC() uint64 {
 	if x != nil {
-		return x.Value
+		return x.PC
+	}
+	return 0
+}
+
+func (x *LineInfo) GetLine() uint64 {
+	if x != nil {
+		return x.Line
 	}
 	return 0
 }
 
-type ArrayContainer struct {
+type SymbolInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Values []*BinaryTypedValue `protobuf:"bytes,1,rep,name=Values,proto3" json:"Values,omitempty"`
+	Function string `protobuf:"bytes,1,opt,name=Function,proto3" json:"Function,omitempty"`
+	Index    uint64 `protobuf:"varint,2,opt,name=Index,proto3" json:"Index,omitempty"`
+	Name     string `protobuf:"bytes,3,opt,name=Name,proto3" json:"Name,omitempty"`
 }
 
-func (x *ArrayContainer) Reset() {
-	*x = ArrayContainer{}
+func (x *SymbolInfo) Reset() {
+	*x = SymbolInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_goscript_proto_msgTypes[8]
+		mi := &file_goscript_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *ArrayContainer) String() string {
+func (x *SymbolInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ArrayContainer) ProtoMessage() {}
+func (*SymbolInfo) ProtoMessage() {}
 
-func (x *ArrayContainer) ProtoReflect() protoreflect.Message {
-	mi := &file_goscript_proto_msgTypes[8]
+func (x *SymbolInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_goscript_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -504,77 +835,253 @@ func (x *ArrayContainer) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ArrayContainer.ProtoReflect.Descriptor instead.
-func (*ArrayContainer) Descriptor() ([]byte, []int) {
-	return file_goscript_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use SymbolInfo.ProtoReflect.Descriptor instead.
+func (*SymbolInfo) Descriptor() ([]byte, []int) {
+	return file_goscript_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *ArrayContainer) GetValues() []*BinaryTypedValue {
+func (x *SymbolInfo) GetFunction() string {
 	if x != nil {
-		return x.Values
+		return x.Function
+	}
+	return ""
+}
+
+func (x *SymbolInfo) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *SymbolInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type ContainerHeader struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CompilerVersion  string `protobuf:"bytes,1,opt,name=CompilerVersion,proto3" json:"CompilerVersion,omitempty"`
+	Application      string `protobuf:"bytes,2,opt,name=Application,proto3" json:"Application,omitempty"`
+	Flags            uint32 `protobuf:"varint,3,opt,name=Flags,proto3" json:"Flags,omitempty"`
+	Checksum         []byte `protobuf:"bytes,4,opt,name=Checksum,proto3" json:"Checksum,omitempty"`
+	Compression      uint32 `protobuf:"varint,5,opt,name=Compression,proto3" json:"Compression,omitempty"`
+	Codec            string `protobuf:"bytes,6,opt,name=Codec,proto3" json:"Codec,omitempty"`
+	PublicKey        []byte `protobuf:"bytes,7,opt,name=PublicKey,proto3" json:"PublicKey,omitempty"`
+	UncompressedSize uint64 `protobuf:"varint,9,opt,name=UncompressedSize,proto3" json:"UncompressedSize,omitempty"`
+}
+
+func (x *ContainerHeader) Reset() {
+	*x = ContainerHeader{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_goscript_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ContainerHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerHeader) ProtoMessage() {}
+
+func (x *ContainerHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_goscript_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerHeader.ProtoReflect.Descriptor instead.
+func (*ContainerHeader) Descriptor() ([]byte, []int) {
+	return file_goscript_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ContainerHeader) GetCompilerVersion() string {
+	if x != nil {
+		return x.CompilerVersion
+	}
+	return ""
+}
+
+func (x *ContainerHeader) GetApplication() string {
+	if x != nil {
+		return x.Application
+	}
+	return ""
+}
+
+func (x *ContainerHeader) GetFlags() uint32 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+func (x *ContainerHeader) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+func (x *ContainerHeader) GetCompression() uint32 {
+	if x != nil {
+		return x.Compression
+	}
+	return 0
+}
+
+func (x *ContainerHeader) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *ContainerHeader) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
 	}
 	return nil
 }
 
+func (x *ContainerHeader) GetUncompressedSize() uint64 {
+	if x != nil {
+		return x.UncompressedSize
+	}
+	return 0
+}
+
 var File_goscript_proto protoreflect.FileDescriptor
 
 var file_goscript_proto_rawDesc = []byte{
 	0x0a, 0x0e, 0x67, 0x6f, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x12, 0x08, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x1a, 0x19, 0x67, 0x6f, 0x6f, 0x67,
-	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x61, 0x6e, 0x79, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xec, 0x01, 0x0a, 0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73,
-	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x28, 0x0a, 0x04, 0x4c, 0x65, 0x66, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x12, 0x08, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x22, 0xf2, 0x01, 0x0a, 0x0a, 0x45,
+	0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x28, 0x0a, 0x04, 0x4c, 0x65, 0x66,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x04, 0x4c,
+	0x65, 0x66, 0x74, 0x12, 0x2a, 0x0a, 0x05, 0x52, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01,
 	0x28, 0x0b, 0x32, 0x14, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x45, 0x78,
-	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x04, 0x4c, 0x65, 0x66, 0x74, 0x12, 0x2a,
-	0x0a, 0x05, 0x52, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e,
-	0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73,
-	0x69, 0x6f, 0x6e, 0x52, 0x05, 0x52, 0x69, 0x67, 0x68, 0x74, 0x12, 0x30, 0x0a, 0x05, 0x56, 0x61,
-	0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x65, 0x6e, 0x63, 0x6f,
-	0x64, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x64,
-	0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x10, 0x0a, 0x03,
-	0x52, 0x65, 0x66, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x52, 0x65, 0x66, 0x12, 0x1a,
-	0x0a, 0x08, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d,
-	0x52, 0x08, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x28, 0x0a, 0x04, 0x41, 0x72,
-	0x67, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
-	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79, 0x52, 0x04,
-	0x41, 0x72, 0x67, 0x73, 0x22, 0x52, 0x0a, 0x10, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x54, 0x79,
-	0x70, 0x65, 0x64, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x2a, 0x0a, 0x05,
-	0x56, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e,
-	0x79, 0x52, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x4f, 0x0a, 0x0f, 0x42, 0x69, 0x6e, 0x61,
-	0x72, 0x79, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x54,
-	0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12,
-	0x28, 0x0a, 0x04, 0x41, 0x72, 0x67, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e,
-	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
-	0x41, 0x6e, 0x79, 0x52, 0x04, 0x41, 0x72, 0x67, 0x73, 0x22, 0x6e, 0x0a, 0x07, 0x50, 0x72, 0x6f,
-	0x67, 0x72, 0x61, 0x6d, 0x12, 0x28, 0x0a, 0x0f, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x54, 0x61,
-	0x62, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x53,
-	0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x39,
-	0x0a, 0x0a, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x69,
-	0x6e, 0x61, 0x72, 0x79, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x4f,
-	0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x66, 0x0a, 0x10, 0x46, 0x75, 0x6e,
-	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x41, 0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x34, 0x0a,
-	0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x52, 0x69, 0x67, 0x68, 0x74, 0x12,
+	0x30, 0x0a, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79,
+	0x54, 0x79, 0x70, 0x65, 0x64, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x05, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x12, 0x10, 0x0a, 0x03, 0x52, 0x65, 0x66, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03,
+	0x52, 0x65, 0x66, 0x12, 0x1a, 0x0a, 0x08, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x12,
+	0x2e, 0x0a, 0x04, 0x41, 0x72, 0x67, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x41, 0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x04, 0x41, 0x72, 0x67, 0x73, 0x22,
+	0xac, 0x02, 0x0a, 0x10, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x64, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x06, 0x53, 0x69, 0x67, 0x6e,
+	0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x12, 0x48, 0x00, 0x52, 0x06, 0x53, 0x69, 0x67, 0x6e,
+	0x65, 0x64, 0x12, 0x1c, 0x0a, 0x08, 0x55, 0x6e, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x08, 0x55, 0x6e, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x64,
+	0x12, 0x16, 0x0a, 0x05, 0x46, 0x6c, 0x6f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x48,
+	0x00, 0x52, 0x05, 0x46, 0x6c, 0x6f, 0x61, 0x74, 0x12, 0x14, 0x0a, 0x04, 0x42, 0x6f, 0x6f, 0x6c,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x04, 0x42, 0x6f, 0x6f, 0x6c, 0x12, 0x14,
+	0x0a, 0x04, 0x43, 0x68, 0x61, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x11, 0x48, 0x00, 0x52, 0x04,
+	0x43, 0x68, 0x61, 0x72, 0x12, 0x18, 0x0a, 0x06, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x2d,
+	0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x65,
+	0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x6f, 0x6e, 0x74,
+	0x61, 0x69, 0x6e, 0x65, 0x72, 0x48, 0x00, 0x52, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x36, 0x0a,
+	0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28,
 	0x0b, 0x32, 0x14, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x45, 0x78, 0x70,
-	0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73,
-	0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x52, 0x65, 0x66,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x52, 0x65,
-	0x66, 0x22, 0x24, 0x0a, 0x0c, 0x55, 0x36, 0x34, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65,
-	0x72, 0x12, 0x14, 0x0a, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
-	0x52, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x27, 0x0a, 0x0f, 0x53, 0x74, 0x72, 0x69, 0x6e,
-	0x67, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x56, 0x61,
-	0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65,
-	0x22, 0x24, 0x0a, 0x0c, 0x46, 0x36, 0x34, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72,
-	0x12, 0x14, 0x0a, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52,
-	0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x44, 0x0a, 0x0e, 0x41, 0x72, 0x72, 0x61, 0x79, 0x43,
-	0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x32, 0x0a, 0x06, 0x56, 0x61, 0x6c, 0x75,
-	0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64,
-	0x69, 0x6e, 0x67, 0x2e, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x64, 0x56,
-	0x61, 0x6c, 0x75, 0x65, 0x52, 0x06, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x42, 0x19, 0x48, 0x01,
-	0x5a, 0x15, 0x67, 0x6f, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x65,
-	0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x0a, 0x45, 0x78, 0x70, 0x72, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x07, 0x0a, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x4d,
+	0x0a, 0x0f, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x26, 0x0a, 0x04, 0x41, 0x72, 0x67, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x41,
+	0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x04, 0x41, 0x72, 0x67, 0x73, 0x22, 0x75, 0x0a,
+	0x08, 0x41, 0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x03, 0x49, 0x6e, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x12, 0x48, 0x00, 0x52, 0x03, 0x49, 0x6e, 0x74, 0x12, 0x14, 0x0a,
+	0x04, 0x54, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x04, 0x54,
+	0x79, 0x70, 0x65, 0x12, 0x36, 0x0a, 0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52,
+	0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x07, 0x0a, 0x05, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x22, 0xad, 0x01, 0x0a, 0x07, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x61, 0x6d,
+	0x12, 0x28, 0x0a, 0x0f, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x53,
+	0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x53, 0x79, 0x6d, 0x62, 0x6f,
+	0x6c, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x39, 0x0a, 0x0a, 0x4f, 0x70,
+	0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19,
+	0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79,
+	0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x4f, 0x70, 0x65, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x48, 0x61, 0x73, 0x68, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x04, 0x48, 0x61, 0x73, 0x68, 0x12, 0x29, 0x0a, 0x05, 0x44, 0x65, 0x62,
+	0x75, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64,
+	0x69, 0x6e, 0x67, 0x2e, 0x44, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x44,
+	0x65, 0x62, 0x75, 0x67, 0x22, 0x66, 0x0a, 0x10, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x41, 0x72, 0x67, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x34, 0x0a, 0x0a, 0x45, 0x78, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x65,
+	0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x52, 0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1c,
+	0x0a, 0x09, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x52, 0x65, 0x66, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x09, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x52, 0x65, 0x66, 0x22, 0x43, 0x0a, 0x0d,
+	0x4c, 0x69, 0x73, 0x74, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x32, 0x0a,
+	0x06, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x54,
+	0x79, 0x70, 0x65, 0x64, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x06, 0x56, 0x61, 0x6c, 0x75, 0x65,
+	0x73, 0x22, 0x9b, 0x01, 0x0a, 0x09, 0x44, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e, 0x66, 0x6f, 0x12,
+	0x34, 0x0a, 0x09, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x16, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x46, 0x75,
+	0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x46, 0x75, 0x6e, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x28, 0x0a, 0x05, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e,
+	0x4c, 0x69, 0x6e, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x12,
+	0x2e, 0x0a, 0x07, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x53, 0x79, 0x6d, 0x62,
+	0x6f, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x22,
+	0x70, 0x0a, 0x0c, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x66, 0x6f, 0x12,
+	0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x42, 0x61, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x04, 0x42, 0x61, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x45, 0x6e, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x45, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x46, 0x69, 0x6c,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x4c, 0x69, 0x6e, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x4c, 0x69, 0x6e,
+	0x65, 0x22, 0x2e, 0x0a, 0x08, 0x4c, 0x69, 0x6e, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x0e, 0x0a,
+	0x02, 0x50, 0x43, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x50, 0x43, 0x12, 0x12, 0x0a,
+	0x04, 0x4c, 0x69, 0x6e, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x4c, 0x69, 0x6e,
+	0x65, 0x22, 0x52, 0x0a, 0x0a, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x12,
+	0x1a, 0x0a, 0x08, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x49,
+	0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x49, 0x6e, 0x64, 0x65,
+	0x78, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x91, 0x02, 0x0a, 0x0f, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69,
+	0x6e, 0x65, 0x72, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x28, 0x0a, 0x0f, 0x43, 0x6f, 0x6d,
+	0x70, 0x69, 0x6c, 0x65, 0x72, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0f, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x72, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x41, 0x70, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x41, 0x70, 0x70, 0x6c, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x43,
+	0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x43,
+	0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x12, 0x20, 0x0a, 0x0b, 0x43, 0x6f, 0x6d, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x43, 0x6f,
+	0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x43, 0x6f, 0x64,
+	0x65, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x43, 0x6f, 0x64, 0x65, 0x63, 0x12,
+	0x1c, 0x0a, 0x09, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x09, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x2a, 0x0a,
+	0x10, 0x55, 0x6e, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x65, 0x64, 0x53, 0x69, 0x7a,
+	0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10, 0x55, 0x6e, 0x63, 0x6f, 0x6d, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x65, 0x64, 0x53, 0x69, 0x7a, 0x65, 0x42, 0x19, 0x48, 0x01, 0x5a, 0x15, 0x67,
+	0x6f, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x65, 0x6e, 0x63, 0x6f,
+	0x64, 0x69, 0x6e, 0x67, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -589,34 +1096,42 @@ func file_goscript_proto_rawDescGZIP() []byte {
 	return file_goscript_proto_rawDescData
 }
 
-var file_goscript_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_goscript_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_goscript_proto_goTypes = []interface{}{
 	(*Expression)(nil),       // 0: encoding.Expression
 	(*BinaryTypedValue)(nil), // 1: encoding.BinaryTypedValue
 	(*BinaryOperation)(nil),  // 2: encoding.BinaryOperation
-	(*Program)(nil),          // 3: encoding.Program
-	(*FunctionArgument)(nil), // 4: encoding.FunctionArgument
-	(*U64Container)(nil),     // 5: encoding.U64Container
-	(*StringContainer)(nil),  // 6: encoding.StringContainer
-	(*F64Container)(nil),     // 7: encoding.F64Container
-	(*ArrayContainer)(nil),   // 8: encoding.ArrayContainer
-	(*anypb.Any)(nil),        // 9: google.protobuf.Any
+	(*Argument)(nil),         // 3: encoding.Argument
+	(*Program)(nil),          // 4: encoding.Program
+	(*FunctionArgument)(nil), // 5: encoding.FunctionArgument
+	(*ListContainer)(nil),    // 6: encoding.ListContainer
+	(*DebugInfo)(nil),        // 7: encoding.DebugInfo
+	(*FunctionInfo)(nil),     // 8: encoding.FunctionInfo
+	(*LineInfo)(nil),         // 9: encoding.LineInfo
+	(*SymbolInfo)(nil),       // 10: encoding.SymbolInfo
+	(*ContainerHeader)(nil),  // 11: encoding.ContainerHeader
 }
 var file_goscript_proto_depIdxs = []int32{
-	0, // 0: encoding.Expression.Left:type_name -> encoding.Expression
-	0, // 1: encoding.Expression.Right:type_name -> encoding.Expression
-	1, // 2: encoding.Expression.Value:type_name -> encoding.BinaryTypedValue
-	9, // 3: encoding.Expression.Args:type_name -> google.protobuf.Any
-	9, // 4: encoding.BinaryTypedValue.Value:type_name -> google.protobuf.Any
-	9, // 5: encoding.BinaryOperation.Args:type_name -> google.protobuf.Any
-	2, // 6: encoding.Program.Operations:type_name -> encoding.BinaryOperation
-	0, // 7: encoding.FunctionArgument.Expression:type_name -> encoding.Expression
-	1, // 8: encoding.ArrayContainer.Values:type_name -> encoding.BinaryTypedValue
-	9, // [9:9] is the sub-list for method output_type
-	9, // [9:9] is the sub-list for method input_type
-	9, // [9:9] is the sub-list for extension type_name
-	9, // [9:9] is the sub-list for extension extendee
-	0, // [0:9] is the sub-list for field type_name
+	0,  // 0: encoding.Expression.Left:type_name -> encoding.Expression
+	0,  // 1: encoding.Expression.Right:type_name -> encoding.Expression
+	1,  // 2: encoding.Expression.Value:type_name -> encoding.BinaryTypedValue
+	5,  // 3: encoding.Expression.Args:type_name -> encoding.FunctionArgument
+	6,  // 4: encoding.BinaryTypedValue.List:type_name -> encoding.ListContainer
+	0,  // 5: encoding.BinaryTypedValue.Expression:type_name -> encoding.Expression
+	3,  // 6: encoding.BinaryOperation.Args:type_name -> encoding.Argument
+	0,  // 7: encoding.Argument.Expression:type_name -> encoding.Expression
+	2,  // 8: encoding.Program.Operations:type_name -> encoding.BinaryOperation
+	7,  // 9: encoding.Program.Debug:type_name -> encoding.DebugInfo
+	0,  // 10: encoding.FunctionArgument.Expression:type_name -> encoding.Expression
+	1,  // 11: encoding.ListContainer.Values:type_name -> encoding.BinaryTypedValue
+	8,  // 12: encoding.DebugInfo.Functions:type_name -> encoding.FunctionInfo
+	9,  // 13: encoding.DebugInfo.Lines:type_name -> encoding.LineInfo
+	10, // 14: encoding.DebugInfo.Symbols:type_name -> encoding.SymbolInfo
+	15, // [15:15] is the sub-list for method output_type
+	15, // [15:15] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_goscript_proto_init() }
@@ -662,7 +1177,7 @@ func file_goscript_proto_init() {
 			}
 		}
 		file_goscript_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Program); i {
+			switch v := v.(*Argument); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -674,7 +1189,7 @@ func file_goscript_proto_init() {
 			}
 		}
 		file_goscript_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FunctionArgument); i {
+			switch v := v.(*Program); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -686,7 +1201,7 @@ func file_goscript_proto_init() {
 			}
 		}
 		file_goscript_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*U64Container); i {
+			switch v := v.(*FunctionArgument); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -698,7 +1213,7 @@ func file_goscript_proto_init() {
 			}
 		}
 		file_goscript_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*StringContainer); i {
+			switch v := v.(*ListContainer); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -710,7 +1225,7 @@ func file_goscript_proto_init() {
 			}
 		}
 		file_goscript_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*F64Container); i {
+			switch v := v.(*DebugInfo); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -722,7 +1237,19 @@ func file_goscript_proto_init() {
 			}
 		}
 		file_goscript_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ArrayContainer); i {
+			switch v := v.(*FunctionInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_goscript_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LineInfo); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -733,6 +1260,45 @@ func file_goscript_proto_init() {
 				return nil
 			}
 		}
+		file_goscript_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SymbolInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_goscript_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ContainerHeader); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_goscript_proto_msgTypes[1].OneofWrappers = []interface{}{
+		(*BinaryTypedValue_Signed)(nil),
+		(*BinaryTypedValue_Unsigned)(nil),
+		(*BinaryTypedValue_Float)(nil),
+		(*BinaryTypedValue_Bool)(nil),
+		(*BinaryTypedValue_Char)(nil),
+		(*BinaryTypedValue_String_)(nil),
+		(*BinaryTypedValue_List)(nil),
+		(*BinaryTypedValue_Expression)(nil),
+	}
+	file_goscript_proto_msgTypes[3].OneofWrappers = []interface{}{
+		(*Argument_Int)(nil),
+		(*Argument_Type)(nil),
+		(*Argument_Expression)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -740,7 +1306,7 @@ func file_goscript_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_goscript_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,1088 @@
+package goscript
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
+)
+
+/*
+	Programs can be written in a textual assembly syntax. Disassemble renders a program in this syntax and Assemble
+	parses the text back into the same program, which makes it possible to write bytecode by hand:
+
+		.symbols 2                       ; the size of the symbol table, defaults to the highest symbol used + 1
+		main:                            ; a label names the address of the operation that follows it
+			BIND $0.i, UINT64            ; symbols are written as $index, optionally followed by their name
+			ASSIGN $0.i, UINT64 0        ; constants are written as their type followed by a literal
+		loop:
+			JUMP_IF_NOT ($0 < UINT64 3), @done
+			EXPRESSION println($0)
+			ASSIGN $0, ($0 + UINT64 1):UINT64
+			JUMP @loop                   ; jump targets are written as @label or as the address @3
+		done:
+			RETURN call @square($1 = $0):UINT64
+		square:
+			RETURN ($1 * $1):UINT64
+
+	Every line holds one operation, its operands are separated by commas and appear in the order of the arguments
+	of the operation (see operationArgs). Jump targets are the address of the operation that is executed next, the
+	assembler stores them as the address before it like the New*Op functions do. Comments begin with a semicolon.
+
+	Expressions are written as
+	- TYPE literal                         a constant, the literal is a number, a quoted string or char, true,
+	                                       false, nil, [TYPE literal, ...] for arrays or {expression}
+	- $symbol                              a symbol
+	- $symbol[expression]                  an index into an array symbol
+	- call @function($param = expr, ...)   a function call, which assigns the arguments to the parameter symbols
+	- name(expr, ...)                      a builtin call, see builtins
+	- null                                 the null expression
+	- (expression OP expression)           an operator, one of + - * / == > < >= <=
+
+	Any expression except constants and indices can be followed by an annotation :TYPE or :TYPE literal, which
+	sets the value of the expression. Calls use it for their return type. Operators store their result in their
+	value, so :TYPE stands for the zero value of numeric types on operators.
+
+	Names are informational and ignored by the assembler. The disassembler takes them from the debug info of the
+	program if it has any, otherwise functions and jump targets are labeled L<address>.
+*/
+
+// assemblyOperand is the kind of an operand of an operation in the assembly syntax
+type assemblyOperand byte
+
+const (
+	AO_SYMBOL     assemblyOperand = 1
+	AO_AMOUNT     assemblyOperand = 2
+	AO_TARGET     assemblyOperand = 3
+	AO_TYPE       assemblyOperand = 4
+	AO_EXPRESSION assemblyOperand = 5
+)
+
+// assemblyOperands specifies the operands of every operation, they must match operationArgs
+var assemblyOperands = map[OperationType][]assemblyOperand{
+	ASSIGN:       {AO_SYMBOL, AO_EXPRESSION},
+	INDEX_ASSIGN: {AO_SYMBOL, AO_EXPRESSION, AO_EXPRESSION},
+	BIND:         {AO_SYMBOL, AO_TYPE},
+	RETURN:       {AO_EXPRESSION},
+	EXPRESSION:   {AO_EXPRESSION},
+	ENTER_SCOPE:  {},
+	EXIT_SCOPE:   {},
+	JUMP:         {AO_TARGET},
+	JUMP_IF:      {AO_EXPRESSION, AO_TARGET},
+	JUMP_IF_NOT:  {AO_EXPRESSION, AO_TARGET},
+	GROW:         {AO_SYMBOL, AO_AMOUNT, AO_TYPE},
+	SHRINK:       {AO_SYMBOL, AO_AMOUNT},
+}
+
+var operationNames = map[OperationType]string{
+	ASSIGN:       "ASSIGN",
+	INDEX_ASSIGN: "INDEX_ASSIGN",
+	BIND:         "BIND",
+	RETURN:       "RETURN",
+	EXPRESSION:   "EXPRESSION",
+	ENTER_SCOPE:  "ENTER_SCOPE",
+	EXIT_SCOPE:   "EXIT_SCOPE",
+	JUMP:         "JUMP",
+	JUMP_IF:      "JUMP_IF",
+	JUMP_IF_NOT:  "JUMP_IF_NOT",
+	GROW:         "GROW",
+	SHRINK:       "SHRINK",
+}
+
+var operatorSymbols = map[BinaryOperator]string{
+	BO_PLUS:           "+",
+	BO_MINUS:          "-",
+	BO_MULTIPLY:       "*",
+	BO_DIVIDE:         "/",
+	BO_EQUALS:         "==",
+	BO_GREATER:        ">",
+	BO_LESSER:         "<",
+	BO_GREATER_EQUALS: ">=",
+	BO_LESSER_EQUALS:  "<=",
+}
+
+// assemblyTypeName returns the name of the type in the assembly syntax, untyped values are used for unresolved symbols
+func assemblyTypeName(valueType BinaryType) (string, error) {
+	if valueType == 0 {
+		return "UNTYPED", nil
+	}
+	name := valueType.String()
+	if name == "invalid type" {
+		return "", fmt.Errorf("unknown type %v", byte(valueType))
+	}
+	return name, nil
+}
+
+// Disassemble renders the program in the assembly syntax, names are taken from the debug info if the program has any
+func Disassemble(program *Program) (string, error) {
+	d := &disassembler{program: program, labels: map[int]string{}}
+	d.collectLabels()
+	out := &strings.Builder{}
+	fmt.Fprintf(out, ".symbols %v\n", program.SymbolTableSize)
+	for pc := range program.Operations {
+		if label, ok := d.labels[pc]; ok {
+			fmt.Fprintf(out, "%v:\n", label)
+		}
+		line, err := d.operation(pc)
+		if err != nil {
+			return "", fmt.Errorf("cannot disassemble operation %v with error %v", pc, err)
+		}
+		fmt.Fprintf(out, "\t%v\n", line)
+	}
+	return out.String(), nil
+}
+
+type disassembler struct {
+	program *Program
+	labels  map[int]string // the labels of all functions and jump targets by address
+}
+
+// collectLabels labels functions after their name in the debug info and all other addresses L<address>
+func (d *disassembler) collectLabels() {
+	functions := map[int]bool{0: true}
+	targets := map[int]bool{}
+	d.program.forEachExpression(func(pc int, expr *Expression) {
+		if expr.Operator == BO_FUNCTION_CALL {
+			functions[expr.Ref] = true
+		}
+	})
+	for _, op := range d.program.Operations {
+		for i, kind := range assemblyOperands[op.Type] {
+			if kind == AO_TARGET && i < len(op.Args) {
+				if target, ok := op.Args[i].(int); ok {
+					targets[target+1] = true
+				}
+			}
+		}
+	}
+	names := map[int]string{}
+	if d.program.Debug != nil {
+		for _, function := range d.program.Debug.Functions {
+			names[function.Base] = function.Name
+			functions[function.Base] = true
+		}
+	}
+	addresses := []int{}
+	for address := range functions {
+		addresses = append(addresses, address)
+	}
+	for address := range targets {
+		if !functions[address] {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Ints(addresses)
+	used := map[string]bool{}
+	for _, address := range addresses {
+		if address < 0 || address >= len(d.program.Operations) {
+			continue
+		}
+		label := names[address]
+		if !isAssemblyName(label) || used[label] {
+			label = fmt.Sprintf("L%v", address)
+		}
+		used[label] = true
+		d.labels[address] = label
+	}
+}
+
+func (d *disassembler) operation(pc int) (string, error) {
+	op := &d.program.Operations[pc]
+	operands, ok := assemblyOperands[op.Type]
+	if !ok {
+		return "", fmt.Errorf("unknown operation type %v", op.Type)
+	}
+	if len(op.Args) != len(operands) {
+		return "", fmt.Errorf("operation type %v expects %v arguments but has %v", op.Type, len(operands), len(op.Args))
+	}
+	parts := make([]string, len(operands))
+	for i, kind := range operands {
+		var err error
+		switch kind {
+		case AO_SYMBOL, AO_AMOUNT, AO_TARGET:
+			val, ok := op.Args[i].(int)
+			if !ok {
+				return "", fmt.Errorf("argument %v of operation type %v must be an int but is %T", i, op.Type, op.Args[i])
+			}
+			switch kind {
+			case AO_SYMBOL:
+				parts[i] = d.symbol(d.functionAt(pc), val)
+			case AO_AMOUNT:
+				parts[i] = strconv.Itoa(val)
+			default:
+				parts[i] = d.target(val + 1)
+			}
+		case AO_TYPE:
+			val, ok := op.Args[i].(BinaryType)
+			if !ok {
+				return "", fmt.Errorf("argument %v of operation type %v must be a type but is %T", i, op.Type, op.Args[i])
+			}
+			parts[i], err = assemblyTypeName(val)
+		case AO_EXPRESSION:
+			expr, ok := op.Args[i].(*Expression)
+			if !ok {
+				return "", fmt.Errorf("argument %v of operation type %v must be an expression but is %T", i, op.Type, op.Args[i])
+			}
+			parts[i], err = d.expression(pc, expr)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(parts) == 0 {
+		return operationNames[op.Type], nil
+	}
+	return operationNames[op.Type] + " " + strings.Join(parts, ", "), nil
+}
+
+// functionAt returns the name of the function the operation at pc belongs to or an empty string if it is unknown
+func (d *disassembler) functionAt(pc int) string {
+	if function := d.program.Debug.functionInfoAt(pc); function != nil {
+		return function.Name
+	}
+	return ""
+}
+
+func (d *disassembler) symbol(function string, ref int) string {
+	if name := d.program.Debug.SymbolName(function, ref); isAssemblyName(name) {
+		return fmt.Sprintf("$%v.%v", ref, name)
+	}
+	return fmt.Sprintf("$%v", ref)
+}
+
+func (d *disassembler) target(address int) string {
+	if label, ok := d.labels[address]; ok {
+		return "@" + label
+	}
+	return fmt.Sprintf("@%v", address)
+}
+
+func (d *disassembler) expression(pc int, expr *Expression) (string, error) {
+	if expr == nil {
+		return "", fmt.Errorf("missing expression")
+	}
+	var text string
+	switch expr.Operator {
+	case BO_CONSTANT:
+		if err := representable(expr, false, false, false); err != nil {
+			return "", err
+		}
+		if expr.Value == nil {
+			return "", fmt.Errorf("constant expression without value")
+		}
+		return d.value(pc, expr.Value)
+	case BO_VSYMBOL:
+		if err := representable(expr, true, false, false); err != nil {
+			return "", err
+		}
+		text = d.symbol(d.functionAt(pc), expr.Ref)
+	case BO_INDEX_INTO:
+		if err := representable(expr, true, false, false); err != nil {
+			return "", err
+		}
+		if expr.Value == nil || expr.Value.Type != BT_EXPRESSION {
+			return "", fmt.Errorf("index expression without index")
+		}
+		index, ok := expr.Value.Value.(*Expression)
+		if !ok {
+			return "", fmt.Errorf("index expression without index")
+		}
+		indexText, err := d.expression(pc, index)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%v[%v]", d.symbol(d.functionAt(pc), expr.Ref), indexText), nil
+	case BO_FUNCTION_CALL:
+		if err := representable(expr, true, true, false); err != nil {
+			return "", err
+		}
+		args, err := d.arguments(pc, expr, d.functionAt(expr.Ref), true)
+		if err != nil {
+			return "", err
+		}
+		text = fmt.Sprintf("call %v(%v)", d.target(expr.Ref), args)
+	case BO_BUILTIN_CALL:
+		if err := representable(expr, true, true, false); err != nil {
+			return "", err
+		}
+		name, ok := builtinNames[BuiltinFunction(expr.Ref)]
+		if !ok || expr.Ref != int(BuiltinFunction(expr.Ref)) {
+			return "", fmt.Errorf("unknown builtin %v", expr.Ref)
+		}
+		args, err := d.arguments(pc, expr, "", false)
+		if err != nil {
+			return "", err
+		}
+		text = fmt.Sprintf("%v(%v)", name, args)
+	case BO_NULLEXPR:
+		if err := representable(expr, false, false, false); err != nil {
+			return "", err
+		}
+		text = "null"
+	default:
+		symbol, ok := operatorSymbols[expr.Operator]
+		if !ok {
+			return "", fmt.Errorf("unknown operator %v", byte(expr.Operator))
+		}
+		if err := representable(expr, false, false, true); err != nil {
+			return "", err
+		}
+		if expr.LeftExpression == nil || expr.RightExpression == nil {
+			return "", fmt.Errorf("operator %v requires two operands", symbol)
+		}
+		left, err := d.expression(pc, expr.LeftExpression)
+		if err != nil {
+			return "", err
+		}
+		right, err := d.expression(pc, expr.RightExpression)
+		if err != nil {
+			return "", err
+		}
+		text = fmt.Sprintf("(%v %v %v)", left, symbol, right)
+	}
+	annotation, err := d.annotation(pc, expr)
+	if err != nil {
+		return "", err
+	}
+	return text + annotation, nil
+}
+
+// representable checks that the expression does not use fields the syntax of its operator cannot express
+func representable(expr *Expression, ref bool, args bool, operands bool) error {
+	if (!ref && expr.Ref != 0) || (!args && len(expr.Args) > 0) || (!operands && (expr.LeftExpression != nil || expr.RightExpression != nil)) {
+		return fmt.Errorf("expression with operator %v has unexpected fields", byte(expr.Operator))
+	}
+	return nil
+}
+
+// arguments renders the arguments of a call, the parameters of builtins are omitted unless they are set
+func (d *disassembler) arguments(pc int, expr *Expression, callee string, function bool) (string, error) {
+	parts := make([]string, len(expr.Args))
+	for i, arg := range expr.Args {
+		if arg == nil {
+			return "", fmt.Errorf("call with a missing argument")
+		}
+		text, err := d.expression(pc, arg.Expression)
+		if err != nil {
+			return "", err
+		}
+		if function || arg.SymbolRef != 0 {
+			text = fmt.Sprintf("%v = %v", d.symbol(callee, arg.SymbolRef), text)
+		}
+		parts[i] = text
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// annotation renders the value of a non constant expression, see the syntax description above
+func (d *disassembler) annotation(pc int, expr *Expression) (string, error) {
+	if expr.Value == nil {
+		return "", nil
+	}
+	typeName, err := assemblyTypeName(expr.Value.Type)
+	if err != nil {
+		return "", err
+	}
+	_, operator := operatorSymbols[expr.Operator]
+	if operator && expr.Value.Type.isNumeric() {
+		if expr.Value.Value != nil {
+			literal, err := d.literal(pc, expr.Value)
+			if err != nil {
+				return "", err
+			}
+			if literal == "0" {
+				return ":" + typeName, nil
+			}
+			return fmt.Sprintf(":%v %v", typeName, literal), nil
+		}
+	} else if expr.Value.Value == nil {
+		return ":" + typeName, nil
+	}
+	literal, err := d.literal(pc, expr.Value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(":%v %v", typeName, literal), nil
+}
+
+// value renders a typed value as its type followed by its literal
+func (d *disassembler) value(pc int, value *BinaryTypedValue) (string, error) {
+	typeName, err := assemblyTypeName(value.Type)
+	if err != nil {
+		return "", err
+	}
+	literal, err := d.literal(pc, value)
+	if err != nil {
+		return "", err
+	}
+	return typeName + " " + literal, nil
+}
+
+func (d *disassembler) literal(pc int, value *BinaryTypedValue) (string, error) {
+	if value.Value == nil {
+		return "nil", nil
+	}
+	switch value.Type {
+	case BT_LIST:
+		elements, err := valueOf[[]*BinaryTypedValue](value)
+		if err != nil {
+			return "", err
+		}
+		parts := make([]string, len(elements))
+		for i, elem := range elements {
+			if elem == nil {
+				return "", fmt.Errorf("array with a missing element")
+			}
+			if parts[i], err = d.value(pc, elem); err != nil {
+				return "", err
+			}
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case BT_EXPRESSION:
+		expr, ok := value.Value.(*Expression)
+		if !ok {
+			return "", fmt.Errorf("cannot encode %T as a value of type %v", value.Value, value.Type)
+		}
+		text, err := d.expression(pc, expr)
+		if err != nil {
+			return "", err
+		}
+		return "{" + text + "}", nil
+	}
+	// the encoder checks that the go type of the value matches its type
+	encValue, err := encodeTypedValue(value)
+	if err != nil {
+		return "", err
+	}
+	switch val := encValue.Value.(type) {
+	case *encoding.BinaryTypedValue_Signed:
+		return strconv.FormatInt(val.Signed, 10), nil
+	case *encoding.BinaryTypedValue_Unsigned:
+		return strconv.FormatUint(val.Unsigned, 10), nil
+	case *encoding.BinaryTypedValue_Float:
+		if value.Type == BT_FLOAT32 {
+			return strconv.FormatFloat(val.Float, 'g', -1, 32), nil
+		}
+		return strconv.FormatFloat(val.Float, 'g', -1, 64), nil
+	case *encoding.BinaryTypedValue_Bool:
+		return strconv.FormatBool(val.Bool), nil
+	case *encoding.BinaryTypedValue_Char:
+		// chars that are not valid runes cannot be quoted without losing their value
+		if !utf8.ValidRune(val.Char) {
+			return strconv.FormatInt(int64(val.Char), 10), nil
+		}
+		return strconv.QuoteRune(val.Char), nil
+	case *encoding.BinaryTypedValue_String_:
+		return strconv.Quote(val.String_), nil
+	default:
+		return "", fmt.Errorf("cannot render values of type %v", value.Type)
+	}
+}
+
+// isAssemblyName checks that the name can be used as a label or symbol name
+func isAssemblyName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isAssemblyNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAssemblyNameChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '#' || c == '.'
+}
+
+// assemblyTokenKind is the kind of a token of the assembly syntax
+type assemblyTokenKind byte
+
+const (
+	AT_WORD    assemblyTokenKind = 1 // operation names, labels, types, builtins and keywords
+	AT_NUMBER  assemblyTokenKind = 2
+	AT_STRING  assemblyTokenKind = 3
+	AT_CHAR    assemblyTokenKind = 4
+	AT_SYMBOL  assemblyTokenKind = 5 // $index or $index.name
+	AT_TARGET  assemblyTokenKind = 6 // @label or @address
+	AT_PUNCT   assemblyTokenKind = 7 // punctuation and operators
+	AT_NEWLINE assemblyTokenKind = 8
+	AT_EOF     assemblyTokenKind = 9
+)
+
+type assemblyToken struct {
+	Kind assemblyTokenKind
+	Text string
+	Line int
+}
+
+func (t assemblyToken) String() string {
+	switch t.Kind {
+	case AT_NEWLINE:
+		return "end of line"
+	case AT_EOF:
+		return "end of input"
+	default:
+		return strconv.Quote(t.Text)
+	}
+}
+
+// assemblyPunctuation holds all punctuation, longer tokens must come first
+var assemblyPunctuation = []string{"==", ">=", "<=", "(", ")", "[", "]", "{", "}", ",", ":", "=", "+", "-", "*", "/", ">", "<"}
+
+func tokenizeAssembly(text string) ([]assemblyToken, error) {
+	tokens := []assemblyToken{}
+	line := 1
+	emit := func(kind assemblyTokenKind, text string) {
+		tokens = append(tokens, assemblyToken{Kind: kind, Text: text, Line: line})
+	}
+	// consumeName returns the index after the name beginning at i
+	consumeName := func(i int) int {
+		for i < len(text) && isAssemblyNameChar(text[i]) {
+			i++
+		}
+		return i
+	}
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\n':
+			emit(AT_NEWLINE, "\n")
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == ';':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quoted, err := strconv.QuotedPrefix(text[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid quoted literal", line)
+			}
+			if c == '"' {
+				emit(AT_STRING, quoted)
+			} else {
+				emit(AT_CHAR, quoted)
+			}
+			i += len(quoted)
+		case c == '$':
+			j := i + 1
+			for j < len(text) && text[j] >= '0' && text[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("line %v: expected the index of a symbol after $", line)
+			}
+			if j < len(text) && text[j] == '.' {
+				j = consumeName(j + 1)
+			}
+			emit(AT_SYMBOL, text[i:j])
+			i = j
+		case c == '@':
+			j := i + 1
+			if j < len(text) && (text[j] == '-' || text[j] == '+') {
+				j++
+			}
+			j = consumeName(j)
+			if j == i+1 {
+				return nil, fmt.Errorf("line %v: expected a label or address after @", line)
+			}
+			emit(AT_TARGET, text[i:j])
+			i = j
+		case (c >= '0' && c <= '9') || ((c == '-' || c == '+') && i+1 < len(text) && ((text[i+1] >= '0' && text[i+1] <= '9') || text[i+1] == 'I')):
+			// numbers include signs, exponents and the infinities
+			j := i + 1
+			for j < len(text) && (isAssemblyNameChar(text[j]) || ((text[j] == '-' || text[j] == '+') && (text[j-1] == 'e' || text[j-1] == 'E'))) {
+				j++
+			}
+			emit(AT_NUMBER, text[i:j])
+			i = j
+		case isAssemblyNameChar(c):
+			j := consumeName(i)
+			emit(AT_WORD, text[i:j])
+			i = j
+		default:
+			found := false
+			for _, punct := range assemblyPunctuation {
+				if strings.HasPrefix(text[i:], punct) {
+					emit(AT_PUNCT, punct)
+					i += len(punct)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("line %v: unexpected character %q", line, c)
+			}
+		}
+	}
+	emit(AT_EOF, "")
+	return tokens, nil
+}
+
+// the reverse lookups of the names used by the assembly syntax
+var (
+	operationsByName  = map[string]OperationType{}
+	operatorsBySymbol = map[string]BinaryOperator{}
+	typesByName       = map[string]BinaryType{}
+	builtinNames      = map[BuiltinFunction]string{}
+)
+
+func init() {
+	for op, name := range operationNames {
+		operationsByName[name] = op
+	}
+	for op, symbol := range operatorSymbols {
+		operatorsBySymbol[symbol] = op
+	}
+	for i := 0; i < 256; i++ {
+		if name, err := assemblyTypeName(BinaryType(i)); err == nil {
+			typesByName[name] = BinaryType(i)
+		}
+	}
+	for name, builtin := range builtins {
+		builtinNames[builtin] = name
+	}
+}
+
+// Assemble parses a program written in the assembly syntax, see Disassemble
+func Assemble(text string) (*Program, error) {
+	tokens, err := tokenizeAssembly(text)
+	if err != nil {
+		return nil, err
+	}
+	a := &assembler{tokens: tokens, program: &Program{Operations: []BinaryOperation{}}, labels: map[string]int{}}
+	symbolTableSize, sized := 0, false
+	for a.peek().Kind != AT_EOF {
+		tok := a.next()
+		switch {
+		case tok.Kind == AT_NEWLINE:
+			continue
+		case tok.Kind == AT_WORD && tok.Text == ".symbols":
+			if symbolTableSize, err = a.integer(); err != nil {
+				return nil, err
+			}
+			sized = true
+		case tok.Kind == AT_WORD && a.peek().Kind == AT_PUNCT && a.peek().Text == ":":
+			a.next()
+			if _, ok := a.labels[tok.Text]; ok {
+				return nil, fmt.Errorf("line %v: label %v is already defined", tok.Line, tok.Text)
+			}
+			a.labels[tok.Text] = len(a.program.Operations)
+			// the operation may follow on the same line
+			continue
+		case tok.Kind == AT_WORD:
+			if err := a.operation(tok); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("line %v: expected an operation but found %v", tok.Line, tok)
+		}
+		if end := a.next(); end.Kind != AT_NEWLINE && end.Kind != AT_EOF {
+			return nil, fmt.Errorf("line %v: expected end of line but found %v", end.Line, end)
+		}
+	}
+	for _, fixup := range a.fixups {
+		address, ok := a.labels[fixup.label]
+		if !ok {
+			return nil, fmt.Errorf("line %v: undefined label %v", fixup.line, fixup.label)
+		}
+		fixup.resolve(address)
+	}
+	a.program.SymbolTableSize = a.symbols
+	if sized {
+		a.program.SymbolTableSize = symbolTableSize
+	}
+	return a.program, nil
+}
+
+type assembler struct {
+	tokens  []assemblyToken
+	pos     int
+	program *Program
+	labels  map[string]int  // the address of every label
+	fixups  []assemblyFixup // references to labels that may be defined later
+	symbols int             // the highest symbol used + 1
+}
+
+// assemblyFixup resolves a reference to a label once all labels are known
+type assemblyFixup struct {
+	label   string
+	line    int
+	resolve func(address int)
+}
+
+func (a *assembler) peek() assemblyToken {
+	return a.tokens[a.pos]
+}
+
+func (a *assembler) next() assemblyToken {
+	tok := a.tokens[a.pos]
+	if tok.Kind != AT_EOF {
+		a.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the punctuation
+func (a *assembler) accept(punct string) bool {
+	if tok := a.peek(); tok.Kind == AT_PUNCT && tok.Text == punct {
+		a.pos++
+		return true
+	}
+	return false
+}
+
+func (a *assembler) expect(punct string) error {
+	if !a.accept(punct) {
+		tok := a.peek()
+		return fmt.Errorf("line %v: expected %q but found %v", tok.Line, punct, tok)
+	}
+	return nil
+}
+
+func (a *assembler) operation(name assemblyToken) error {
+	opType, ok := operationsByName[name.Text]
+	if !ok {
+		return fmt.Errorf("line %v: unknown operation %v", name.Line, name.Text)
+	}
+	operands := assemblyOperands[opType]
+	args := make([]any, len(operands))
+	for i, kind := range operands {
+		if i > 0 {
+			if err := a.expect(","); err != nil {
+				return err
+			}
+		}
+		var err error
+		switch kind {
+		case AO_SYMBOL:
+			args[i], err = a.symbol()
+		case AO_AMOUNT:
+			args[i], err = a.integer()
+		case AO_TARGET:
+			i := i
+			args[i] = 0
+			err = a.target(func(address int) { args[i] = address - 1 })
+		case AO_TYPE:
+			args[i], err = a.typeName()
+		case AO_EXPRESSION:
+			args[i], err = a.expression()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	a.program.Operations = append(a.program.Operations, BinaryOperation{Type: opType, Args: args})
+	return nil
+}
+
+func (a *assembler) integer() (int, error) {
+	tok := a.next()
+	val, err := strconv.Atoi(tok.Text)
+	if tok.Kind != AT_NUMBER || err != nil {
+		return 0, fmt.Errorf("line %v: expected an integer but found %v", tok.Line, tok)
+	}
+	return val, nil
+}
+
+func (a *assembler) symbol() (int, error) {
+	tok := a.next()
+	if tok.Kind != AT_SYMBOL {
+		return 0, fmt.Errorf("line %v: expected a symbol but found %v", tok.Line, tok)
+	}
+	index := strings.SplitN(tok.Text[1:], ".", 2)[0]
+	ref, err := strconv.Atoi(index)
+	if err != nil {
+		return 0, fmt.Errorf("line %v: invalid symbol %v", tok.Line, tok.Text)
+	}
+	if ref+1 > a.symbols {
+		a.symbols = ref + 1
+	}
+	return ref, nil
+}
+
+// target parses a label or address and passes the address to resolve, labels are resolved after all lines are parsed
+func (a *assembler) target(resolve func(address int)) error {
+	tok := a.next()
+	if tok.Kind != AT_TARGET {
+		return fmt.Errorf("line %v: expected a jump target but found %v", tok.Line, tok)
+	}
+	name := tok.Text[1:]
+	if c := name[0]; (c >= '0' && c <= '9') || c == '-' || c == '+' {
+		address, err := strconv.Atoi(name)
+		if err != nil {
+			return fmt.Errorf("line %v: invalid address %v", tok.Line, tok.Text)
+		}
+		resolve(address)
+		return nil
+	}
+	a.fixups = append(a.fixups, assemblyFixup{label: name, line: tok.Line, resolve: resolve})
+	return nil
+}
+
+func (a *assembler) typeName() (BinaryType, error) {
+	tok := a.next()
+	valueType, ok := typesByName[tok.Text]
+	if tok.Kind != AT_WORD || !ok {
+		return 0, fmt.Errorf("line %v: expected a type but found %v", tok.Line, tok)
+	}
+	return valueType, nil
+}
+
+func (a *assembler) expression() (*Expression, error) {
+	tok := a.next()
+	valueType, isType := typesByName[tok.Text]
+	var expr *Expression
+	switch {
+	case tok.Kind == AT_WORD && isType:
+		value, err := a.literal(valueType)
+		if err != nil {
+			return nil, err
+		}
+		return &Expression{Operator: BO_CONSTANT, Value: value}, nil
+	case tok.Kind == AT_SYMBOL:
+		a.pos--
+		ref, err := a.symbol()
+		if err != nil {
+			return nil, err
+		}
+		if a.accept("[") {
+			index, err := a.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err := a.expect("]"); err != nil {
+				return nil, err
+			}
+			return NewIndexIntoExpression(ref, index), nil
+		}
+		expr = &Expression{Operator: BO_VSYMBOL, Ref: ref}
+	case tok.Kind == AT_WORD && tok.Text == "call":
+		expr = &Expression{Operator: BO_FUNCTION_CALL}
+		if err := a.target(func(address int) { expr.Ref = address }); err != nil {
+			return nil, err
+		}
+		args, err := a.arguments()
+		if err != nil {
+			return nil, err
+		}
+		expr.Args = args
+	case tok.Kind == AT_WORD && tok.Text == "null":
+		expr = &Expression{Operator: BO_NULLEXPR}
+	case tok.Kind == AT_WORD && builtins[tok.Text] != 0:
+		args, err := a.arguments()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Expression{Operator: BO_BUILTIN_CALL, Ref: int(builtins[tok.Text]), Args: args}
+	case tok.Kind == AT_PUNCT && tok.Text == "(":
+		left, err := a.expression()
+		if err != nil {
+			return nil, err
+		}
+		symbol := a.next()
+		operator, ok := operatorsBySymbol[symbol.Text]
+		if symbol.Kind != AT_PUNCT || !ok {
+			return nil, fmt.Errorf("line %v: expected an operator but found %v", symbol.Line, symbol)
+		}
+		right, err := a.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := a.expect(")"); err != nil {
+			return nil, err
+		}
+		expr = &Expression{Operator: operator, LeftExpression: left, RightExpression: right}
+	default:
+		return nil, fmt.Errorf("line %v: expected an expression but found %v", tok.Line, tok)
+	}
+	if a.accept(":") {
+		value, err := a.annotation(expr.Operator)
+		if err != nil {
+			return nil, err
+		}
+		expr.Value = value
+	}
+	return expr, nil
+}
+
+// arguments parses the arguments of a call, each argument may be preceded by the parameter symbol it is assigned to
+func (a *assembler) arguments() ([]*FunctionArgument, error) {
+	if err := a.expect("("); err != nil {
+		return nil, err
+	}
+	args := []*FunctionArgument{}
+	for !a.accept(")") {
+		if len(args) > 0 {
+			if err := a.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg := &FunctionArgument{}
+		if a.peek().Kind == AT_SYMBOL && a.tokens[a.pos+1].Kind == AT_PUNCT && a.tokens[a.pos+1].Text == "=" {
+			ref, err := a.symbol()
+			if err != nil {
+				return nil, err
+			}
+			a.next()
+			arg.SymbolRef = ref
+		}
+		expr, err := a.expression()
+		if err != nil {
+			return nil, err
+		}
+		arg.Expression = expr
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// annotation parses the value of a non constant expression, see the syntax description above
+func (a *assembler) annotation(operator BinaryOperator) (*BinaryTypedValue, error) {
+	valueType, err := a.typeName()
+	if err != nil {
+		return nil, err
+	}
+	if tok := a.peek(); tok.Kind == AT_NUMBER || tok.Kind == AT_STRING || tok.Kind == AT_CHAR || tok.Kind == AT_WORD ||
+		(tok.Kind == AT_PUNCT && (tok.Text == "[" || tok.Text == "{")) {
+		return a.literal(valueType)
+	}
+	if _, ok := operatorSymbols[operator]; ok && valueType.isNumeric() {
+		return &BinaryTypedValue{Type: valueType, Value: defaultValuePtrOf(valueType)}, nil
+	}
+	return &BinaryTypedValue{Type: valueType}, nil
+}
+
+func (a *assembler) literal(valueType BinaryType) (*BinaryTypedValue, error) {
+	value := &BinaryTypedValue{Type: valueType}
+	tok := a.next()
+	if tok.Kind == AT_WORD && tok.Text == "nil" {
+		return value, nil
+	}
+	var err error
+	switch valueType {
+	case 0:
+		var val int64
+		val, err = strconv.ParseInt(tok.Text, 10, 64)
+		value.Value = int(val)
+	case BT_INT8:
+		value.Value, err = parseSigned[int8](tok.Text, 8)
+	case BT_INT16:
+		value.Value, err = parseSigned[int16](tok.Text, 16)
+	case BT_INT32:
+		value.Value, err = parseSigned[int32](tok.Text, 32)
+	case BT_INT64:
+		value.Value, err = parseSigned[int64](tok.Text, 64)
+	case BT_UINT8, BT_BYTE:
+		value.Value, err = parseUnsigned[uint8](tok.Text, 8)
+	case BT_UINT16:
+		value.Value, err = parseUnsigned[uint16](tok.Text, 16)
+	case BT_UINT32:
+		value.Value, err = parseUnsigned[uint32](tok.Text, 32)
+	case BT_UINT64:
+		value.Value, err = parseUnsigned[uint64](tok.Text, 64)
+	case BT_FLOAT32:
+		var val float64
+		val, err = strconv.ParseFloat(tok.Text, 32)
+		f := float32(val)
+		value.Value = &f
+	case BT_FLOAT64:
+		var val float64
+		val, err = strconv.ParseFloat(tok.Text, 64)
+		value.Value = &val
+	case BT_BOOLEAN:
+		var val bool
+		val, err = strconv.ParseBool(tok.Text)
+		value.Value = &val
+	case BT_STRING:
+		var val string
+		if val, err = strconv.Unquote(tok.Text); tok.Kind != AT_STRING {
+			err = fmt.Errorf("not a string")
+		}
+		value.Value = &val
+	case BT_CHAR:
+		var val rune
+		if tok.Kind == AT_CHAR {
+			// unquoting rejects char literals with several runes, but accepts the empty literal ''
+			var unquoted string
+			unquoted, err = strconv.Unquote(tok.Text)
+			if err == nil && utf8.RuneCountInString(unquoted) != 1 {
+				err = fmt.Errorf("a char literal must hold exactly one rune")
+			}
+			val, _ = utf8.DecodeRuneInString(unquoted)
+		} else {
+			var n int64
+			n, err = strconv.ParseInt(tok.Text, 10, 32)
+			val = rune(n)
+		}
+		value.Value = &val
+	case BT_LIST:
+		a.pos--
+		return a.list()
+	case BT_EXPRESSION:
+		a.pos--
+		if err := a.expect("{"); err != nil {
+			return nil, err
+		}
+		expr, err := a.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := a.expect("}"); err != nil {
+			return nil, err
+		}
+		value.Value = expr
+		return value, nil
+	default:
+		return nil, fmt.Errorf("line %v: values of type %v can only be nil", tok.Line, valueType)
+	}
+	if err != nil || (tok.Kind != AT_NUMBER && tok.Kind != AT_WORD && tok.Kind != AT_STRING && tok.Kind != AT_CHAR) {
+		name, _ := assemblyTypeName(valueType)
+		return nil, fmt.Errorf("line %v: invalid %v literal %v", tok.Line, name, tok)
+	}
+	return value, nil
+}
+
+// list parses the elements of an array, every element is written as its type followed by its literal
+func (a *assembler) list() (*BinaryTypedValue, error) {
+	if err := a.expect("["); err != nil {
+		return nil, err
+	}
+	elements := []*BinaryTypedValue{}
+	for !a.accept("]") {
+		if len(elements) > 0 {
+			if err := a.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		elemType, err := a.typeName()
+		if err != nil {
+			return nil, err
+		}
+		elem, err := a.literal(elemType)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, elem)
+	}
+	return &BinaryTypedValue{Type: BT_LIST, Value: &elements}, nil
+}
+
+func parseSigned[T int8 | int16 | int32 | int64](text string, bits int) (*T, error) {
+	val, err := strconv.ParseInt(text, 10, bits)
+	converted := T(val)
+	return &converted, err
+}
+
+func parseUnsigned[T uint8 | uint16 | uint32 | uint64](text string, bits int) (*T, error) {
+	val, err := strconv.ParseUint(text, 10, bits)
+	converted := T(val)
+	return &converted, err
+}
@@ -0,0 +1,174 @@
+package goscript
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// assemble assembles a hand written program, which must be valid
+func assemble(t testing.TB, text string) *Program {
+	prog, err := Assemble(text)
+	if err != nil {
+		t.Fatalf("assembling failed with error %v", err)
+	}
+	if err := Verify(prog); err != nil {
+		t.Fatalf("the assembled program should be valid but got %v", err)
+	}
+	return prog
+}
+
+func TestAssemblyRoundTrip(t *testing.T) {
+	for name, prog := range workspacePrograms(t) {
+		text, err := Disassemble(prog)
+		if err != nil {
+			t.Fatalf("disassembling %v failed with error %v", name, err)
+		}
+		assembled := assemble(t, text)
+		expectValue(string(mustHash(t, assembled)), string(mustHash(t, prog)))
+		// without debug info the listing only differs in its names
+		prog.StripDebugInfo()
+		stripped, err := Disassemble(prog)
+		if err != nil {
+			t.Fatalf("disassembling %v failed with error %v", name, err)
+		}
+		reassembled, err := Disassemble(assembled)
+		if err != nil {
+			t.Fatalf("disassembling %v failed with error %v", name, err)
+		}
+		expectValue(reassembled, stripped)
+	}
+}
+
+func TestDisassembleNames(t *testing.T) {
+	text, err := Disassemble(workspacePrograms(t)["fib.gs"])
+	if err != nil {
+		t.Fatalf("disassembling failed with error %v", err)
+	}
+	fmt.Println(text)
+	expectValue(strings.Contains(text, "\n#fn_0_main_fib:\n"), true)
+	expectValue(strings.Contains(text, "call @#fn_0_main_fib($0.n = UINT64 20):UINT64"), true)
+	expectValue(strings.Contains(text, "BIND $1.i, UINT64"), true)
+}
+
+func TestAssembleHandWritten(t *testing.T) {
+	prog := assemble(t, `
+		.symbols 2                       ; the size of the symbol table
+		main:
+			BIND $0.i, UINT64
+			ASSIGN $0.i, UINT64 0
+		loop:
+			JUMP_IF_NOT ($0 < UINT64 3), @done
+			EXPRESSION println($0)
+			ASSIGN $0, ($0 + UINT64 1):UINT64
+			JUMP @loop
+		done: RETURN call @square($1 = $0):UINT64
+		square:
+			RETURN ($1 * $1):UINT64
+	`)
+	expectValue(prog.SymbolTableSize, 2)
+	expectLength(prog.Operations, 8, "operations")
+	expectValue(prog.Operations[2].Args[1].(int), 5) // stored as the address before the target
+	expectValue(prog.Operations[5].Args[0].(int), 1)
+	ret, err := NewRuntime().Exec(*prog)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectValue(*ret.Value.(*uint64), uint64(9))
+}
+
+func TestAssembleValues(t *testing.T) {
+	values := []string{
+		"INT8 -128", "INT16 32767", "INT32 -5", "INT64 -9223372036854775808",
+		"UINT8 255", "BYTE 7", "UINT16 65535", "UINT32 4294967295", "UINT64 18446744073709551615",
+		"FLOAT32 0.1", "FLOAT64 0.1", "FLOAT64 -1e+100", "FLOAT64 +Inf", "FLOAT64 NaN",
+		`STRING "tab\tquote\"semicolon;"`, `CHAR 'ä'`, "CHAR -1", "BOOLEAN true", "STRING nil",
+		"ARRAY [UINT8 1, STRING \"two\", ARRAY []]", "EXPRESSION {($0 + UINT64 1):UINT64}",
+	}
+	for _, value := range values {
+		text := fmt.Sprintf("RETURN %v\n", value)
+		prog := assemble(t, text)
+		disassembled, err := Disassemble(prog)
+		if err != nil {
+			t.Fatalf("disassembling %v failed with error %v", value, err)
+		}
+		expectValue(disassembled, fmt.Sprintf(".symbols %v\nL0:\n\t%v", prog.SymbolTableSize, text))
+	}
+	prog := assemble(t, "RETURN FLOAT32 0.1")
+	expectValue(*prog.Operations[0].Args[0].(*Expression).Value.Value.(*float32), float32(0.1))
+	prog = assemble(t, "RETURN FLOAT64 -Inf")
+	expectValue(math.IsInf(*prog.Operations[0].Args[0].(*Expression).Value.Value.(*float64), -1), true)
+}
+
+func TestAssembleAnnotations(t *testing.T) {
+	prog := assemble(t, `
+		RETURN ((call @1():UINT64 + $0:UNTYPED 0):UINT64 - ($0 / UINT64 2):UINT64 7):UINT64 nil
+		RETURN null:NOTYPE
+	`)
+	expr := prog.Operations[0].Args[0].(*Expression)
+	expectValue(expr.Value.Type, BT_UINT64)
+	expectValue(expr.Value.Value == nil, true)
+	expectValue(*expr.LeftExpression.Value.Value.(*uint64), uint64(0))
+	expectValue(*expr.RightExpression.Value.Value.(*uint64), uint64(7))
+	call := expr.LeftExpression.LeftExpression
+	expectValue(call.Value.Type, BT_UINT64)
+	expectValue(call.Value.Value == nil, true)
+	expectValue(expr.LeftExpression.RightExpression.Value.Value.(int), 0)
+	expectValue(prog.Operations[1].Args[0].(*Expression).Value.Type, BT_NOTYPE)
+}
+
+func TestAssembleErrors(t *testing.T) {
+	invalid := map[string]string{
+		"unknown operation":     "PUSH $0",
+		"missing operand":       "ASSIGN $0",
+		"missing comma":         "ASSIGN $0 UINT64 1",
+		"trailing operand":      "ENTER_SCOPE $0",
+		"undefined label":       "JUMP @nowhere",
+		"duplicate label":       "a:\na:\nENTER_SCOPE",
+		"unknown type":          "BIND $0, UINT128",
+		"out of range":          "RETURN UINT8 256",
+		"invalid literal":       "RETURN BOOLEAN yes",
+		"string as number":      `RETURN INT64 "1"`,
+		"unterminated string":   `RETURN STRING "abc`,
+		"multiple runes":        "RETURN CHAR 'ab'",
+		"empty char":            "RETURN CHAR ''",
+		"literal of any":        "RETURN ANY 1",
+		"unknown operator":      "RETURN ($0 % $1)",
+		"unclosed operator":     "RETURN ($0 + $1",
+		"unknown builtin":       "EXPRESSION printline($0)",
+		"unclosed call":         "EXPRESSION call @0($0 = $1",
+		"invalid symbol":        "RETURN $x",
+		"unexpected character":  "RETURN ~",
+		"invalid address":       "JUMP @1x",
+		"annotated constant":    "RETURN UINT64 1:UINT64",
+		"expression as literal": "RETURN EXPRESSION $0",
+	}
+	for name, text := range invalid {
+		_, err := Assemble(text)
+		if err == nil {
+			t.Fatalf("assembling the program with %v should fail", name)
+		}
+		fmt.Printf("%v: %v\n", name, err)
+	}
+	_, err := Assemble("ENTER_SCOPE\n\nRETURN $0 $1")
+	expectValue(err.Error(), `line 3: expected end of line but found "$1"`)
+}
+
+func TestDisassembleErrors(t *testing.T) {
+	invalid := map[string]*Expression{
+		"untyped value":       {Operator: BO_LESSER, LeftExpression: NewVSymbolExpression(0), RightExpression: NewVSymbolExpression(0), Value: &BinaryTypedValue{Value: ptr(false)}},
+		"constant mismatch":   NewConstantExpression(ptr("1"), BT_UINT64),
+		"unknown builtin":     {Operator: BO_BUILTIN_CALL, Ref: 99},
+		"placeholder":         {Operator: BO_FUNCTION_CALL_PLACEHOLDER},
+		"missing operand":     {Operator: BO_PLUS, LeftExpression: NewVSymbolExpression(0)},
+		"unexpected operands": {Operator: BO_VSYMBOL, LeftExpression: NewVSymbolExpression(0)},
+	}
+	for name, expr := range invalid {
+		_, err := Disassemble(&Program{Operations: []BinaryOperation{NewReturnValueOp(expr)}, SymbolTableSize: 1})
+		if err == nil {
+			t.Fatalf("disassembling the program with %v should fail", name)
+		}
+		fmt.Printf("%v: %v\n", name, err)
+	}
+}
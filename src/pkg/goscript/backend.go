@@ -0,0 +1,96 @@
+package goscript
+
+import "fmt"
+
+// jumpFixup records a jump operation whose target block has not been laid out yet
+type jumpFixup struct {
+	PC     int
+	Target *BasicBlock
+}
+
+// emitFunction lays out the blocks of the graph in order and appends their bytecode to the current program.
+// Jumps to the block that is laid out next are elided, since control flow falls through to it anyway.
+func (c *Compiler) emitFunction(graph *ControlFlowGraph) {
+	c.funcBaseByName[graph.Function] = len(c.currentProgram.Operations)
+	addressOf := make(map[*BasicBlock]int)
+	fixups := []jumpFixup{}
+	lines := &lineTableBuilder{debug: c.currentProgram.Debug}
+	emit := func(op BinaryOperation, line int) {
+		lines.add(len(c.currentProgram.Operations), line)
+		c.currentProgram.Operations = append(c.currentProgram.Operations, op)
+	}
+	for i, block := range graph.Blocks {
+		addressOf[block] = len(c.currentProgram.Operations)
+		for j, op := range block.Operations {
+			emit(op, block.LineOf(j))
+		}
+		// determine which block will be laid out after this one
+		var next *BasicBlock
+		if i+1 < len(graph.Blocks) {
+			next = graph.Blocks[i+1]
+		}
+		line := block.Terminator.Line
+		switch block.Terminator.Type {
+		case TT_JUMP:
+			if block.Terminator.Then != next {
+				fixups = append(fixups, jumpFixup{PC: len(c.currentProgram.Operations), Target: block.Terminator.Then})
+				emit(NewJumpOp(0), line)
+			}
+		case TT_BRANCH:
+			// if the true branch follows immediately, we only have to jump when the condition is false
+			if block.Terminator.Then == next {
+				fixups = append(fixups, jumpFixup{PC: len(c.currentProgram.Operations), Target: block.Terminator.Else})
+				emit(NewJumpIfNotOp(0, block.Terminator.Condition), line)
+				continue
+			}
+			fixups = append(fixups, jumpFixup{PC: len(c.currentProgram.Operations), Target: block.Terminator.Then})
+			emit(NewJumpIfOp(0, block.Terminator.Condition), line)
+			if block.Terminator.Else != next {
+				fixups = append(fixups, jumpFixup{PC: len(c.currentProgram.Operations), Target: block.Terminator.Else})
+				emit(NewJumpOp(0), line)
+			}
+		case TT_RETURN:
+			emit(NewReturnValueOp(block.Terminator.Value), line)
+		default:
+			panic(fmt.Sprintf("cannot emit unterminated block B%v in function %v", block.ID, graph.Function))
+		}
+	}
+	// record the range and the declaration of the function for debugging
+	function := FunctionInfo{
+		Name: graph.Function,
+		Base: c.funcBaseByName[graph.Function],
+		End:  len(c.currentProgram.Operations),
+	}
+	if source := c.sourceByName[graph.Function]; source != nil {
+		function.File = source.File
+		function.Line = source.Line
+	}
+	c.currentProgram.Debug.Functions = append(c.currentProgram.Debug.Functions, function)
+	// patch the jump targets, jumps store the address before their target since the pc is incremented after the jump
+	for _, fixup := range fixups {
+		target, ok := addressOf[fixup.Target]
+		if !ok {
+			panic(fmt.Sprintf("jump to block B%v which is not part of function %v", fixup.Target.ID, graph.Function))
+		}
+		switch c.currentProgram.Operations[fixup.PC].Type {
+		case JUMP:
+			c.currentProgram.Operations[fixup.PC].Args[0] = target - 1
+		case JUMP_IF, JUMP_IF_NOT:
+			c.currentProgram.Operations[fixup.PC].Args[1] = target - 1
+		}
+	}
+}
+
+// lineTableBuilder appends an entry to the line table of the program whenever the source line changes
+type lineTableBuilder struct {
+	debug *DebugInfo
+	last  int
+}
+
+func (l *lineTableBuilder) add(pc int, line int) {
+	if line == 0 || line == l.last {
+		return
+	}
+	l.debug.Lines = append(l.debug.Lines, LineInfo{PC: pc, Line: line})
+	l.last = line
+}
@@ -1,44 +1,13 @@
 package goscript
 
-import (
-	"encoding/gob"
-	"fmt"
-	"log"
-	"os"
-
-	"go.mongodb.org/mongo-driver/bson"
-)
+import "fmt"
 
+// Program is a compiled goscript program. Runtimes never modify the program they execute,
+// so a single Program may be executed by any number of Runtimes concurrently.
 type Program struct {
 	Operations      []BinaryOperation
 	SymbolTableSize int
-}
-
-func (p *Program) Encode(out string) {
-	f, err := os.OpenFile(out, os.O_RDWR|os.O_CREATE, 0600)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	gob.Register(BT_ANY)
-	gob.Register(Expression{})
-	gob.Register([]*BinaryTypedValue{})
-	enc := gob.NewEncoder(f)
-	err = enc.Encode(p)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-func (p *Program) EncodeBSON(out string) {
-	buff, err := bson.Marshal(p)
-	if err != nil {
-		log.Fatal(err)
-	}
-	err = os.WriteFile(out, buff, 0600)
-	if err != nil {
-		log.Fatal(err)
-	}
+	Debug           *DebugInfo // optional, runtime errors can only name functions if this is present
 }
 
 type OperationType byte
@@ -276,6 +245,8 @@ func NewExpressionOp(expr *Expression) BinaryOperation {
 type BinaryType byte
 
 const (
+	// BT_UNTYPED is the zero value, carried by internal int values that have no goscript type, like the value of a symbol expression
+	BT_UNTYPED    BinaryType = 0
 	BT_INT8       BinaryType = 1
 	BT_INT16      BinaryType = 2
 	BT_INT32      BinaryType = 3
@@ -338,6 +309,20 @@ func (b BinaryType) String() string {
 		return "BOOLEAN"
 	case BT_LIST:
 		return "ARRAY"
+	case BT_NOTYPE:
+		return "NOTYPE"
+	case BT_EXPRESSION:
+		return "EXPRESSION"
+	case BT_VECTOR:
+		return "VECTOR"
+	case BT_TENSOR:
+		return "TENSOR"
+	case BT_MAP:
+		return "MAP"
+	case BT_POINTER:
+		return "POINTER"
+	case BT_NULL:
+		return "NULL"
 	default:
 		return "invalid type"
 	}
@@ -528,6 +513,10 @@ type FunctionArgument struct {
 	SymbolRef  int         `json:",omitempty" bson:",omitempty"`
 }
 
+func (a *FunctionArgument) String() string {
+	return fmt.Sprintf("SYM(%v)=%v", a.SymbolRef, a.Expression)
+}
+
 type BinaryTypedValue struct {
 	Type  BinaryType `json:",omitempty" bson:",omitempty"`
 	Value any        `json:",omitempty" bson:",omitempty"`
@@ -544,3 +533,34 @@ func (e *Expression) IsConstant() bool {
 func (e *Expression) IsFunction() bool {
 	return e.Operator == BO_FUNCTION_CALL
 }
+
+// forEachExpression calls visit for every expression of the program, including all nested expressions
+func (p *Program) forEachExpression(visit func(pc int, expr *Expression)) {
+	var walk func(pc int, expr *Expression)
+	walk = func(pc int, expr *Expression) {
+		if expr == nil {
+			return
+		}
+		visit(pc, expr)
+		// indices and expression constants hold an expression in their value
+		if expr.Value != nil {
+			if nested, ok := expr.Value.Value.(*Expression); ok {
+				walk(pc, nested)
+			}
+		}
+		for _, arg := range expr.Args {
+			if arg != nil {
+				walk(pc, arg.Expression)
+			}
+		}
+		walk(pc, expr.LeftExpression)
+		walk(pc, expr.RightExpression)
+	}
+	for pc, op := range p.Operations {
+		for _, arg := range op.Args {
+			if expr, ok := arg.(*Expression); ok {
+				walk(pc, expr)
+			}
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package goscript
+
+import "fmt"
+
+/*
+	The control flow graph sits between the intermediate representation and the bytecode.
+	Every function is lowered into a graph of basic blocks. A basic block is a sequence of operations
+	that is always executed from start to end and is terminated by exactly one control flow transfer.
+	Branches, loops, break and continue are all expressed as edges between blocks, which means
+	optimizations only have to understand the graph and bytecode emission (see backend.go) only
+	has to understand the four terminator types.
+
+	Count Loop Graph
+	B0 ENTER_SCOPE, BIND i, ASSIGN i init      JUMP B1
+	B1 (loop head)                             BRANCH cond B2 B4
+	B2 ... loop body ...                       JUMP B3        (continue jumps to B3, break jumps to B4)
+	B3 (loop latch) ASSIGN i i+1               JUMP B1
+	B4 (loop exit) EXIT_SCOPE                  ...
+*/
+
+type TerminatorType byte
+
+const (
+	TT_NONE   TerminatorType = 0 // the block is still being built
+	TT_JUMP   TerminatorType = 1 // continue in the Then block
+	TT_BRANCH TerminatorType = 2 // continue in the Then block if the condition is true, otherwise in the Else block
+	TT_RETURN TerminatorType = 3 // return the value from the current function
+)
+
+// Terminator is the control flow transfer at the end of a basic block
+type Terminator struct {
+	Type      TerminatorType
+	Condition *Expression // condition of a branch
+	Value     *Expression // value of a return
+	Then      *BasicBlock // target of a jump or the target of a branch if the condition is true
+	Else      *BasicBlock // target of a branch if the condition is false
+	Line      int         // the source line the terminator was compiled from, 0 if unknown
+}
+
+// BasicBlock is a sequence of operations without any control flow transfers
+type BasicBlock struct {
+	ID           int
+	Operations   []BinaryOperation
+	Lines        []int // the source line of every operation, 0 if unknown
+	Terminator   Terminator
+	Predecessors []*BasicBlock
+}
+
+// Append appends an operation that was compiled from the source line to the block
+func (b *BasicBlock) Append(op BinaryOperation, line int) {
+	// operations that were appended directly have no line
+	for len(b.Lines) < len(b.Operations) {
+		b.Lines = append(b.Lines, 0)
+	}
+	b.Operations = append(b.Operations, op)
+	b.Lines = append(b.Lines, line)
+}
+
+// LineOf returns the source line of the operation at the index in the block, 0 if unknown
+func (b *BasicBlock) LineOf(index int) int {
+	if index < len(b.Lines) {
+		return b.Lines[index]
+	}
+	return 0
+}
+
+// Successors returns the blocks control flow may continue in after this block
+func (b *BasicBlock) Successors() []*BasicBlock {
+	switch b.Terminator.Type {
+	case TT_JUMP:
+		return []*BasicBlock{b.Terminator.Then}
+	case TT_BRANCH:
+		return []*BasicBlock{b.Terminator.Then, b.Terminator.Else}
+	default:
+		return []*BasicBlock{}
+	}
+}
+
+// ControlFlowGraph is the graph of basic blocks of a single function
+type ControlFlowGraph struct {
+	Function string
+	Entry    *BasicBlock
+	Blocks   []*BasicBlock // all blocks in the order in which they will be laid out in the bytecode
+	nextID   int
+}
+
+func NewControlFlowGraph(function string) *ControlFlowGraph {
+	graph := &ControlFlowGraph{Function: function}
+	graph.Entry = graph.NewBlock()
+	return graph
+}
+
+// NewBlock appends a new empty block to the graph
+func (g *ControlFlowGraph) NewBlock() *BasicBlock {
+	block := &BasicBlock{ID: g.nextID}
+	g.nextID++
+	g.Blocks = append(g.Blocks, block)
+	return block
+}
+
+// Jump terminates the block with an unconditional jump to the target
+func (b *BasicBlock) Jump(target *BasicBlock) {
+	b.terminate(Terminator{Type: TT_JUMP, Then: target})
+}
+
+// Branch terminates the block with a conditional jump
+func (b *BasicBlock) Branch(condition *Expression, then *BasicBlock, els *BasicBlock) {
+	b.terminate(Terminator{Type: TT_BRANCH, Condition: condition, Then: then, Else: els})
+}
+
+// Return terminates the block with a return of the value
+func (b *BasicBlock) Return(value *Expression) {
+	b.terminate(Terminator{Type: TT_RETURN, Value: value})
+}
+
+func (b *BasicBlock) terminate(terminator Terminator) {
+	if b.Terminator.Type != TT_NONE {
+		panic(fmt.Sprintf("block B%v is already terminated", b.ID))
+	}
+	b.Terminator = terminator
+	for _, successor := range b.Successors() {
+		successor.Predecessors = append(successor.Predecessors, b)
+	}
+}
+
+// RemoveUnreachableBlocks removes all blocks that cannot be reached from the entry block and yields the number of removed blocks
+func (g *ControlFlowGraph) RemoveUnreachableBlocks() int {
+	reachable := make(map[*BasicBlock]bool)
+	pending := []*BasicBlock{g.Entry}
+	for len(pending) > 0 {
+		block := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if reachable[block] {
+			continue
+		}
+		reachable[block] = true
+		pending = append(pending, block.Successors()...)
+	}
+	blocks := []*BasicBlock{}
+	for _, block := range g.Blocks {
+		if reachable[block] {
+			blocks = append(blocks, block)
+		}
+	}
+	removed := len(g.Blocks) - len(blocks)
+	g.Blocks = blocks
+	// unreachable blocks must no longer be considered as predecessors
+	for _, block := range g.Blocks {
+		predecessors := []*BasicBlock{}
+		for _, predecessor := range block.Predecessors {
+			if reachable[predecessor] {
+				predecessors = append(predecessors, predecessor)
+			}
+		}
+		block.Predecessors = predecessors
+	}
+	return removed
+}
+
+// MergeBlocks merges every block into its predecessor if the predecessor unconditionally jumps to it and
+// the block has no other predecessors. Yields the number of merged blocks.
+func (g *ControlFlowGraph) MergeBlocks() int {
+	merged := 0
+	removed := make(map[*BasicBlock]bool)
+	for _, block := range g.Blocks {
+		if removed[block] {
+			continue
+		}
+		for block.Terminator.Type == TT_JUMP {
+			next := block.Terminator.Then
+			if next == block || next == g.Entry || len(next.Predecessors) != 1 {
+				break
+			}
+			for i, op := range next.Operations {
+				block.Append(op, next.LineOf(i))
+			}
+			block.Terminator = next.Terminator
+			for _, successor := range block.Successors() {
+				for i, predecessor := range successor.Predecessors {
+					if predecessor == next {
+						successor.Predecessors[i] = block
+					}
+				}
+			}
+			removed[next] = true
+			merged++
+		}
+	}
+	blocks := []*BasicBlock{}
+	for _, block := range g.Blocks {
+		if !removed[block] {
+			blocks = append(blocks, block)
+		}
+	}
+	g.Blocks = blocks
+	return merged
+}
+
+func (g *ControlFlowGraph) String() string {
+	ret := fmt.Sprintf("BEGIN GRAPH %v, %v BLOCKS\n", g.Function, len(g.Blocks))
+	for _, block := range g.Blocks {
+		ret += fmt.Sprintf("B%v:\n", block.ID)
+		for _, op := range block.Operations {
+			ret += fmt.Sprintf("  %v\n", op.String())
+		}
+		ret += fmt.Sprintf("  %v\n", block.Terminator.String())
+	}
+	return ret
+}
+
+func (t *Terminator) String() string {
+	switch t.Type {
+	case TT_JUMP:
+		return fmt.Sprintf("JUMP B%v", t.Then.ID)
+	case TT_BRANCH:
+		return fmt.Sprintf("BRANCH %v B%v B%v", t.Condition, t.Then.ID, t.Else.ID)
+	case TT_RETURN:
+		return fmt.Sprintf("RETURN %v", t.Value)
+	default:
+		return "UNTERMINATED"
+	}
+}
+
+// loopTargets holds the blocks that break and continue jump to inside of a loop
+type loopTargets struct {
+	Continue *BasicBlock
+	Break    *BasicBlock
+}
+
+// buildGraph lowers the operations of a function into a control flow graph
+func (c *Compiler) buildGraph(def *FunctionDefinition) *ControlFlowGraph {
+	c.currentFunction = def
+	c.currentGraph = NewControlFlowGraph(def.Name)
+	c.graphsByName[def.Name] = c.currentGraph
+	c.currentBlock = c.currentGraph.Entry
+	c.loops = []loopTargets{}
+	for c.currentOpIndex = 0; c.currentOpIndex < len(def.Operations); c.currentOpIndex++ {
+		op := def.Operations[c.currentOpIndex]
+		c.currentLine = c.sourceLine(def.Name, op)
+		switch op.Type {
+		case IM_ASSIGN:
+			c.generateAssign(op)
+		case IM_BREAK:
+			c.generateBreak()
+		case IM_CONTINUE:
+			c.generateContinue()
+		case IM_CLOSING_BRACKET:
+			c.generateLoopEnd()
+		case IM_EXPRESSION:
+			c.generateExpression(op)
+		case IM_FOR:
+			c.generateLoop(op)
+		case IM_FOREACH:
+			panic("foreach is not implemented in buildGraph")
+		case IM_RETURN:
+			c.generateReturn(op)
+		case IM_NOP:
+		default:
+			panic(fmt.Sprintf("unkndown operation %v cannot compile", op.Type))
+		}
+	}
+	if len(c.loops) != 0 {
+		panic(fmt.Sprintf("function %v has a loop that is never closed", def.Name))
+	}
+	// if the function does not end in a return, we will insert one
+	if c.currentBlock.Terminator.Type == TT_NONE {
+		c.returnFrom(c.currentBlock, &Expression{Operator: BO_NULLEXPR})
+	}
+	return c.currentGraph
+}
+
+// sourceLine returns the line in the source file the operation of the function was parsed from, 0 if unknown
+func (c *Compiler) sourceLine(function string, op *IntermediateOperation) int {
+	source := c.sourceByName[function]
+	if source == nil || op.Line >= len(source.Lines) {
+		return 0
+	}
+	return source.Lines[op.Line]
+}
+
+// emit appends an operation to the block that is currently being built
+func (c *Compiler) emit(op BinaryOperation) {
+	c.currentBlock.Append(op, c.currentLine)
+}
+
+// jump terminates the block with a jump that is attributed to the current line
+func (c *Compiler) jump(block *BasicBlock, target *BasicBlock) {
+	block.Jump(target)
+	block.Terminator.Line = c.currentLine
+}
+
+// branch terminates the block with a branch that is attributed to the current line
+func (c *Compiler) branch(block *BasicBlock, condition *Expression, then *BasicBlock, els *BasicBlock) {
+	block.Branch(condition, then, els)
+	block.Terminator.Line = c.currentLine
+}
+
+// returnFrom terminates the block with a return that is attributed to the current line
+func (c *Compiler) returnFrom(block *BasicBlock, value *Expression) {
+	block.Return(value)
+	block.Terminator.Line = c.currentLine
+}
+
+// startBlock continues building in a new block, which is unreachable unless something jumps to it
+func (c *Compiler) startBlock() {
+	c.currentBlock = c.currentGraph.NewBlock()
+}
+
+func (c *Compiler) generateLoop(op *IntermediateOperation) {
+	iteratorRef := c.symbolIndexByName[op.Args[0].(string)]
+	iteratorType := op.Args[1].(IntermediateType).Type
+	c.emit(NewEnterScope())
+	c.emit(NewBindOp(iteratorRef, iteratorType))
+	c.emit(NewAssignExpressionOp(iteratorRef, c.compileExpression(coerceConstant(op.Args[2].(*Expression), iteratorType))))
+	head := c.currentGraph.NewBlock()
+	body := c.currentGraph.NewBlock()
+	latch := c.currentGraph.NewBlock()
+	exit := c.currentGraph.NewBlock()
+	c.jump(c.currentBlock, head)
+	c.branch(head, c.compileExpression(op.Args[3].(*Expression)), body, exit)
+	// the latch advances the iterator if the loop head specified an increment or decrement
+	if direction, ok := op.Args[4].(*bool); ok && direction != nil {
+		operator := BO_MINUS
+		if *direction {
+			operator = BO_PLUS
+		}
+		one := uint64(1)
+		latch.Append(NewAssignExpressionOp(iteratorRef, &Expression{
+			LeftExpression:  NewVSymbolExpression(iteratorRef),
+			RightExpression: NewConstantExpression(castNumeric(&BinaryTypedValue{Type: BT_UINT64, Value: &one}, iteratorType), iteratorType),
+			Operator:        operator,
+			Value: &BinaryTypedValue{
+				Type:  iteratorType,
+				Value: defaultValuePtrOf(iteratorType),
+			},
+		}), c.currentLine)
+	}
+	c.jump(latch, head)
+	exit.Append(NewExitScopeOp(), c.currentLine)
+	c.loops = append(c.loops, loopTargets{Continue: latch, Break: exit})
+	c.currentBlock = body
+}
+
+// generateLoopEnd closes the innermost loop, continuing in its exit block
+func (c *Compiler) generateLoopEnd() {
+	if len(c.loops) == 0 {
+		panic(fmt.Sprintf("unexpected closing bracket in function %v", c.currentFunction.Name))
+	}
+	loop := c.loops[len(c.loops)-1]
+	c.loops = c.loops[:len(c.loops)-1]
+	if c.currentBlock.Terminator.Type == TT_NONE {
+		c.jump(c.currentBlock, loop.Continue)
+	}
+	c.currentBlock = loop.Break
+}
+
+func (c *Compiler) generateBreak() {
+	if len(c.loops) == 0 {
+		panic(fmt.Sprintf("break outside of a loop in function %v", c.currentFunction.Name))
+	}
+	c.jump(c.currentBlock, c.loops[len(c.loops)-1].Break)
+	c.startBlock()
+}
+
+func (c *Compiler) generateContinue() {
+	if len(c.loops) == 0 {
+		panic(fmt.Sprintf("continue outside of a loop in function %v", c.currentFunction.Name))
+	}
+	c.jump(c.currentBlock, c.loops[len(c.loops)-1].Continue)
+	c.startBlock()
+}
+
+func (c *Compiler) generateReturn(op *IntermediateOperation) {
+	value, ok := op.Args[0].(*Expression)
+	if !ok || value == nil {
+		value = &Expression{Operator: BO_NULLEXPR}
+	}
+	if c.isSelfCall(value) {
+		c.generateTailCall(value)
+	} else {
+		c.returnFrom(c.currentBlock, c.compileExpression(value))
+	}
+	c.startBlock()
+}
+
+// isSelfCall checks if the expression is a call to the function that is currently being compiled
+func (c *Compiler) isSelfCall(expr *Expression) bool {
+	return expr.Operator == BO_FUNCTION_CALL_PLACEHOLDER && expr.Value.Value.(*FunctionCallPlaceholder).Name == c.currentFunction.Name
+}
+
+/*
+generateTailCall compiles `return f(...)` inside of f into a reassignment of the parameters followed by a jump
+to the entry block, which is laid out at the base address of the function, so the recursion runs in constant stack space.
+All arguments are evaluated into temporaries before any parameter is reassigned, since arguments may reference parameters.
+
+	ENTER_SCOPE
+	BIND tmp0, ASSIGN tmp0 arg0, BIND tmp1, ASSIGN tmp1 arg1
+	ASSIGN param0 tmp0, ASSIGN param1 tmp1
+	EXIT_SCOPE
+	EXIT_SCOPE (once for every loop the return is nested in)
+	JUMP base
+*/
+func (c *Compiler) generateTailCall(call *Expression) {
+	placeholder := call.Value.Value.(*FunctionCallPlaceholder)
+	// the argument count was checked by checkCalls
+	params := c.currentFunction.Accepts
+	fmt.Printf("[GSC][TCO] eliminate tail call in %v\n", c.currentFunction.Name)
+	if len(params) == 1 {
+		// a single argument is fully evaluated before it is assigned, so no temporary is required
+		paramType := params[0].Type.Type
+		c.emit(NewAssignExpressionOp(c.symbolIndexByName[params[0].Name], c.compileExpression(coerceConstant(placeholder.Args[0], paramType))))
+	} else if len(params) > 1 {
+		c.emit(NewEnterScope())
+		temporaries := []int{}
+		for i, param := range params {
+			paramType := param.Type.Type
+			temporary := c.declareSymbol(c.currentFunction.Name, &IntermediateVar{Name: fmt.Sprintf("#%v_tail_%v", c.currentFunction.Name, i), Type: param.Type})
+			temporaries = append(temporaries, temporary)
+			c.emit(NewBindOp(temporary, paramType))
+			c.emit(NewAssignExpressionOp(temporary, c.compileExpression(coerceConstant(placeholder.Args[i], paramType))))
+		}
+		for i, param := range params {
+			c.emit(NewAssignExpressionOp(c.symbolIndexByName[param.Name], NewVSymbolExpression(temporaries[i])))
+		}
+		c.emit(NewExitScopeOp())
+	}
+	// leave the scopes of all loops we are currently in
+	for range c.loops {
+		c.emit(NewExitScopeOp())
+	}
+	c.jump(c.currentBlock, c.currentGraph.Entry)
+}
+
+func (c *Compiler) generateExpression(op *IntermediateOperation) {
+	c.emit(NewExpressionOp(c.compileExpression(op.Args[0].(*Expression))))
+}
+
+func (c *Compiler) generateAssign(op *IntermediateOperation) {
+	c.emit(NewBindOp(c.symbolIndexByName[op.Args[0].(string)], op.Args[1].(IntermediateType).Type))
+	if len(op.Args) == 3 && op.Args[2].(*Expression) != nil {
+		expr := coerceConstant(op.Args[2].(*Expression), op.Args[1].(IntermediateType).Type)
+		c.emit(NewAssignExpressionOp(c.symbolIndexByName[op.Args[0].(string)], c.compileExpression(expr)))
+	} else {
+		c.emit(NewAssignExpressionOp(c.symbolIndexByName[op.Args[0].(string)], NewConstantExpression(defaultValuePtrOf(op.Args[1].(IntermediateType).Type), op.Args[1].(IntermediateType).Type)))
+	}
+}
+
+// optimizeGraph runs all graph level optimizations on the graph
+func (c *Compiler) optimizeGraph(graph *ControlFlowGraph) {
+	if removed := graph.RemoveUnreachableBlocks(); removed > 0 {
+		fmt.Printf("[GSC][DCE] eliminate %v unreachable blocks in %v\n", removed, graph.Function)
+	}
+	graph.MergeBlocks()
+}
@@ -0,0 +1,129 @@
+package goscript
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestControlFlowGraphLoops(t *testing.T) {
+	compiler := NewCompiler()
+	prog, err := compiler.Compile(CompileJob{
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, "loop.gs"),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
+	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	graph := compiler.graphsByName["#fn_0_main_main"]
+	fmt.Println(graph)
+	fmt.Println(prog)
+	branches := []*BasicBlock{}
+	expressions := 0
+	for _, block := range graph.Blocks {
+		if block.Terminator.Type == TT_NONE {
+			t.Fatalf("block B%v is not terminated", block.ID)
+		}
+		if block.Terminator.Type == TT_BRANCH {
+			branches = append(branches, block)
+		}
+		for _, op := range block.Operations {
+			if op.Type == EXPRESSION {
+				expressions++
+			}
+		}
+	}
+	expectLength(branches, 2, "every loop should have exactly one loop head")
+	// the println after the continue is unreachable
+	expectValue(expressions, 1)
+	for _, head := range branches {
+		// leaving a loop must leave its scope
+		expectValue(head.Terminator.Else.Operations[0].Type, EXIT_SCOPE)
+	}
+	// the body of the first loop continues by incrementing the iterator and jumping back to the head
+	body := branches[0].Terminator.Then
+	expectValue(body.Terminator.Type, TT_JUMP)
+	expectValue(body.Terminator.Then, branches[0])
+	expectValue(body.Operations[len(body.Operations)-1].Type, ASSIGN)
+	// the body of the second loop breaks out of the loop immediately
+	body = branches[1].Terminator.Then
+	expectValue(body.Terminator.Type, TT_JUMP)
+	expectValue(body.Terminator.Then, branches[1].Terminator.Else)
+	// the program must terminate
+	rt := NewRuntime()
+	ret, err := rt.Exec(*prog)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectValue(*ret.Value.(*uint64), uint64(0))
+}
+
+func TestControlFlowGraphMergeBlocks(t *testing.T) {
+	graph := NewControlFlowGraph("test")
+	a := graph.Entry
+	b := graph.NewBlock()
+	c := graph.NewBlock()
+	unreachable := graph.NewBlock()
+	a.Operations = append(a.Operations, NewEnterScope())
+	a.Jump(b)
+	b.Operations = append(b.Operations, NewExitScopeOp())
+	b.Jump(c)
+	c.Return(&Expression{Operator: BO_NULLEXPR})
+	unreachable.Jump(c)
+	expectValue(graph.RemoveUnreachableBlocks(), 1)
+	expectLength(c.Predecessors, 1, "unreachable predecessors must be removed")
+	expectValue(graph.MergeBlocks(), 2)
+	expectLength(graph.Blocks, 1, "all blocks should have been merged into the entry")
+	expectLength(graph.Entry.Operations, 2, "the merged block should contain all operations")
+	expectValue(graph.Entry.Terminator.Type, TT_RETURN)
+}
+
+func TestCompileTailCall(t *testing.T) {
+	compiler := NewCompiler()
+	prog, err := compiler.Compile(CompileJob{
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, "tailcall.gs"),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
+	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	fmt.Println(prog)
+	// only the calls in main remain, the recursive calls have been replaced by jumps
+	calls := 0
+	for _, op := range prog.Operations {
+		for _, arg := range op.Args {
+			if expr, ok := arg.(*Expression); ok {
+				calls += countCalls(expr)
+			}
+		}
+	}
+	expectValue(calls, 2)
+	// a million recursions deep countdown followed by a million recursions deep sum
+	rt := NewRuntime()
+	ret, err := rt.Exec(*prog)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectValue(*ret.Value.(*uint64), uint64(500000500000))
+}
+
+func countCalls(expr *Expression) int {
+	calls := 0
+	if expr.Operator == BO_FUNCTION_CALL {
+		calls++
+	}
+	for _, arg := range expr.Args {
+		calls += countCalls(arg.Expression)
+	}
+	if expr.LeftExpression != nil {
+		calls += countCalls(expr.LeftExpression)
+	}
+	if expr.RightExpression != nil {
+		calls += countCalls(expr.RightExpression)
+	}
+	return calls
+}
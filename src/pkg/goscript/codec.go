@@ -0,0 +1,401 @@
+package goscript
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+	A Codec serializes programs. The protobuf codec is the default and the only one that is stable across
+	compiler versions, see encoding.go. The gob and bson codecs serialize the Program structure directly, they are
+	mostly useful to compare encodings (see BenchmarkCodecs) and for deployments that already depend on them.
+
+	The codec of a program is recorded in its container, so the runtime detects it automatically when loading.
+	Additional codecs can be added using RegisterCodec.
+*/
+
+// Codec encodes and decodes programs
+type Codec interface {
+	Name() string // the name recorded in containers and used in the gsc and gsr -codec flags
+	Encode(w io.Writer, program *Program) error
+	Decode(r io.Reader) (*Program, error)
+}
+
+// DEFAULT_CODEC is the codec used for containers that do not specify one
+const DEFAULT_CODEC = "protobuf"
+
+var codecs = map[string]Codec{
+	"protobuf": &protobufCodec{},
+	"gob":      &gobCodec{},
+	"bson":     &bsonCodec{},
+}
+
+// RegisterCodec makes a codec available for encoding and decoding containers.
+// It must be called during initialization, before any container is encoded or decoded.
+func RegisterCodec(codec Codec) {
+	if codecs[codec.Name()] != nil {
+		panic(fmt.Sprintf("codec %v is already registered", codec.Name()))
+	}
+	codecs[codec.Name()] = codec
+}
+
+// CodecByName returns the registered codec with the name
+func CodecByName(name string) (Codec, error) {
+	codec := codecs[name]
+	if codec == nil {
+		return nil, fmt.Errorf("unknown codec %v", name)
+	}
+	return codec, nil
+}
+
+// Codecs returns the names of all registered codecs in alphabetical order
+func Codecs() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// protobufCodec encodes programs using EncodeProgram and DecodeProgram
+type protobufCodec struct{}
+
+func (c *protobufCodec) Name() string {
+	return "protobuf"
+}
+
+func (c *protobufCodec) Encode(w io.Writer, program *Program) error {
+	buff, err := EncodeProgram(program)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buff); err != nil {
+		return fmt.Errorf("failed to write program with error %v", err)
+	}
+	return nil
+}
+
+func (c *protobufCodec) Decode(r io.Reader) (*Program, error) {
+	buff, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read program with error %v", err)
+	}
+	return DecodeProgram(buff)
+}
+
+// gobCodec encodes the Program structure using encoding/gob
+type gobCodec struct{}
+
+func init() {
+	// register the types that can be held by an argument or a value, gob knows the basic types already
+	gob.Register(BinaryType(0))
+	gob.Register(&Expression{})
+	gob.Register(&[]*BinaryTypedValue{})
+}
+
+func (c *gobCodec) Name() string {
+	return "gob"
+}
+
+func (c *gobCodec) Encode(w io.Writer, program *Program) error {
+	if err := gob.NewEncoder(w).Encode(program); err != nil {
+		return fmt.Errorf("failed to encode program with error %v", err)
+	}
+	return nil
+}
+
+func (c *gobCodec) Decode(r io.Reader) (*Program, error) {
+	program := &Program{}
+	if err := gob.NewDecoder(r).Decode(program); err != nil {
+		return nil, fmt.Errorf("failed to decode program with error %v", err)
+	}
+	for _, op := range program.Operations {
+		for _, arg := range op.Args {
+			if expr, ok := arg.(*Expression); ok {
+				restoreGobPointers(expr)
+			}
+		}
+	}
+	return program, nil
+}
+
+// restoreGobPointers makes the values of the expression pointers again, gob transmits the values they point to
+func restoreGobPointers(expr *Expression) {
+	if expr == nil {
+		return
+	}
+	restoreGobValuePointer(expr.Value)
+	restoreGobPointers(expr.LeftExpression)
+	restoreGobPointers(expr.RightExpression)
+	for _, arg := range expr.Args {
+		restoreGobPointers(arg.Expression)
+	}
+}
+
+func restoreGobValuePointer(value *BinaryTypedValue) {
+	if value == nil {
+		return
+	}
+	switch val := value.Value.(type) {
+	case int8:
+		value.Value = &val
+	case int16:
+		value.Value = &val
+	case int32:
+		value.Value = &val
+	case int64:
+		value.Value = &val
+	case uint8:
+		value.Value = &val
+	case uint16:
+		value.Value = &val
+	case uint32:
+		value.Value = &val
+	case uint64:
+		value.Value = &val
+	case float32:
+		value.Value = &val
+	case float64:
+		value.Value = &val
+	case bool:
+		value.Value = &val
+	case string:
+		value.Value = &val
+	case *[]*BinaryTypedValue:
+		// gob does not distinguish empty and nil slices
+		if *val == nil {
+			*val = []*BinaryTypedValue{}
+		}
+		for _, elem := range *val {
+			restoreGobValuePointer(elem)
+		}
+	case *Expression:
+		restoreGobPointers(val)
+	}
+}
+
+// bsonCodec encodes the Program structure using bson, see BinaryOperation.UnmarshalBSON and BinaryTypedValue.MarshalBSON
+type bsonCodec struct{}
+
+func (c *bsonCodec) Name() string {
+	return "bson"
+}
+
+func (c *bsonCodec) Encode(w io.Writer, program *Program) error {
+	buff, err := bson.Marshal(program)
+	if err != nil {
+		return fmt.Errorf("failed to encode program with error %v", err)
+	}
+	if _, err := w.Write(buff); err != nil {
+		return fmt.Errorf("failed to write program with error %v", err)
+	}
+	return nil
+}
+
+func (c *bsonCodec) Decode(r io.Reader) (*Program, error) {
+	buff, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read program with error %v", err)
+	}
+	program := &Program{}
+	if err := bson.Unmarshal(buff, program); err != nil {
+		return nil, fmt.Errorf("failed to decode program with error %v", err)
+	}
+	return program, nil
+}
+
+// UnmarshalBSON restores the go types of the arguments, which bson decodes as generic numbers and documents
+func (b *BinaryOperation) UnmarshalBSON(data []byte) error {
+	raw := struct {
+		Type OperationType
+		Args []bson.RawValue
+	}{}
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	kinds, ok := operationArgs[raw.Type]
+	if !ok {
+		return fmt.Errorf("unknown operation type %v", raw.Type)
+	}
+	if len(raw.Args) != len(kinds) {
+		return fmt.Errorf("operation type %v expects %v arguments but has %v", raw.Type, len(kinds), len(raw.Args))
+	}
+	b.Type = raw.Type
+	b.Args = make([]any, len(kinds))
+	for i, kind := range kinds {
+		switch kind {
+		case AK_INT:
+			val, ok := raw.Args[i].AsInt64OK()
+			if !ok {
+				return fmt.Errorf("argument %v of operation type %v must be an integer", i, raw.Type)
+			}
+			b.Args[i] = int(val)
+		case AK_TYPE:
+			val, ok := raw.Args[i].AsInt32OK()
+			if !ok {
+				return fmt.Errorf("argument %v of operation type %v must be a type", i, raw.Type)
+			}
+			b.Args[i] = BinaryType(val)
+		case AK_EXPRESSION:
+			expr := &Expression{}
+			if err := raw.Args[i].Unmarshal(expr); err != nil {
+				return fmt.Errorf("argument %v of operation type %v must be an expression, %v", i, raw.Type, err)
+			}
+			b.Args[i] = expr
+		}
+	}
+	return nil
+}
+
+// MarshalBSON stores the value as an int64, double, boolean, int32, string, array or document depending on its type.
+// Unsigned integers are stored as their two's complement int64, since bson has no unsigned integers.
+func (bv *BinaryTypedValue) MarshalBSON() ([]byte, error) {
+	doc := bson.D{{Key: "Type", Value: bv.Type}}
+	var value any
+	switch val := bv.Value.(type) {
+	case nil:
+	case int:
+		value = int64(val)
+	case *int8:
+		value = int64(*val)
+	case *int16:
+		value = int64(*val)
+	case *int32:
+		// chars are int32 as well, they are stored like BT_INT32
+		value = *val
+	case *int64:
+		value = *val
+	case *uint8:
+		value = int64(*val)
+	case *uint16:
+		value = int64(*val)
+	case *uint32:
+		value = int64(*val)
+	case *uint64:
+		value = int64(*val)
+	case *float32:
+		value = float64(*val)
+	case *float64:
+		value = *val
+	case *bool:
+		value = *val
+	case *string:
+		value = *val
+	case *[]*BinaryTypedValue:
+		value = *val
+	case *Expression:
+		value = val
+	default:
+		return nil, fmt.Errorf("cannot encode value %v of go type %T", val, val)
+	}
+	if value != nil {
+		doc = append(doc, bson.E{Key: "Value", Value: value})
+	}
+	return bson.Marshal(doc)
+}
+
+// UnmarshalBSON converts the stored value back to the go type of its BinaryType, see MarshalBSON
+func (bv *BinaryTypedValue) UnmarshalBSON(data []byte) error {
+	raw := struct {
+		Type  BinaryType
+		Value bson.RawValue
+	}{}
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	bv.Type = raw.Type
+	bv.Value = nil
+	if raw.Value.Type == 0 {
+		return nil
+	}
+	var err error
+	ok := true
+	switch raw.Type {
+	case BT_INT8, BT_INT16, BT_INT32, BT_INT64, 0:
+		var val int64
+		if val, ok = raw.Value.AsInt64OK(); ok {
+			switch raw.Type {
+			case BT_INT8:
+				bv.Value, err = convertExact[int8](val, raw.Type)
+			case BT_INT16:
+				bv.Value, err = convertExact[int16](val, raw.Type)
+			case BT_INT32:
+				bv.Value, err = convertExact[int32](val, raw.Type)
+			case BT_INT64:
+				bv.Value = &val
+			default:
+				bv.Value = int(val)
+			}
+		}
+	case BT_UINT8, BT_BYTE, BT_UINT16, BT_UINT32, BT_UINT64:
+		var val int64
+		if val, ok = raw.Value.Int64OK(); ok {
+			unsigned := uint64(val)
+			switch raw.Type {
+			case BT_UINT8, BT_BYTE:
+				bv.Value, err = convertExact[uint8](unsigned, raw.Type)
+			case BT_UINT16:
+				bv.Value, err = convertExact[uint16](unsigned, raw.Type)
+			case BT_UINT32:
+				bv.Value, err = convertExact[uint32](unsigned, raw.Type)
+			default:
+				bv.Value = &unsigned
+			}
+		}
+	case BT_FLOAT32:
+		var val float64
+		if val, ok = raw.Value.DoubleOK(); ok {
+			f := float32(val)
+			bv.Value = &f
+		}
+	case BT_FLOAT64:
+		var val float64
+		if val, ok = raw.Value.DoubleOK(); ok {
+			bv.Value = &val
+		}
+	case BT_BOOLEAN:
+		var val bool
+		if val, ok = raw.Value.BooleanOK(); ok {
+			bv.Value = &val
+		}
+	case BT_CHAR:
+		var val int32
+		if val, ok = raw.Value.Int32OK(); ok {
+			bv.Value = &val
+		}
+	case BT_STRING:
+		var val string
+		if val, ok = raw.Value.StringValueOK(); ok {
+			bv.Value = &val
+		}
+	case BT_LIST:
+		elements := []*BinaryTypedValue{}
+		err = raw.Value.Unmarshal(&elements)
+		bv.Value = &elements
+	case BT_EXPRESSION:
+		expr := &Expression{}
+		err = raw.Value.Unmarshal(expr)
+		bv.Value = expr
+	default:
+		return fmt.Errorf("a value of type %v cannot be decoded", raw.Type)
+	}
+	if !ok {
+		return fmt.Errorf("a value of type %v cannot be stored as bson %v", raw.Type, raw.Value.Type)
+	}
+	return err
+}
+
+// encodeWith encodes the program using the codec into a buffer
+func encodeWith(codec Codec, program *Program) ([]byte, error) {
+	buff := bytes.NewBuffer(nil)
+	if err := codec.Encode(buff, program); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
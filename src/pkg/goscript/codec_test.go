@@ -0,0 +1,148 @@
+package goscript
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	programs := workspacePrograms(t)
+	for _, name := range Codecs() {
+		codec, err := CodecByName(name)
+		if err != nil {
+			t.Fatalf("codec %v should be available but got %v", name, err)
+		}
+		for file, prog := range programs {
+			buff := bytes.NewBuffer(nil)
+			if err := codec.Encode(buff, prog); err != nil {
+				t.Fatalf("encoding %v with %v failed with error %v", file, name, err)
+			}
+			size := buff.Len()
+			decoded, err := codec.Decode(buff)
+			if err != nil {
+				t.Fatalf("decoding %v with %v failed with error %v", file, name, err)
+			}
+			// the decoded program must be identical, which the protobuf encoding checks including all value types
+			expectValue(fmt.Sprintf("%x", mustHash(t, decoded)), fmt.Sprintf("%x", mustHash(t, prog)))
+			if !reflect.DeepEqual(decoded.Debug, prog.Debug) {
+				t.Fatalf("decoding %v with %v lost debug info", file, name)
+			}
+			fmt.Printf("%v %v: %v bytes\n", name, file, size)
+		}
+	}
+	if _, err := CodecByName("xml"); err == nil {
+		t.Fatalf("an unknown codec should be rejected")
+	}
+}
+
+func TestCodecValues(t *testing.T) {
+	for _, name := range Codecs() {
+		codec := codecs[name]
+		for _, value := range typedValues() {
+			prog := &Program{Operations: []BinaryOperation{NewReturnValueOp(&Expression{Operator: BO_CONSTANT, Value: value})}}
+			buff, err := encodeWith(codec, prog)
+			if err != nil {
+				t.Fatalf("encoding %v with %v failed with error %v", value.Type, name, err)
+			}
+			decoded, err := codec.Decode(bytes.NewReader(buff))
+			if err != nil {
+				t.Fatalf("decoding %v with %v failed with error %v", value.Type, name, err)
+			}
+			actual := decoded.Operations[0].Args[0].(*Expression).Value
+			if !reflect.DeepEqual(actual, value) {
+				t.Fatalf("value %#v of type %v was decoded by %v as %#v", value.Value, value.Type, name, actual.Value)
+			}
+		}
+	}
+}
+
+func TestContainerCodec(t *testing.T) {
+	prog := workspacePrograms(t)["fib.gs"]
+	for _, name := range Codecs() {
+		buff, err := EncodeContainer("fib", prog, ContainerOptions{Codec: codecs[name], Compression: CT_GZIP})
+		if err != nil {
+			t.Fatalf("encoding with %v failed with error %v", name, err)
+		}
+		container, err := DecodeContainer(buff)
+		if err != nil {
+			t.Fatalf("decoding with %v failed with error %v", name, err)
+		}
+		expectValue(container.Codec, name)
+		expectValue(container.Flags&CF_CODEC != 0, name != DEFAULT_CODEC)
+		ret, err := NewRuntime().Exec(*container.Program)
+		if err != nil {
+			t.Fatalf("execution failed with error %v", err)
+		}
+		expectValue(*ret.Value.(*uint64), uint64(6765))
+	}
+	// a program encoded with a codec this runtime does not have must be rejected as incompatible
+	RegisterCodec(&renamedCodec{Codec: codecs["gob"], name: "test"})
+	buff, err := EncodeContainer("fib", prog, ContainerOptions{Codec: codecs["test"]})
+	delete(codecs, "test")
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	_, err = DecodeContainer(buff)
+	if !errors.Is(err, ErrIncompatibleContainer) {
+		t.Fatalf("decoding with an unregistered codec should fail with an incompatibility error but got %v", err)
+	}
+	fmt.Println(err)
+	expectPanic(func() { RegisterCodec(&renamedCodec{Codec: codecs["gob"], name: "bson"}) })
+}
+
+// renamedCodec registers an existing codec under another name
+type renamedCodec struct {
+	Codec
+	name string
+}
+
+func (r *renamedCodec) Name() string {
+	return r.name
+}
+
+// BenchmarkCodecs reports the size and speed of every codec over the workspace programs
+func BenchmarkCodecs(b *testing.B) {
+	programs := workspacePrograms(b)
+	names := make([]string, 0, len(programs))
+	for name := range programs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, codecName := range Codecs() {
+		codec := codecs[codecName]
+		encoded := make([][]byte, len(names))
+		size := 0
+		for i, name := range names {
+			buff, err := encodeWith(codec, programs[name])
+			if err != nil {
+				b.Fatalf("encoding %v failed with error %v", name, err)
+			}
+			encoded[i] = buff
+			size += len(buff)
+		}
+		b.Run(codecName+"/encode", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, name := range names {
+					if err := codec.Encode(&bytes.Buffer{}, programs[name]); err != nil {
+						b.Fatalf("encoding %v failed with error %v", name, err)
+					}
+				}
+			}
+			b.ReportMetric(float64(size), "bytes")
+		})
+		b.Run(codecName+"/decode", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, buff := range encoded {
+					if _, err := codec.Decode(bytes.NewReader(buff)); err != nil {
+						b.Fatalf("decoding failed with error %v", err)
+					}
+				}
+			}
+			b.ReportMetric(float64(size), "bytes")
+		})
+	}
+}
@@ -2,6 +2,9 @@ package goscript
 
 import (
 	"fmt"
+	"io/fs"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -10,11 +13,27 @@ type Compiler struct {
 	funcBaseByName       map[string]int
 	symbolByName         map[string]*IntermediateVar
 	symbolIndexByName    map[string]int
-	currentSymbolIndex   int
+	frameSizeByName      map[string]int
 	calledFunctionByName map[string]bool
+	callsByName          map[string][]string
+	warnings             []CompilerWarning
 	currentProgram       *Program
 	currentOpIndex       int
 	currentFunction      *FunctionDefinition
+	graphsByName         map[string]*ControlFlowGraph
+	currentGraph         *ControlFlowGraph
+	currentBlock         *BasicBlock
+	loops                []loopTargets
+	inlineThreshold      int
+	inlineCount          int
+	verbose              bool
+	stripDebugInfo       bool
+	sourceByName         map[string]*functionSource
+	sourceNameByName     map[string]string
+	currentLine          int
+	application          string
+	moduleGraph          *ModuleGraph
+	externals            []*ExternalModule
 }
 
 func NewCompiler() *Compiler {
@@ -24,29 +43,67 @@ func NewCompiler() *Compiler {
 		symbolByName:         make(map[string]*IntermediateVar),
 		symbolIndexByName:    make(map[string]int),
 		calledFunctionByName: make(map[string]bool),
-		currentSymbolIndex:   0,
+		callsByName:          make(map[string][]string),
+		graphsByName:         make(map[string]*ControlFlowGraph),
+		frameSizeByName:      make(map[string]int),
+		sourceByName:         make(map[string]*functionSource),
+		sourceNameByName:     make(map[string]string),
 		currentProgram: &Program{
 			Operations: []BinaryOperation{},
+			Debug:      &DebugInfo{},
 		},
 	}
 }
 
+// CompileJob holds everything a compilation depends on, so compilers can compile different jobs concurrently
 type CompileJob struct {
-	MainFilePath       string
-	VendorPath         string
-	LocalWorkspaceRoot string
-	StandardLibPath    string
+	MainFilePath       string // path of the main file, inside of Workspace if it is set
+	VendorPath         string // root of the ext modules, none are available if empty
+	LocalWorkspaceRoot string // root of the loc modules, none are available if empty
+	StandardLibPath    string // root of the std modules, the embedded standard library is used if empty
+	Workspace          fs.FS  // if set, the main file and loc modules are read from it instead of the disk
+	Vendor             fs.FS  // if set, the ext modules are read from it instead of VendorPath
+	Standard           fs.FS  // if set, the std modules are read from it instead of StandardLibPath
+	ModuleCachePath    string // externals that are not vendored are fetched with git into this directory, disabled if empty
+	FQSCDumpPath       string // if set, the generated fqsc will be written to this path for debugging
+	InlineThreshold    int    // functions with at most this many operations are inlined, 0 disables inlining
+	Verbose            bool   // report optimization decisions
+	StripDebugInfo     bool   // omit the debug info from the program, runtime errors will only report the pc
 }
 
 func (c *Compiler) Compile(job CompileJob) (*Program, error) {
-	appSource, err := discoverSources(job.MainFilePath, job.LocalWorkspaceRoot)
+	c.inlineThreshold = job.InlineThreshold
+	c.verbose = job.Verbose
+	c.stripDebugInfo = job.StripDebugInfo
+	c.warnings = []CompilerWarning{}
+	appSource, err := discoverSources(job)
 	if err != nil {
 		return nil, err
 	}
+	c.moduleGraph = appSource.Graph
+	c.externals = appSource.Externals
+	// imports must be checked before the preprocessor rewrites the module references
+	for _, warning := range findUnusedImports(appSource) {
+		c.warn(warning)
+	}
+	// the application directive is deleted by the preprocessor
+	c.application = ""
+	if match := APPLICATION_REGEX.FindStringSubmatch(appSource.ApplicationFile.Content); match != nil {
+		c.application = strings.TrimSpace(match[1])
+	}
+	// the preprocessor merges all files, so the lines of the functions have to be recorded beforehand
+	c.sourceByName = mapSourceLines(appSource)
 	fqsc, err := generateFQSC(appSource)
 	if err != nil {
 		return nil, err
 	}
+	// dump fqsc to a file if FQSC debugging is enabled
+	if len(job.FQSCDumpPath) > 0 {
+		err = os.WriteFile(job.FQSCDumpPath, []byte(fqsc), 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dump fqsc with error %v", err)
+		}
+	}
 	intermediate := parse(fqsc)
 	return c.generateProgram(intermediate)
 }
@@ -54,13 +111,18 @@ func (c *Compiler) Compile(job CompileJob) (*Program, error) {
 /*
 generateProgram generates a program from the intermediary program representation
 The following steps will be performed:
+- Build the call graph of all functions
+- Eliminate functions that are not reachable from main
+- Inline calls to small leaf functions
 - Replace Symbol Placeholders in expressions
 - Replace Function Placeholders in expressions
-- Eliminate Dead code
+- Lower every function into a control flow graph of basic blocks (see cfg.go)
 - Optimize:
+  - Eliminate unreachable blocks
+  - Merge blocks that are always executed in sequence
   - Resolve constant expressions as far as possible (WIP)
 
-- Generate the actual bytecode
+- Generate the actual bytecode from the control flow graphs (see backend.go)
 */
 func (c *Compiler) generateProgram(intermediate *IntermediateProgram) (*Program, error) {
 	fmt.Println("[GSC][generateProgram] begin generating program")
@@ -70,32 +132,65 @@ func (c *Compiler) generateProgram(intermediate *IntermediateProgram) (*Program,
 		funcDef := funcDef
 		c.funcsByName[funcDef.Name] = funcDef
 	}
-	// start off with a prescan of the program, discovering all symbols and function calls
+	// eliminate functions that are never called
+	newFuncs := c.eliminateDeadFunctions(&intermediate.Entrypoint, intermediate.Functions)
+	// calls are only resolved while lowering, so invalid calls have to be reported before any function is rewritten
+	if err := c.checkCalls(&intermediate.Entrypoint); err != nil {
+		return nil, err
+	}
+	for _, function := range newFuncs {
+		if err := c.checkCalls(function); err != nil {
+			return nil, err
+		}
+	}
+	// check the remaining functions for symbols that are declared but never used
+	c.findUnusedSymbols(&intermediate.Entrypoint)
+	for _, function := range newFuncs {
+		c.findUnusedSymbols(function)
+	}
+	// inline calls to small leaf functions, which may leave some functions without callers
+	if c.inlineThreshold > 0 {
+		fmt.Println("[GSC][inline] begin inlining")
+		startInline := time.Now()
+		inlined := c.inlineCalls(&intermediate.Entrypoint)
+		for _, function := range newFuncs {
+			inlined += c.inlineCalls(function)
+		}
+		if inlined > 0 {
+			newFuncs = c.eliminateDeadFunctions(&intermediate.Entrypoint, newFuncs)
+		}
+		fmt.Printf("[GSC][STAGE_COMPLETION] inlined %v calls in %v\n", inlined, time.Since(startInline))
+	}
+	// prescan the remaining functions, discovering all symbols
 	fmt.Println("[GSC][codePreScan] begin code prescan")
 	startScan := time.Now()
 	c.uniquifyVariables(&intermediate.Entrypoint)
 	c.prescanFunction(&intermediate.Entrypoint)
+	for _, function := range newFuncs {
+		c.uniquifyVariables(function)
+		c.prescanFunction(function)
+	}
 	fmt.Printf("[GSC][STAGE_COMPLETION] code prescan completed in %v\n", time.Since(startScan))
-	// eliminate functions that are never called
-	fmt.Println("[GSC][DCE] begin dead code elimination")
-	startDce := time.Now()
-	newFuncs := make(map[string]*FunctionDefinition)
-	for name, called := range c.funcsByName {
-		called := called
-		if !c.calledFunctionByName[name] && name != "#fn_0_main_main" {
-			fmt.Printf("[GSC][DCE] eliminate function %v\n", name)
-			continue
-		}
-		newFuncs[name] = called
+	// lower every function into a control flow graph
+	fmt.Println("[GSC][buildGraph] begin building control flow graphs")
+	startGraphs := time.Now()
+	graphs := []*ControlFlowGraph{c.buildGraph(&intermediate.Entrypoint)}
+	for _, function := range newFuncs {
+		graphs = append(graphs, c.buildGraph(function))
 	}
-	fmt.Printf("[GSC][STAGE_COMPLETION] DCE completed in %v\n", time.Since(startDce))
-	// generate our bytecode
+	fmt.Printf("[GSC][STAGE_COMPLETION] building control flow graphs completed in %v\n", time.Since(startGraphs))
+	// optimize the control flow graphs
+	fmt.Println("[GSC][optimizeGraph] begin optimizing control flow graphs")
+	startOptimize := time.Now()
+	for _, graph := range graphs {
+		c.optimizeGraph(graph)
+	}
+	fmt.Printf("[GSC][STAGE_COMPLETION] optimizing control flow graphs completed in %v\n", time.Since(startOptimize))
+	// generate our bytecode, the entrypoint must be emitted first since execution begins at pc 0
 	fmt.Println("[GSC][generateBytecode] begin generating bytecode")
 	startGenBytecode := time.Now()
-	c.compileFunction(&intermediate.Entrypoint)
-	for _, function := range newFuncs {
-		function := function
-		c.compileFunction(function)
+	for _, graph := range graphs {
+		c.emitFunction(graph)
 	}
 	fmt.Printf("[GSC][STAGE_COMPLETION] generating bytecode completed in %v\n", time.Since(startGenBytecode))
 	fmt.Println("[GSC][finalizeProgram] begin finalizing references")
@@ -103,10 +198,83 @@ func (c *Compiler) generateProgram(intermediate *IntermediateProgram) (*Program,
 	c.currentProgram = c.finalizeProgram(c.currentProgram)
 	fmt.Printf("[GSC][STAGE_COMPLETION] finalization completed in %v\n", time.Since(startFinalize))
 	fmt.Printf("[GSC][generateProgram] completed in %v\n", time.Since(start))
-	c.currentProgram.SymbolTableSize = len(c.symbolIndexByName) + 1
+	// every call frame is large enough to hold the symbols of any function
+	for _, size := range c.frameSizeByName {
+		if size > c.currentProgram.SymbolTableSize {
+			c.currentProgram.SymbolTableSize = size
+		}
+	}
+	if c.stripDebugInfo {
+		c.currentProgram.StripDebugInfo()
+	}
 	return c.currentProgram, nil
 }
 
+// Application returns the name from the application directive of the main file of the last compilation
+func (c *Compiler) Application() string {
+	return c.application
+}
+
+// ModuleGraph returns the modules required by the application of the last compilation and their imports
+func (c *Compiler) ModuleGraph() *ModuleGraph {
+	return c.moduleGraph
+}
+
+// Externals returns the externals that were fetched into the module cache during the last compilation
+func (c *Compiler) Externals() []*ExternalModule {
+	return c.externals
+}
+
+// Warnings returns the warnings that were emitted during the last compilation
+func (c *Compiler) Warnings() []CompilerWarning {
+	return c.warnings
+}
+
+func (c *Compiler) warn(warning CompilerWarning) {
+	fmt.Printf("[GSC][WARN] %v\n", warning.String())
+	c.warnings = append(c.warnings, warning)
+}
+
+// eliminateDeadFunctions builds the call graph over the entrypoint and the specified functions and returns
+// the functions that are reachable from the entrypoint, in the order in which they were specified
+func (c *Compiler) eliminateDeadFunctions(entrypoint *FunctionDefinition, functions []*FunctionDefinition) []*FunctionDefinition {
+	// build the call graph over all functions, including the ones that are never called
+	fmt.Println("[GSC][callGraph] begin building call graph")
+	startGraph := time.Now()
+	c.callsByName = make(map[string][]string)
+	c.calledFunctionByName = make(map[string]bool)
+	c.scanCalls(entrypoint)
+	for _, funcDef := range functions {
+		c.scanCalls(funcDef)
+	}
+	c.markReachable(entrypoint.Name)
+	fmt.Printf("[GSC][STAGE_COMPLETION] call graph completed in %v\n", time.Since(startGraph))
+	fmt.Println("[GSC][DCE] begin dead code elimination")
+	startDce := time.Now()
+	// functions are kept in source order, so the layout of the program is identical for identical inputs
+	newFuncs := []*FunctionDefinition{}
+	for _, function := range functions {
+		if !c.calledFunctionByName[function.Name] && function.Name != entrypoint.Name {
+			fmt.Printf("[GSC][DCE] eliminate function %v\n", function.Name)
+			continue
+		}
+		newFuncs = append(newFuncs, function)
+	}
+	fmt.Printf("[GSC][STAGE_COMPLETION] DCE completed in %v\n", time.Since(startDce))
+	return newFuncs
+}
+
+// markReachable marks the specified function and every function it transitively calls as reachable
+func (c *Compiler) markReachable(name string) {
+	if c.calledFunctionByName[name] {
+		return
+	}
+	c.calledFunctionByName[name] = true
+	for _, callee := range c.callsByName[name] {
+		c.markReachable(callee)
+	}
+}
+
 // finalizeProgram will recompile all expressions after all functions have been initially generated to fix
 // all function base address references
 func (c *Compiler) finalizeProgram(prog *Program) *Program {
@@ -152,65 +320,17 @@ func (c *Compiler) recompileArgs(op BinaryOperation, indices ...int) BinaryOpera
 	return op
 }
 
-func (c *Compiler) compileFunction(def *FunctionDefinition) {
-	c.currentFunction = def
-	c.currentOpIndex = 0
-	c.funcBaseByName[def.Name] = len(c.currentProgram.Operations)
-	for {
-		if len(c.currentFunction.Operations)-1 == c.currentOpIndex {
-			break
-		}
-		op := c.currentFunction.Operations[c.currentOpIndex]
-		switch op.Type {
-		case IM_ASSIGN:
-			c.generateAssign(op)
-		case IM_BREAK:
-			panic("break is not implemented in compileFunction")
-		case IM_CLOSING_BRACKET:
-			panic("should not reach closig bracket outside of inner parser")
-		case IM_EXPRESSION:
-			c.generateExpression(op)
-		case IM_FOR:
-			c.generateLoop(op)
-		case IM_FOREACH:
-			panic("foreach is not implemented in compileFunction")
-		case IM_RETURN:
-			c.generateReturn(op)
-		case IM_NOP:
-		default:
-			panic(fmt.Sprintf("unkndown operation %v cannot compile", op.Type))
-		}
-		c.currentOpIndex++
-	}
-	// if the function does not end in a return, we will insert one
-	if c.currentProgram.Operations[len(c.currentProgram.Operations)-1].Type != RETURN {
-		c.currentProgram.Operations = append(c.currentProgram.Operations, BinaryOperation{
-			Type: RETURN,
-			Args: []any{&Expression{Operator: BO_NULLEXPR}},
-		})
-	}
-}
-
-func (c *Compiler) generateLoop(op *IntermediateOperation) {
-	iteratorRef := c.symbolIndexByName[op.Args[0].(string)]
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewEnterScope())
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewBindOp(iteratorRef, op.Args[1].(IntermediateType).Type))
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewAssignExpressionOp(iteratorRef, c.compileExpression(op.Args[2].(*Expression))))
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewJumpIfNotOp(1, c.compileExpression(op.Args[3].(*Expression))))
-	loopHeadAddr := len(c.currentProgram.Operations) - 1
-	c.currentOpIndex++
-	c.generateUntilClose()
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewJumpOp(loopHeadAddr))
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewExitScopeOp())
-	loopEndAddr := len(c.currentProgram.Operations) - 1
-	c.currentProgram.Operations[loopHeadAddr] = NewJumpIfNotOp(loopEndAddr, c.compileExpression(op.Args[3].(*Expression)))
-}
-
 func (c *Compiler) compileExpression(expr *Expression) *Expression {
 	return c.resolveSymbols(c.resolveCalls(expr))
 }
 
 func (c *Compiler) resolveSymbols(expr *Expression) *Expression {
+	// operators on symbols cannot be typed by the parser, so we type them here while the symbol names are still known
+	if expr.LeftExpression != nil && expr.Value != nil && expr.Value.Type == BT_NOTYPE {
+		if resultType := c.expressionType(expr.LeftExpression); resultType.isNumeric() {
+			expr.Value = &BinaryTypedValue{Type: resultType, Value: defaultValuePtrOf(resultType)}
+		}
+	}
 	if expr.Operator == BO_VSYMBOL_PLACEHOLDER {
 		expr.Operator = BO_VSYMBOL
 		expr.Ref = c.symbolIndexByName[expr.Value.Value.(string)]
@@ -228,6 +348,37 @@ func (c *Compiler) resolveSymbols(expr *Expression) *Expression {
 	return expr
 }
 
+// expressionType returns the type an unresolved expression yields or BT_NOTYPE if it cannot be determined
+func (c *Compiler) expressionType(expr *Expression) BinaryType {
+	switch expr.Operator {
+	case BO_CONSTANT:
+		return expr.Value.Type
+	case BO_VSYMBOL_PLACEHOLDER:
+		if symbol := c.symbolByName[expr.Value.Value.(string)]; symbol != nil {
+			return symbol.Type.Type
+		}
+		return BT_NOTYPE
+	case BO_FUNCTION_CALL_PLACEHOLDER:
+		if callee := c.funcsByName[expr.Value.Value.(*FunctionCallPlaceholder).Name]; callee != nil {
+			return callee.Returns.Type
+		}
+		return BT_NOTYPE
+	case BO_FUNCTION_CALL:
+		if expr.Value != nil {
+			return expr.Value.Type
+		}
+		return BT_NOTYPE
+	default:
+		if expr.Value != nil && expr.Value.Type != BT_NOTYPE {
+			return expr.Value.Type
+		}
+		if expr.LeftExpression != nil {
+			return c.expressionType(expr.LeftExpression)
+		}
+		return BT_NOTYPE
+	}
+}
+
 func (c *Compiler) resolveCalls(expr *Expression) *Expression {
 	if expr.Operator == BO_FUNCTION_CALL_PLACEHOLDER {
 		expr.Operator = BO_FUNCTION_CALL
@@ -247,13 +398,11 @@ func (c *Compiler) resolveCalls(expr *Expression) *Expression {
 					})
 				}
 				expr.Args = funcArgs
+				// the placeholder is no longer required once the call is resolved
+				expr.Value = nil
 			} else {
-				// if no base address exists for this function, check our known functions
-				sideFunc := c.funcsByName[expr.Value.Value.(*FunctionCallPlaceholder).Name]
-				if sideFunc == nil {
-					panic(fmt.Sprintf("cannot call undefined function %v", expr.Value.Value.(*FunctionCallPlaceholder).Name))
-				}
-				// otherwise, this function is known, but not yet compiled, in which case we just exit here
+				// calls to undefined functions were rejected by checkCalls, so this function is known, but not yet
+				// compiled, in which case we just exit here
 				// for now and fix this in a later pass. This is significantly easier that generating a resolution
 				// order graph, which also might not always work (cyclic calls, recursion)
 				// in this case we also set this call back to placeholder status, since we want later passes to fix it
@@ -262,16 +411,20 @@ func (c *Compiler) resolveCalls(expr *Expression) *Expression {
 		} else {
 			// if this function is still a placeholder but we know an address for it, insert it now
 			expr.Ref = c.funcBaseByName[expr.Value.Value.(*FunctionCallPlaceholder).Name]
-			// map the function arguments into the bytecode format, resolving vsymbol references
+			// map the function arguments into the bytecode format, binding each argument to the parameter it is passed to
 			ph := expr.Value.Value.(*FunctionCallPlaceholder)
+			// the argument count was checked by checkCalls
+			callee := c.funcsByName[ph.Name]
 			funcArgs := []*FunctionArgument{}
 			for i := 0; i < len(ph.Args); i++ {
 				funcArgs = append(funcArgs, &FunctionArgument{
 					Expression: c.compileExpression(ph.Args[i]),
-					SymbolRef:  c.symbolIndexByName[ph.Name],
+					SymbolRef:  c.symbolIndexByName[callee.Accepts[i].Name],
 				})
 			}
 			expr.Args = funcArgs
+			// replace the placeholder with the return type of the function
+			expr.Value = &BinaryTypedValue{Type: callee.Returns.Type}
 		}
 	}
 	for i := 0; i < len(expr.Args); i++ {
@@ -286,47 +439,13 @@ func (c *Compiler) resolveCalls(expr *Expression) *Expression {
 	return expr
 }
 
-func (c *Compiler) generateUntilClose() {
-	for {
-		op := c.currentFunction.Operations[c.currentOpIndex]
-		switch op.Type {
-		case IM_ASSIGN:
-			c.generateAssign(op)
-		case IM_BREAK:
-			panic("break is not implemented in generateUntilClose")
-		case IM_CLOSING_BRACKET:
-			return
-		case IM_EXPRESSION:
-			c.generateExpression(op)
-		case IM_FOR:
-			c.generateLoop(op)
-		case IM_FOREACH:
-			panic("foreach is not implemented in generateUntilClose")
-		case IM_RETURN:
-			c.generateReturn(op)
-		case IM_NOP:
-		default:
-			panic(fmt.Sprintf("unkndown operation %v cannot compile", op.Type))
-		}
-		c.currentOpIndex++
-	}
-}
-
-func (c *Compiler) generateReturn(op *IntermediateOperation) {
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewReturnValueOp(c.compileExpression(op.Args[0].(*Expression))))
-}
-
-func (c *Compiler) generateExpression(op *IntermediateOperation) {
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewExpressionOp(c.compileExpression(op.Args[0].(*Expression))))
-}
-
-func (c *Compiler) generateAssign(op *IntermediateOperation) {
-	c.currentProgram.Operations = append(c.currentProgram.Operations, NewBindOp(c.symbolIndexByName[op.Args[0].(string)], op.Args[1].(IntermediateType).Type))
-	if len(op.Args) == 3 {
-		c.currentProgram.Operations = append(c.currentProgram.Operations, NewAssignExpressionOp(c.symbolIndexByName[op.Args[0].(string)], c.compileExpression(op.Args[2].(*Expression))))
-	} else {
-		c.currentProgram.Operations = append(c.currentProgram.Operations, NewAssignExpressionOp(c.symbolIndexByName[op.Args[0].(string)], NewConstantExpression(defaultValuePtrOf(op.Args[1].(IntermediateType).Type), op.Args[1].(IntermediateType).Type)))
+// coerceConstant converts a numeric constant expression to the numeric type of the symbol it is assigned to,
+// since numeric literals are always parsed as the widest type
+func coerceConstant(expr *Expression, target BinaryType) *Expression {
+	if expr.Operator != BO_CONSTANT || expr.Value.Type == target || !expr.Value.Type.isNumeric() || !target.isNumeric() {
+		return expr
 	}
+	return NewConstantExpression(castNumeric(expr.Value, target), target)
 }
 
 func (c *Compiler) replaceAliasInExpression(expr *Expression, aliasTable map[string]string) {
@@ -339,10 +458,6 @@ func (c *Compiler) replaceAliasInExpression(expr *Expression, aliasTable map[str
 		for _, arg := range placeholder.Args {
 			c.replaceAliasInExpression(arg, aliasTable)
 		}
-		if builtins[expr.Value.Value.(*FunctionCallPlaceholder).Name] == 0 {
-			function := c.funcsByName[placeholder.Name]
-			c.uniquifyVariables(function)
-		}
 	}
 	if expr.LeftExpression != nil {
 		c.replaceAliasInExpression(expr.LeftExpression, aliasTable)
@@ -365,6 +480,7 @@ func (c *Compiler) uniquifyVariables(def *FunctionDefinition) {
 		isDefined[param.Name] = true
 		newName := "#" + def.Name + "_param_" + param.Name
 		alias[param.Name] = newName
+		c.sourceNameByName[newName] = c.sourceName(param.Name)
 		param.Name = newName
 	}
 	// scan the operations for any symbols and function calls
@@ -378,6 +494,7 @@ func (c *Compiler) uniquifyVariables(def *FunctionDefinition) {
 			isDefined[name] = true
 			newName := "#" + def.Name + "_var_" + name + "_" + fmt.Sprint(idx)
 			alias[name] = newName
+			c.sourceNameByName[newName] = c.sourceName(name)
 			op.Args[0] = newName
 			// check if an assigned expression is present
 			if len(op.Args) == 3 {
@@ -396,6 +513,7 @@ func (c *Compiler) uniquifyVariables(def *FunctionDefinition) {
 			isDefined[name] = true
 			newName := "#" + def.Name + "_var_" + name + "_" + fmt.Sprint(idx)
 			alias[name] = newName
+			c.sourceNameByName[newName] = c.sourceName(name)
 			op.Args[0] = newName
 			c.replaceAliasInExpression(op.Args[2].(*Expression), alias)
 			c.replaceAliasInExpression(op.Args[3].(*Expression), alias)
@@ -414,52 +532,119 @@ func (c *Compiler) prescanFunction(def *FunctionDefinition) {
 	fmt.Printf("[GSC][prescan::%v]\n", def.Name)
 	// scan the parameters
 	for _, param := range def.Accepts {
-		param := param
-		c.symbolByName[param.Name] = param
-		c.symbolIndexByName[param.Name] = c.currentSymbolIndex
-		c.currentSymbolIndex++
+		c.declareSymbol(def.Name, param)
 	}
-	// scan the operations for any symbols and function calls
+	// scan the operations for any symbols
 	for _, op := range def.Operations {
 		switch op.Type {
-		case IM_ASSIGN:
-			c.symbolByName[op.Args[0].(string)] = &IntermediateVar{
+		case IM_ASSIGN, IM_FOR:
+			c.declareSymbol(def.Name, &IntermediateVar{
 				Name: op.Args[0].(string),
 				Type: op.Args[1].(IntermediateType),
+			})
+			// case IM_FOREACH:
+			// we need type infenerce here
+		}
+	}
+}
+
+// sourceName returns the name a symbol was declared with in the source code, before it was renamed by uniquifyVariables
+func (c *Compiler) sourceName(name string) string {
+	if sourceName, ok := c.sourceNameByName[name]; ok {
+		return sourceName
+	}
+	return name
+}
+
+// declareSymbol assigns the next free slot in the call frame of the function to the symbol.
+// Symbol references are relative to the frame, so the symbols of different functions may share the same index.
+func (c *Compiler) declareSymbol(function string, symbol *IntermediateVar) int {
+	if index, ok := c.symbolIndexByName[symbol.Name]; ok {
+		return index
+	}
+	c.symbolByName[symbol.Name] = symbol
+	c.symbolIndexByName[symbol.Name] = c.frameSizeByName[function]
+	c.currentProgram.Debug.Symbols = append(c.currentProgram.Debug.Symbols, SymbolInfo{
+		Function: function,
+		Index:    c.frameSizeByName[function],
+		Name:     c.sourceName(symbol.Name),
+	})
+	c.frameSizeByName[function]++
+	return c.symbolIndexByName[symbol.Name]
+}
+
+// scanCalls adds the edges from the specified function to all functions it calls to the call graph
+func (c *Compiler) scanCalls(def *FunctionDefinition) {
+	for _, op := range def.Operations {
+		for _, expr := range operationExpressions(op) {
+			c.scanExpression(def.Name, expr)
+		}
+	}
+}
+
+// checkCalls returns an error if the function calls an undefined function or passes the wrong number of arguments
+func (c *Compiler) checkCalls(def *FunctionDefinition) error {
+	for _, op := range def.Operations {
+		for _, expr := range operationExpressions(op) {
+			if err := c.checkCallExpression(def.Name, expr); err != nil {
+				// the call can only be located by the declaration of the calling function
+				if source := c.sourceByName[def.Name]; source != nil {
+					return fmt.Errorf("%v:%v: %v", source.File, source.Line, err)
+				}
+				return err
 			}
-			c.symbolIndexByName[op.Args[0].(string)] = c.currentSymbolIndex
-			c.currentSymbolIndex++
-			// check if an assigned expression is present
-			if len(op.Args) == 3 {
-				c.scanExpression(op.Args[2].(*Expression))
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) checkCallExpression(caller string, expr *Expression) error {
+	if expr.Operator == BO_FUNCTION_CALL_PLACEHOLDER {
+		placeholder := expr.Value.Value.(*FunctionCallPlaceholder)
+		if builtins[placeholder.Name] == 0 {
+			callee := c.funcsByName[placeholder.Name]
+			if callee == nil {
+				return fmt.Errorf("function %v calls undefined function %v", caller, placeholder.Name)
 			}
-		case IM_FOR:
-			c.symbolByName[op.Args[0].(string)] = &IntermediateVar{
-				Name: op.Args[0].(string),
-				Type: op.Args[1].(IntermediateType),
+			if len(placeholder.Args) != len(callee.Accepts) {
+				return fmt.Errorf("function %v calls %v with %v arguments but it expects %v", caller, placeholder.Name, len(placeholder.Args), len(callee.Accepts))
 			}
-			c.symbolIndexByName[op.Args[0].(string)] = c.currentSymbolIndex
-			c.currentSymbolIndex++
-			// scan both expressions of the loop
-			c.scanExpression(op.Args[2].(*Expression))
-			c.scanExpression(op.Args[3].(*Expression))
-		// case IM_FOREACH:
-		// 	c.symbolByName[op.Args[0].(string)] = &IntermediateVar{
-		// 		Name: op.Args[0].(string),
-		// 		Type: op.Args[1].(IntermediateType),
-		// 	}
-		// 	c.symbolIndexByName[op.Args[0].(string)] = c.currentSymbolIndex
-		// 	c.currentSymbolIndex++
-		// we need type infenerce here
-		case IM_RETURN:
-			conv, ok := op.Args[0].(*Expression)
-			if ok {
-				c.scanExpression(conv)
+		}
+		for _, arg := range placeholder.Args {
+			if err := c.checkCallExpression(caller, arg); err != nil {
+				return err
+			}
+		}
+	}
+	if expr.LeftExpression != nil {
+		if err := c.checkCallExpression(caller, expr.LeftExpression); err != nil {
+			return err
+		}
+	}
+	if expr.RightExpression != nil {
+		return c.checkCallExpression(caller, expr.RightExpression)
+	}
+	return nil
+}
+
+// operationExpressions returns all expressions used by an intermediate operation
+func operationExpressions(op *IntermediateOperation) []*Expression {
+	exprs := []*Expression{}
+	switch op.Type {
+	case IM_ASSIGN:
+		if len(op.Args) == 3 {
+			if expr, ok := op.Args[2].(*Expression); ok && expr != nil {
+				exprs = append(exprs, expr)
 			}
-		case IM_EXPRESSION:
-			c.scanExpression(op.Args[0].(*Expression))
+		}
+	case IM_FOR:
+		exprs = append(exprs, op.Args[2].(*Expression), op.Args[3].(*Expression))
+	case IM_RETURN, IM_EXPRESSION:
+		if expr, ok := op.Args[0].(*Expression); ok && expr != nil {
+			exprs = append(exprs, expr)
 		}
 	}
+	return exprs
 }
 
 var builtins = map[string]BuiltinFunction{
@@ -486,18 +671,21 @@ var builtins = map[string]BuiltinFunction{
 	"str":     BF_TOSTRING,
 }
 
-func (c *Compiler) scanExpression(expr *Expression) {
+func (c *Compiler) scanExpression(caller string, expr *Expression) {
 	if expr.Operator == BO_FUNCTION_CALL_PLACEHOLDER {
-		wasKnown := c.calledFunctionByName[expr.Value.Value.(*FunctionCallPlaceholder).Name]
-		c.calledFunctionByName[expr.Value.Value.(*FunctionCallPlaceholder).Name] = true
-		if !wasKnown && builtins[expr.Value.Value.(*FunctionCallPlaceholder).Name] == 0 {
-			c.prescanFunction(c.funcsByName[expr.Value.Value.(*FunctionCallPlaceholder).Name])
+		placeholder := expr.Value.Value.(*FunctionCallPlaceholder)
+		if builtins[placeholder.Name] == 0 {
+			c.callsByName[caller] = append(c.callsByName[caller], placeholder.Name)
+		}
+		// calls may be nested in the arguments of other calls
+		for _, arg := range placeholder.Args {
+			c.scanExpression(caller, arg)
 		}
 	}
 	if expr.LeftExpression != nil {
-		c.scanExpression(expr.LeftExpression)
+		c.scanExpression(caller, expr.LeftExpression)
 	}
 	if expr.RightExpression != nil {
-		c.scanExpression(expr.RightExpression)
+		c.scanExpression(caller, expr.RightExpression)
 	}
 }
@@ -1,51 +1,181 @@
 package goscript
 
 import (
+	"bytes"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
+// the roots of the goscript tree in this repository, relative to this package
+const (
+	TEST_WORKSPACE = "../../../goscript/gs_workspace"
+	TEST_VENDOR    = "../../../goscript/gs_vendor"
+	TEST_STANDARD  = "../../../goscript/gs_standard"
+)
+
+// workspaceJob returns the job that compiles the file of the test workspace, which is read through a file system so
+// its lockfile is verified, but never written into the repository
+func workspaceJob(name string) CompileJob {
+	return CompileJob{
+		MainFilePath: name,
+		Workspace:    os.DirFS(TEST_WORKSPACE),
+		Vendor:       os.DirFS(TEST_VENDOR),
+		Standard:     os.DirFS(TEST_STANDARD),
+	}
+}
+
 func TestCompileVariableIdentity(t *testing.T) {
 	compiler := NewCompiler()
 	prog, err := compiler.Compile(CompileJob{
-		MainFilePath:       filepath.Join(TESTS, "var_identity.gs"),
-		LocalWorkspaceRoot: TESTS,
-		VendorPath:         VENDORPATH,
-		StandardLibPath:    STDPATH,
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, "var_identity.gs"),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Println(prog)
 	rt := NewRuntime()
-	rt.Exec(*prog)
+	if _, err := rt.Exec(*prog); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 }
 
-func TestCompileImports(t *testing.T) {
+// writeWorkspace creates a workspace containing the files and returns its root
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory with error %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %v with error %v", name, err)
+		}
+	}
+	return root
+}
+
+func TestCompileJobRoots(t *testing.T) {
+	// the same application compiled against two standard libraries in parallel
+	workspace := writeWorkspace(t, map[string]string{
+		"main.gs": "import \"std/num\"\n\nfunc main() {\n    return num.value()\n}",
+	})
+	values := []uint64{1, 2}
+	results := make([]uint64, len(values))
+	errs := make([]error, len(values))
+	wg := sync.WaitGroup{}
+	for i, value := range values {
+		standard := writeWorkspace(t, map[string]string{
+			"num/num.gs": fmt.Sprintf("func value() => u64 {\n    return %v\n}", value),
+		})
+		wg.Add(1)
+		go func(i int, standard string) {
+			defer wg.Done()
+			prog, err := NewCompiler().Compile(CompileJob{
+				MainFilePath:       filepath.Join(workspace, "main.gs"),
+				LocalWorkspaceRoot: workspace,
+				StandardLibPath:    standard,
+			})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			ret, err := NewRuntime().Exec(*prog)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = *ret.Value.(*uint64)
+		}(i, standard)
+	}
+	wg.Wait()
+	for i := range values {
+		if errs[i] != nil {
+			t.Fatalf("compilation %v failed with error %v", i, errs[i])
+		}
+		expectValue(results[i], values[i])
+	}
+	// the embedded standard library has no std/num
+	_, err := NewCompiler().Compile(CompileJob{
+		MainFilePath:       filepath.Join(workspace, "main.gs"),
+		LocalWorkspaceRoot: workspace,
+	})
+	if err == nil {
+		t.Fatalf("compilation without a standard library should fail")
+	}
+	fmt.Println(err)
+}
+
+func TestCompileFS(t *testing.T) {
 	compiler := NewCompiler()
 	prog, err := compiler.Compile(CompileJob{
-		MainFilePath:       filepath.Join(TESTS, "imports.gs"),
-		LocalWorkspaceRoot: TESTS,
-		VendorPath:         VENDORPATH,
-		StandardLibPath:    STDPATH,
+		MainFilePath: "app/main.gs",
+		Workspace: mapFS(map[string]string{
+			"app/main.gs":     "application fs\nexternal twice from \"https://example.com/twice\"\n\nimport \"ext/twice\"\nimport \"loc/offset\"\n\nfunc main() {\n    return twice.apply(offset.value())\n}",
+			"offset/value.gs": "import \"std/math\"\n\nfunc value() => u64 {\n    return math.add(20, 1)\n}",
+		}),
+		Vendor: mapFS(map[string]string{
+			"twice/twice.gs": "func apply(x: u64) => u64 {\n    return x * 2\n}",
+		}),
+		Standard: os.DirFS(TEST_STANDARD),
 	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectValue(compiler.Application(), "fs")
+	ret, err := NewRuntime().Exec(*prog)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectValue(*ret.Value.(*uint64), uint64(42))
+	expectValue(prog.Debug.Functions[0].File, "app/main.gs")
+	// the main file is read from the workspace, not the disk
+	_, err = NewCompiler().Compile(CompileJob{
+		MainFilePath: filepath.Join(TEST_WORKSPACE, "fib.gs"),
+		Workspace:    mapFS(nil),
+	})
+	if err == nil {
+		t.Fatalf("compiling a file outside of the workspace should fail")
+	}
+}
+
+func TestCompileImports(t *testing.T) {
+	compiler := NewCompiler()
+	prog, err := compiler.Compile(workspaceJob("imports.gs"))
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Println(prog)
 	rt := NewRuntime()
-	rt.Exec(*prog)
+	if _, err := rt.Exec(*prog); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	// the compilation verified the vendored external against the lockfile fixture
+	lock, err := readLockfile(os.DirFS(TEST_WORKSPACE), "imports.lock")
+	if err != nil || lock == nil {
+		t.Fatalf("failed to read the lockfile with error %v", err)
+	}
+	hash, err := hashModuleContent(os.DirFS(TEST_VENDOR), "log")
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	expectLength(lock.Externals, 1, "locked externals")
+	expectValue(lock.Externals[0], LockedExternal{Name: "log", URL: "https://github.com/Yoshi-Exeler/goscript", Branch: "master", Hash: hash, Vendored: true})
 }
 
 // func TestCompileCall(t *testing.T) {
 // 	compiler := NewCompiler()
 // 	prog, err := compiler.Compile(CompileJob{
-// 		MainFilePath:       filepath.Join(TESTS, "call.gs"),
-// 		LocalWorkspaceRoot: TESTS,
-// 		VendorPath:         VENDORPATH,
-// 		StandardLibPath:    STDPATH,
+// 		MainFilePath:       filepath.Join(TEST_WORKSPACE, "call.gs"),
+// 		LocalWorkspaceRoot: TEST_WORKSPACE,
+// 		VendorPath:         TEST_VENDOR,
+// 		StandardLibPath:    TEST_STANDARD,
 // 	})
 // 	if err != nil {
 // 		log.Fatal(err)
@@ -58,10 +188,10 @@ func TestCompileImports(t *testing.T) {
 // func TestCompileArray(t *testing.T) {
 // 	compiler := NewCompiler()
 // 	_, err := compiler.Compile(CompileJob{
-// 		MainFilePath:       filepath.Join(TESTS, "array.gs"),
-// 		LocalWorkspaceRoot: TESTS,
-// 		VendorPath:         VENDORPATH,
-// 		StandardLibPath:    STDPATH,
+// 		MainFilePath:       filepath.Join(TEST_WORKSPACE, "array.gs"),
+// 		LocalWorkspaceRoot: TEST_WORKSPACE,
+// 		VendorPath:         TEST_VENDOR,
+// 		StandardLibPath:    TEST_STANDARD,
 // 	})
 // 	if err != nil {
 // 		log.Fatal(err)
@@ -71,10 +201,10 @@ func TestCompileImports(t *testing.T) {
 func TestCompileHello(t *testing.T) {
 	compiler := NewCompiler()
 	prog, err := compiler.Compile(CompileJob{
-		MainFilePath:       filepath.Join(TESTS, "hello.gs"),
-		LocalWorkspaceRoot: TESTS,
-		VendorPath:         VENDORPATH,
-		StandardLibPath:    STDPATH,
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, "hello.gs"),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -87,12 +217,98 @@ func TestCompileHello(t *testing.T) {
 func TestCompileTypecast(t *testing.T) {
 	compiler := NewCompiler()
 	_, err := compiler.Compile(CompileJob{
-		MainFilePath:       filepath.Join(TESTS, "typecast.gs"),
-		LocalWorkspaceRoot: TESTS,
-		VendorPath:         VENDORPATH,
-		StandardLibPath:    STDPATH,
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, "typecast.gs"),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+func TestCompileDeadCode(t *testing.T) {
+	compiler := NewCompiler()
+	prog, err := compiler.Compile(CompileJob{
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, "dce.gs"),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
+	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	fmt.Println(prog)
+	// functions that are only called by unreachable functions must be eliminated as well
+	for _, name := range []string{"#fn_0_main_deadA", "#fn_0_main_deadB"} {
+		if _, ok := compiler.funcBaseByName[name]; ok {
+			t.Fatalf("function %v should have been eliminated", name)
+		}
+	}
+	// the operations after the return in main must be eliminated
+	if prog.Operations[3].Type != RETURN || prog.Operations[4].Type != RETURN {
+		t.Fatalf("expected main to end after its return but got %v", prog)
+	}
+	expectLength(prog.Operations, 5, "main and first should compile to five operations")
+	expected := []CompilerWarning{
+		{Type: WT_UNUSED_IMPORT, Location: filepath.Join(TEST_WORKSPACE, "dce.gs"), Name: "std/math"},
+		{Type: WT_UNUSED_VARIABLE, Location: "#fn_0_main_main", Name: "unused"},
+		{Type: WT_UNUSED_PARAMETER, Location: "#fn_0_main_first", Name: "b"},
+	}
+	expectLength(compiler.Warnings(), len(expected), "every unused symbol should produce exactly one warning")
+	for i, warning := range compiler.Warnings() {
+		expectValue(warning, expected[i])
+	}
+	rt := NewRuntime()
+	ret, err := rt.Exec(*prog)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectValue(*ret.Value.(*uint64), uint64(0))
+	// the warnings are those of the last compilation only
+	_, err = compiler.Compile(CompileJob{
+		MainFilePath: "main.gs",
+		Workspace:    mapFS(map[string]string{"main.gs": "func main() {\n    return missing()\n}"}),
+	})
+	if err == nil {
+		t.Fatalf("compiling a call to an undefined function should fail")
+	}
+	expectLength(compiler.Warnings(), 0, "warnings of the previous compilation")
+}
+
+func TestCompileInvalidCalls(t *testing.T) {
+	for _, source := range []string{
+		"func main() {\n    return add(1)\n}\n\nfunc add(a: u64, b: u64) => u64 {\n    return a + b\n}",
+		"func main() {\n    return add(1, 2, 3)\n}\n\nfunc add(a: u64, b: u64) => u64 {\n    return a + b\n}",
+		"func main() {\n    println(add(1))\n    return 0\n}\n\nfunc add(a: u64, b: u64) => u64 {\n    return a + b\n}",
+		"func main() {\n    return missing(1)\n}",
+	} {
+		_, err := NewCompiler().Compile(CompileJob{MainFilePath: "main.gs", Workspace: mapFS(map[string]string{"main.gs": source})})
+		if err == nil {
+			t.Fatalf("compiling %v should fail", source)
+		}
+		fmt.Println(err)
+	}
+}
+
+func TestCompileDeterministic(t *testing.T) {
+	var reference []byte
+	for i := 0; i < 10; i++ {
+		compiler := NewCompiler()
+		prog, err := compiler.Compile(workspaceJob("imports.gs"))
+		if err != nil {
+			t.Fatalf("compilation failed with error %v", err)
+		}
+		encoded, err := EncodeProgram(prog)
+		if err != nil {
+			t.Fatalf("encoding failed with error %v", err)
+		}
+		if reference == nil {
+			reference = encoded
+			continue
+		}
+		if !bytes.Equal(reference, encoded) {
+			t.Fatalf("compilation %v produced different output than the first compilation", i)
+		}
+	}
+}
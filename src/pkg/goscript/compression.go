@@ -0,0 +1,183 @@
+package goscript
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+/*
+	The payload of a container may be compressed. The compression is recorded in the container header
+	together with the CF_COMPRESSED flag, so the runtime detects it automatically when loading, and older runtimes
+	refuse compressed programs instead of failing to decode them.
+
+	The standard library codecs are always available. Other codecs like zstd or snappy can be added by
+	registering a Compressor for CT_ZSTD or CT_SNAPPY using RegisterCompressor, which keeps their dependencies
+	out of this package. A runtime can only load programs that were compressed with a codec it has registered.
+
+	The header records the size of the payload before compression. Programs are decoded from untrusted files and the
+	header is written by whoever wrote the file, so the checksum does not protect against payloads that decompress to
+	far more data than they claim. Decompression therefore stops after the recorded size, and payloads that claim
+	more than MAX_DECOMPRESSED_SIZE are rejected before they are decompressed.
+*/
+
+// MAX_DECOMPRESSED_SIZE is the largest payload size in bytes that the runtime decompresses
+const MAX_DECOMPRESSED_SIZE = 256 << 20
+
+type CompressionType byte
+
+const (
+	CT_NONE   CompressionType = 0
+	CT_FLATE  CompressionType = 1
+	CT_ZLIB   CompressionType = 2
+	CT_GZIP   CompressionType = 3
+	CT_ZSTD   CompressionType = 4 // reserved, no implementation is included
+	CT_SNAPPY CompressionType = 5 // reserved, no implementation is included
+)
+
+// Compressor compresses and decompresses container payloads
+type Compressor interface {
+	Name() string // the name used to select the compressor, for example in the gsc -compression flag
+	Compress(data []byte) ([]byte, error)
+	// Decompress must fail instead of producing more than size bytes, which is the size recorded in the header
+	Decompress(data []byte, size int) ([]byte, error)
+}
+
+var compressors = map[CompressionType]Compressor{
+	CT_FLATE: &streamCompressor{
+		name:   "flate",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return flate.NewWriter(w, flate.BestCompression) },
+		reader: func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil },
+	},
+	CT_ZLIB: &streamCompressor{
+		name:   "zlib",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriterLevel(w, zlib.BestCompression) },
+		reader: zlib.NewReader,
+	},
+	CT_GZIP: &streamCompressor{
+		name:   "gzip",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, gzip.BestCompression) },
+		reader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	},
+}
+
+// RegisterCompressor makes a compressor available for encoding and decoding containers.
+// It must be called during initialization, before any container is encoded or decoded.
+func RegisterCompressor(compression CompressionType, compressor Compressor) {
+	if compression == CT_NONE {
+		panic("cannot register a compressor for CT_NONE")
+	}
+	if compressors[compression] != nil {
+		panic(fmt.Sprintf("compressor %v is already registered", compression))
+	}
+	compressors[compression] = compressor
+}
+
+// CompressionByName returns the compression of the registered compressor with the name, "none" disables compression
+func CompressionByName(name string) (CompressionType, error) {
+	if name == "none" {
+		return CT_NONE, nil
+	}
+	for compression, compressor := range compressors {
+		if compressor.Name() == name {
+			return compression, nil
+		}
+	}
+	return CT_NONE, fmt.Errorf("unknown compression %v", name)
+}
+
+func (c CompressionType) String() string {
+	if c == CT_NONE {
+		return "none"
+	}
+	if compressor := compressors[c]; compressor != nil {
+		return compressor.Name()
+	}
+	switch c {
+	case CT_ZSTD:
+		return "zstd"
+	case CT_SNAPPY:
+		return "snappy"
+	default:
+		return fmt.Sprintf("compression(%d)", byte(c))
+	}
+}
+
+func compress(compression CompressionType, data []byte) ([]byte, error) {
+	if compression == CT_NONE {
+		return data, nil
+	}
+	compressor := compressors[compression]
+	if compressor == nil {
+		return nil, fmt.Errorf("compression %v is not available", compression)
+	}
+	return compressor.Compress(data)
+}
+
+// decompress decompresses the payload, which must decompress to exactly size bytes
+func decompress(compression CompressionType, data []byte, size uint64) ([]byte, error) {
+	if compression == CT_NONE {
+		return data, nil
+	}
+	compressor := compressors[compression]
+	if compressor == nil {
+		return nil, fmt.Errorf("%w: the program is compressed with %v, which is not available in this runtime", ErrIncompatibleContainer, compression)
+	}
+	if size > MAX_DECOMPRESSED_SIZE {
+		return nil, fmt.Errorf("%w: the program claims to decompress to %v bytes, which exceeds the limit of %v bytes", ErrIncompatibleContainer, size, MAX_DECOMPRESSED_SIZE)
+	}
+	decompressed, err := compressor.Decompress(data, int(size))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(decompressed)) != size {
+		return nil, fmt.Errorf("%w: the program decompressed to %v bytes, but the header records %v bytes", ErrIncompatibleContainer, len(decompressed), size)
+	}
+	return decompressed, nil
+}
+
+// streamCompressor implements a Compressor using the stream based compression packages of the standard library
+type streamCompressor struct {
+	name   string
+	writer func(w io.Writer) (io.WriteCloser, error)
+	reader func(r io.Reader) (io.ReadCloser, error)
+}
+
+func (s *streamCompressor) Name() string {
+	return s.name
+}
+
+func (s *streamCompressor) Compress(data []byte) ([]byte, error) {
+	buff := bytes.NewBuffer(nil)
+	writer, err := s.writer(buff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v writer with error %v", s.name, err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress with %v with error %v", s.name, err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress with %v with error %v", s.name, err)
+	}
+	return buff.Bytes(), nil
+}
+
+func (s *streamCompressor) Decompress(data []byte, size int) ([]byte, error) {
+	reader, err := s.reader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress with %v with error %v", s.name, err)
+	}
+	defer reader.Close()
+	// reading one byte more than the expected size detects payloads that decompress to more data
+	decompressed, err := io.ReadAll(io.LimitReader(reader, int64(size)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress with %v with error %v", s.name, err)
+	}
+	if len(decompressed) > size {
+		return nil, fmt.Errorf("failed to decompress with %v: the payload decompresses to more than %v bytes", s.name, size)
+	}
+	return decompressed, nil
+}
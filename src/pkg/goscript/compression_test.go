@@ -0,0 +1,183 @@
+package goscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	for _, name := range []string{"none", "flate", "zlib", "gzip"} {
+		compression, err := CompressionByName(name)
+		if err != nil {
+			t.Fatalf("compression %v should be available but got %v", name, err)
+		}
+		expectValue(compression.String(), name)
+		prog := workspacePrograms(t)["fib.gs"]
+		buff, err := EncodeContainer("fib", prog, ContainerOptions{Compression: compression})
+		if err != nil {
+			t.Fatalf("encoding with %v failed with error %v", name, err)
+		}
+		container, err := DecodeContainer(buff)
+		if err != nil {
+			t.Fatalf("decoding with %v failed with error %v", name, err)
+		}
+		expectValue(container.Compression, compression)
+		expectValue(container.Flags&CF_COMPRESSED != 0, compression != CT_NONE)
+		ret, err := NewRuntime().Exec(*container.Program)
+		if err != nil {
+			t.Fatalf("execution failed with error %v", err)
+		}
+		expectValue(*ret.Value.(*uint64), uint64(6765))
+		fmt.Printf("%v: %v bytes\n", name, len(buff))
+	}
+	if _, err := CompressionByName("lzma"); err == nil {
+		t.Fatalf("an unknown compression should be rejected")
+	}
+}
+
+func TestCompressionUnavailable(t *testing.T) {
+	prog := workspacePrograms(t)["fib.gs"]
+	if _, err := EncodeContainer("fib", prog, ContainerOptions{Compression: CT_ZSTD}); err == nil {
+		t.Fatalf("encoding with an unregistered compression should fail")
+	}
+	// a program compressed with a codec this runtime does not have must be rejected as incompatible
+	testCompression := CompressionType(200)
+	RegisterCompressor(testCompression, &reverseCompressor{})
+	buff, err := EncodeContainer("fib", prog, ContainerOptions{Compression: testCompression})
+	delete(compressors, testCompression)
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	_, err = DecodeContainer(buff)
+	if !errors.Is(err, ErrIncompatibleContainer) {
+		t.Fatalf("decoding with an unregistered compression should fail with an incompatibility error but got %v", err)
+	}
+	fmt.Println(err)
+	expectPanic(func() { RegisterCompressor(CT_GZIP, &reverseCompressor{}) })
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	testCompression := CompressionType(201)
+	RegisterCompressor(testCompression, &reverseCompressor{})
+	defer delete(compressors, testCompression)
+	compression, err := CompressionByName("reverse")
+	if err != nil || compression != testCompression {
+		t.Fatalf("the registered compressor should be found by its name but got %v with error %v", compression, err)
+	}
+	buff, err := EncodeContainer("fib", workspacePrograms(t)["fib.gs"], ContainerOptions{Compression: compression})
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	container, err := DecodeContainer(buff)
+	if err != nil {
+		t.Fatalf("decoding failed with error %v", err)
+	}
+	expectValue(container.Compression, testCompression)
+}
+
+func TestDecompressionLimit(t *testing.T) {
+	// a payload of zeros compresses to a tiny fraction of its size
+	bomb := mustCompress(t, CT_FLATE, make([]byte, 16<<20))
+	for _, size := range []uint64{0, 1 << 10, MAX_DECOMPRESSED_SIZE + 1} {
+		checksum := sha256.Sum256(bomb)
+		buff, err := encodeContainer(BYTECODE_FORMAT_VERSION, &encoding.ContainerHeader{
+			CompilerVersion:  COMPILER_VERSION,
+			Flags:            uint32(CF_COMPRESSED),
+			Checksum:         checksum[:],
+			Compression:      uint32(CT_FLATE),
+			UncompressedSize: size,
+		}, bomb, nil)
+		if err != nil {
+			t.Fatalf("encoding failed with error %v", err)
+		}
+		_, err = DecodeContainer(buff)
+		if err == nil {
+			t.Fatalf("a payload that decompresses to more than %v bytes should be rejected", size)
+		}
+		fmt.Println(err)
+	}
+	if _, err := decompress(CT_GZIP, mustCompress(t, CT_GZIP, []byte("short")), 10); !errors.Is(err, ErrIncompatibleContainer) {
+		t.Fatalf("a payload that decompresses to less than the recorded size should be rejected but got %v", err)
+	}
+}
+
+func mustCompress(t *testing.T, compression CompressionType, data []byte) []byte {
+	buff, err := compress(compression, data)
+	if err != nil {
+		t.Fatalf("compression failed with error %v", err)
+	}
+	return buff
+}
+
+// reverseCompressor reverses the payload, which is enough to check that it is applied and detected
+type reverseCompressor struct{}
+
+func (r *reverseCompressor) Name() string {
+	return "reverse"
+}
+
+func (r *reverseCompressor) Compress(data []byte) ([]byte, error) {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+	return reversed, nil
+}
+
+func (r *reverseCompressor) Decompress(data []byte, size int) ([]byte, error) {
+	return r.Compress(data)
+}
+
+// BenchmarkCompression reports the size and speed of every compressor over the workspace programs
+func BenchmarkCompression(b *testing.B) {
+	programs := workspacePrograms(b)
+	names := make([]string, 0, len(programs))
+	for name := range programs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	payloads := make([][]byte, 0, len(programs))
+	total := 0
+	for _, name := range names {
+		payload, err := EncodeProgram(programs[name])
+		if err != nil {
+			b.Fatalf("encoding %v failed with error %v", name, err)
+		}
+		payloads = append(payloads, payload)
+		total += len(payload)
+	}
+	for _, compression := range []CompressionType{CT_FLATE, CT_ZLIB, CT_GZIP} {
+		b.Run(compression.String(), func(b *testing.B) {
+			compressed := 0
+			var decompressTime time.Duration
+			b.SetBytes(int64(total))
+			for i := 0; i < b.N; i++ {
+				compressed = 0
+				for _, payload := range payloads {
+					buff, err := compress(compression, payload)
+					if err != nil {
+						b.Fatalf("compression failed with error %v", err)
+					}
+					compressed += len(buff)
+					start := time.Now()
+					decompressed, err := decompress(compression, buff, uint64(len(payload)))
+					decompressTime += time.Since(start)
+					if err != nil || !bytes.Equal(decompressed, payload) {
+						b.Fatalf("decompression is not lossless, error %v", err)
+					}
+				}
+			}
+			b.ReportMetric(float64(total), "raw-bytes")
+			b.ReportMetric(float64(compressed), "compressed-bytes")
+			b.ReportMetric(float64(compressed)/float64(total), "ratio")
+			b.ReportMetric(float64(decompressTime.Nanoseconds())/float64(b.N), "decompress-ns/op")
+		})
+	}
+}
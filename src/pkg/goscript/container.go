@@ -0,0 +1,318 @@
+package goscript
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+/*
+	Compiled programs are distributed in a container, which allows the runtime to reject files that it cannot execute
+	before it attempts to decode them.
+
+	[0:4]    magic bytes "GSBC"
+	[4:6]    bytecode format version (uint16, little endian)
+	[6:10]   length of the header (uint32, little endian)
+	[10:n]   header (protobuf ContainerHeader), holding the compiler version, application name, flags, the codec and
+	         compression of the payload (see codec.go and compression.go), the size of the payload before it was
+	         compressed, the sha256 of the payload as it is stored and the public key of the signer
+	[n:m]    ed25519 signature of [0:n] if CF_SIGNED is set (see signing.go), otherwise empty
+	[m:]     payload (the program encoded by the codec, compressed if CF_COMPRESSED is set)
+
+	The format version must be incremented whenever the encoding of the header or the payload changes incompatibly.
+*/
+
+// containerMagic are the bytes every container begins with
+const containerMagic = "GSBC"
+
+// BYTECODE_FORMAT_VERSION is the version of the container and program encoding produced and accepted by this package
+const BYTECODE_FORMAT_VERSION uint16 = 1
+
+// COMPILER_VERSION is the version of the compiler that is recorded in every container
+const COMPILER_VERSION = "0.1"
+
+// containerPreambleSize is the size of the magic bytes, the format version and the header length
+const containerPreambleSize = 10
+
+type ContainerFlags uint32
+
+const (
+	CF_DEBUG_INFO ContainerFlags = 1 << 0 // the program contains debug info
+	CF_COMPRESSED ContainerFlags = 1 << 1 // the payload is compressed with the compression specified in the header
+	CF_CODEC      ContainerFlags = 1 << 2 // the payload is encoded with the codec specified in the header instead of DEFAULT_CODEC
+	CF_SIGNED     ContainerFlags = 1 << 3 // the header is signed by the public key it contains
+)
+
+// CF_ALL contains all flags known to this runtime, containers with other flags are rejected
+const CF_ALL = CF_DEBUG_INFO | CF_COMPRESSED | CF_CODEC | CF_SIGNED
+
+func (f ContainerFlags) String() string {
+	names := []string{}
+	for _, flag := range []struct {
+		flag ContainerFlags
+		name string
+	}{{CF_DEBUG_INFO, "DEBUG_INFO"}, {CF_COMPRESSED, "COMPRESSED"}, {CF_CODEC, "CODEC"}, {CF_SIGNED, "SIGNED"}} {
+		if f&flag.flag != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	if unknown := f &^ CF_ALL; unknown != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint32(unknown)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// ErrIncompatibleContainer is wrapped by all errors returned for containers that this runtime cannot execute
+var ErrIncompatibleContainer = errors.New("incompatible program")
+
+// Container is a compiled program together with the information required to check if it can be executed
+type Container struct {
+	FormatVersion   uint16
+	CompilerVersion string
+	Application     string // the name from the application directive of the main file
+	Flags           ContainerFlags
+	Codec           string
+	Compression     CompressionType
+	Signer          ed25519.PublicKey // the key that signed the container, nil if it is unsigned
+	Checksum        []byte            // the sha256 of the payload as it is stored
+	PayloadSize     int               // the size of the payload as it is stored
+	Program         *Program
+}
+
+// ContainerOptions control how a program is stored in a container
+type ContainerOptions struct {
+	Codec       Codec              // the DEFAULT_CODEC is used if nil
+	Compression CompressionType    // CT_NONE stores the payload uncompressed
+	SigningKey  ed25519.PrivateKey // the container is signed with this key if it is not nil
+}
+
+// EncodeContainer encodes the program into a container of the current format version
+func EncodeContainer(application string, program *Program, options ContainerOptions) ([]byte, error) {
+	flags := ContainerFlags(0)
+	codec := options.Codec
+	if codec == nil {
+		codec = codecs[DEFAULT_CODEC]
+	}
+	codecName := ""
+	if codec.Name() != DEFAULT_CODEC {
+		flags |= CF_CODEC
+		codecName = codec.Name()
+	}
+	payload, err := encodeWith(codec, program)
+	if err != nil {
+		return nil, err
+	}
+	if program.Debug != nil {
+		flags |= CF_DEBUG_INFO
+	}
+	uncompressedSize := uint64(len(payload))
+	if options.Compression != CT_NONE {
+		flags |= CF_COMPRESSED
+		payload, err = compress(options.Compression, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	checksum := sha256.Sum256(payload)
+	header := &encoding.ContainerHeader{
+		CompilerVersion: COMPILER_VERSION,
+		Application:     application,
+		Flags:           uint32(flags),
+		Checksum:        checksum[:],
+		Compression:     uint32(options.Compression),
+		Codec:           codecName,
+	}
+	if flags&CF_COMPRESSED != 0 {
+		header.UncompressedSize = uncompressedSize
+	}
+	return encodeContainer(BYTECODE_FORMAT_VERSION, header, payload, options.SigningKey)
+}
+
+// encodeContainer encodes the container, signing it with the key if it is not nil
+func encodeContainer(version uint16, header *encoding.ContainerHeader, payload []byte, key ed25519.PrivateKey) ([]byte, error) {
+	if key != nil {
+		header.Flags |= uint32(CF_SIGNED)
+		header.PublicKey = key.Public().(ed25519.PublicKey)
+	}
+	encHeader, err := proto.MarshalOptions{Deterministic: true}.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode container header with error %v", err)
+	}
+	buff := make([]byte, containerPreambleSize, containerPreambleSize+len(encHeader)+ed25519.SignatureSize+len(payload))
+	copy(buff, containerMagic)
+	binary.LittleEndian.PutUint16(buff[4:6], version)
+	binary.LittleEndian.PutUint32(buff[6:10], uint32(len(encHeader)))
+	buff = append(buff, encHeader...)
+	if key != nil {
+		// the signature covers everything before it
+		buff = append(buff, ed25519.Sign(key, buff)...)
+	}
+	return append(buff, payload...), nil
+}
+
+// DecodeContainer checks that the container is compatible with this runtime and intact, then decodes its program.
+// The signature of signed containers is verified, but any signer is accepted.
+func DecodeContainer(buff []byte) (*Container, error) {
+	return DecodeTrustedContainer(buff, TrustPolicy{})
+}
+
+// DecodeTrustedContainer decodes the container like DecodeContainer if it is accepted by the policy
+func DecodeTrustedContainer(buff []byte, policy TrustPolicy) (*Container, error) {
+	version, err := containerVersion(buff)
+	if err != nil {
+		return nil, err
+	}
+	if version != BYTECODE_FORMAT_VERSION {
+		return nil, fmt.Errorf("%w: bytecode format version %v is not supported by this runtime, which requires version %v. recompile the program with a matching compiler",
+			ErrIncompatibleContainer, version, BYTECODE_FORMAT_VERSION)
+	}
+	raw, err := parseContainer(buff)
+	if err != nil {
+		return nil, err
+	}
+	container := raw.container()
+	if container.Flags&^CF_ALL != 0 {
+		return nil, fmt.Errorf("%w: the program was compiled by gsc %v with flags %b, which are not supported by this runtime",
+			ErrIncompatibleContainer, container.CompilerVersion, container.Flags&^CF_ALL)
+	}
+	if err := raw.verifySignature(); err != nil {
+		return nil, err
+	}
+	if err := policy.checkSigner(container.Signer); err != nil {
+		return nil, err
+	}
+	if err := raw.verifyChecksum(); err != nil {
+		return nil, err
+	}
+	payload, err := decompress(container.Compression, raw.payload, raw.header.UncompressedSize)
+	if err != nil {
+		return nil, err
+	}
+	codec := codecs[container.Codec]
+	if codec == nil {
+		return nil, fmt.Errorf("%w: the program is encoded with codec %v, which is not available in this runtime", ErrIncompatibleContainer, container.Codec)
+	}
+	if container.Program, err = codec.Decode(bytes.NewReader(payload)); err != nil {
+		return nil, err
+	}
+	return container, nil
+}
+
+// ContainerInspection describes a container without decoding its program, see InspectContainer
+type ContainerInspection struct {
+	Container        *Container // the Program is nil, the Signer is the key in the header even if the signature is invalid
+	UncompressedSize uint64     // the size of the payload before it was compressed, 0 if it is not compressed
+	ChecksumError    error      // nil if the payload matches the checksum in the header
+	SignatureError   error      // nil if the container is not signed or its signature is valid
+}
+
+// InspectContainer decodes the preamble and the header of the container and verifies its checksum and signature, but
+// reports their failures instead of returning them, so containers that the runtime refuses can still be inspected.
+// The format version is not checked.
+func InspectContainer(buff []byte) (*ContainerInspection, error) {
+	raw, err := parseContainer(buff)
+	if err != nil {
+		return nil, err
+	}
+	return &ContainerInspection{
+		Container:        raw.container(),
+		UncompressedSize: raw.header.UncompressedSize,
+		ChecksumError:    raw.verifyChecksum(),
+		SignatureError:   raw.verifySignature(),
+	}, nil
+}
+
+// rawContainer is a container whose preamble and header were decoded, but that was not verified yet
+type rawContainer struct {
+	version   uint16
+	header    *encoding.ContainerHeader
+	preamble  []byte // the bytes covered by the signature, ending with the encoded header
+	signature []byte // nil if the container is not signed or the signature is truncated
+	payload   []byte
+}
+
+// containerVersion checks the magic bytes of the container and returns its format version
+func containerVersion(buff []byte) (uint16, error) {
+	if len(buff) < containerPreambleSize || !bytes.Equal(buff[:4], []byte(containerMagic)) {
+		return 0, fmt.Errorf("%w: not a goscript program", ErrIncompatibleContainer)
+	}
+	return binary.LittleEndian.Uint16(buff[4:6]), nil
+}
+
+// parseContainer splits the container into its preamble, signature and payload and decodes its header
+func parseContainer(buff []byte) (*rawContainer, error) {
+	version, err := containerVersion(buff)
+	if err != nil {
+		return nil, err
+	}
+	headerLength := binary.LittleEndian.Uint32(buff[6:10])
+	if uint64(headerLength) > uint64(len(buff)-containerPreambleSize) {
+		return nil, fmt.Errorf("%w: the header is truncated", ErrIncompatibleContainer)
+	}
+	headerEnd := containerPreambleSize + int(headerLength)
+	header := &encoding.ContainerHeader{}
+	if err := proto.Unmarshal(buff[containerPreambleSize:headerEnd], header); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode the header with error %v", ErrIncompatibleContainer, err)
+	}
+	raw := &rawContainer{version: version, header: header, preamble: buff[:headerEnd], payload: buff[headerEnd:]}
+	if ContainerFlags(header.Flags)&CF_SIGNED != 0 && len(raw.payload) >= ed25519.SignatureSize {
+		raw.signature = raw.payload[:ed25519.SignatureSize]
+		raw.payload = raw.payload[ed25519.SignatureSize:]
+	}
+	return raw, nil
+}
+
+// container returns the container described by the header, without its program
+func (r *rawContainer) container() *Container {
+	flags := ContainerFlags(r.header.Flags)
+	container := &Container{
+		FormatVersion:   r.version,
+		CompilerVersion: r.header.CompilerVersion,
+		Application:     r.header.Application,
+		Flags:           flags,
+		Codec:           DEFAULT_CODEC,
+		Compression:     CT_NONE,
+		Checksum:        r.header.Checksum,
+		PayloadSize:     len(r.payload),
+	}
+	if flags&CF_CODEC != 0 {
+		container.Codec = r.header.Codec
+	}
+	if flags&CF_COMPRESSED != 0 {
+		container.Compression = CompressionType(r.header.Compression)
+	}
+	if flags&CF_SIGNED != 0 {
+		container.Signer = ed25519.PublicKey(r.header.PublicKey)
+	}
+	return container
+}
+
+// verifySignature verifies the signature of a signed container, unsigned containers are always valid
+func (r *rawContainer) verifySignature() error {
+	if ContainerFlags(r.header.Flags)&CF_SIGNED == 0 {
+		return nil
+	}
+	if r.signature == nil {
+		return fmt.Errorf("%w: the signature is truncated", ErrUntrustedProgram)
+	}
+	return verifyContainerSignature(r.preamble, r.header, r.signature)
+}
+
+// verifyChecksum checks that the payload matches the checksum in the header
+func (r *rawContainer) verifyChecksum() error {
+	checksum := sha256.Sum256(r.payload)
+	if !bytes.Equal(checksum[:], r.header.Checksum) {
+		return fmt.Errorf("%w: the checksum of the program does not match, the file is corrupted", ErrIncompatibleContainer)
+	}
+	return nil
+}
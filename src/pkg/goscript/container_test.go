@@ -0,0 +1,93 @@
+package goscript
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
+)
+
+func compileContainer(t *testing.T, file string) []byte {
+	compiler := NewCompiler()
+	prog, err := compiler.Compile(CompileJob{
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, file),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
+	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	buff, err := EncodeContainer(compiler.Application(), prog, ContainerOptions{})
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	return buff
+}
+
+func TestContainerRoundTrip(t *testing.T) {
+	container, err := DecodeContainer(compileContainer(t, "fib.gs"))
+	if err != nil {
+		t.Fatalf("decoding failed with error %v", err)
+	}
+	expectValue(container.FormatVersion, BYTECODE_FORMAT_VERSION)
+	expectValue(container.CompilerVersion, COMPILER_VERSION)
+	expectValue(container.Application, "fib")
+	expectValue(container.Flags, CF_DEBUG_INFO)
+	expectValue(container.PayloadSize > 0, true)
+	expectLength(container.Checksum, sha256.Size, "checksum")
+	ret, err := NewRuntime().Exec(*container.Program)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectValue(*ret.Value.(*uint64), uint64(6765))
+}
+
+func TestContainerFlagsString(t *testing.T) {
+	expectValue(ContainerFlags(0).String(), "none")
+	expectValue((CF_DEBUG_INFO | CF_SIGNED).String(), "DEBUG_INFO|SIGNED")
+	expectValue((CF_COMPRESSED | 1<<7).String(), "COMPRESSED|0x80")
+}
+
+func TestContainerIncompatible(t *testing.T) {
+	valid := compileContainer(t, "fib.gs")
+	headerLength := binary.LittleEndian.Uint32(valid[6:10])
+	payload := valid[containerPreambleSize+int(headerLength):]
+	checksum := sha256.Sum256(payload)
+	// a container of the next format version
+	newer := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint16(newer[4:6], BYTECODE_FORMAT_VERSION+1)
+	// a container using a feature this runtime does not know about
+	unknownFlags, err := encodeContainer(BYTECODE_FORMAT_VERSION, &encoding.ContainerHeader{
+		CompilerVersion: "99.0",
+		Flags:           uint32(CF_ALL) + 1,
+		Checksum:        checksum[:],
+	}, payload, nil)
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	// a container whose payload was modified
+	corrupted := append([]byte{}, valid...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	invalid := map[string][]byte{
+		"empty":          {},
+		"bare program":   payload,
+		"newer version":  newer,
+		"unknown flags":  unknownFlags,
+		"corrupted":      corrupted,
+		"truncated":      valid[:len(valid)-1],
+		"no header":      valid[:containerPreambleSize],
+		"invalid length": append(append([]byte{}, valid[:6]...), 0xff, 0xff, 0xff, 0xff),
+	}
+	for name, buff := range invalid {
+		_, err := DecodeContainer(buff)
+		if !errors.Is(err, ErrIncompatibleContainer) {
+			t.Fatalf("decoding the %v container should fail with an incompatibility error but got %v", name, err)
+		}
+		fmt.Printf("%v: %v\n", name, err)
+	}
+}
@@ -0,0 +1,193 @@
+package goscript
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DebugInfo maps the bytecode of a program back to the source it was compiled from.
+// It is optional and can be omitted from production builds using StripDebugInfo.
+type DebugInfo struct {
+	Functions []FunctionInfo // the functions of the program, ordered by their base address
+	Lines     []LineInfo     // the line table of the program, ordered by pc
+	Symbols   []SymbolInfo   // the original names of the symbols of every function
+}
+
+// FunctionInfo describes the range of operations a function was compiled to
+type FunctionInfo struct {
+	Name string
+	Base int    // the pc of the first operation of the function
+	End  int    // the pc after the last operation of the function
+	File string // the source file the function was declared in, empty if unknown
+	Line int    // the line the function was declared on, 0 if unknown
+}
+
+// LineInfo specifies that the operations beginning at PC were compiled from Line, until the next entry of the line table
+type LineInfo struct {
+	PC   int
+	Line int
+}
+
+// SymbolInfo maps the index of a symbol in the call frame of a function to the name it was declared with
+type SymbolInfo struct {
+	Function string
+	Index    int
+	Name     string
+}
+
+// StripDebugInfo removes the debug info from the program, which makes it smaller but runtime errors will only report the pc
+func (p *Program) StripDebugInfo() {
+	p.Debug = nil
+}
+
+// FunctionAt returns the name of the function the operation at pc belongs to
+func (d *DebugInfo) FunctionAt(pc int) string {
+	if function := d.functionInfoAt(pc); function != nil {
+		return function.Name
+	}
+	return fmt.Sprintf("<unknown function at [%v]>", pc)
+}
+
+// functionInfoAt returns the function the operation at pc belongs to or nil if it is unknown
+func (d *DebugInfo) functionInfoAt(pc int) *FunctionInfo {
+	if d == nil {
+		return nil
+	}
+	for i := range d.Functions {
+		if pc >= d.Functions[i].Base && pc < d.Functions[i].End {
+			return &d.Functions[i]
+		}
+	}
+	return nil
+}
+
+// LineAt returns the source line the operation at pc was compiled from or 0 if it is unknown
+func (d *DebugInfo) LineAt(pc int) int {
+	function := d.functionInfoAt(pc)
+	if function == nil {
+		return 0
+	}
+	line := 0
+	for _, entry := range d.Lines {
+		if entry.PC > pc {
+			break
+		}
+		// entries of the previous functions do not apply
+		if entry.PC >= function.Base {
+			line = entry.Line
+		}
+	}
+	return line
+}
+
+// PositionAt returns the source position of the operation at pc as file:line or an empty string if it is unknown
+func (d *DebugInfo) PositionAt(pc int) string {
+	function := d.functionInfoAt(pc)
+	line := d.LineAt(pc)
+	if function == nil || function.File == "" || line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v:%v", function.File, line)
+}
+
+// SymbolName returns the name of the symbol at the index in the call frame of the function or an empty string if it is unknown
+func (d *DebugInfo) SymbolName(function string, index int) string {
+	if d != nil {
+		for _, symbol := range d.Symbols {
+			if symbol.Function == function && symbol.Index == index {
+				return symbol.Name
+			}
+		}
+	}
+	return ""
+}
+
+// functionSource maps the lines of the body of a function, as the tokenizer sees them, to the lines of its source file
+type functionSource struct {
+	File  string
+	Line  int   // the line the function is declared on
+	Lines []int // the source line of every line of the body
+}
+
+var FUNC_DECLARATION_REGEX = regexp.MustCompile(`^func ([a-zA-Z_][a-zA-Z0-9_]*)\(`)
+
+// mapSourceLines maps the lines of all functions of the application by the name they will have after preprocessing
+func mapSourceLines(source *ApplicationSource) map[string]*functionSource {
+	functions := make(map[string]*functionSource)
+	mapFileLines(functions, source.ApplicationFile, "0", "main")
+	for _, mod := range source.Modules {
+		for _, file := range mod.Files {
+			mapFileLines(functions, file, mod.Hash, mod.Name)
+		}
+	}
+	return functions
+}
+
+func mapFileLines(functions map[string]*functionSource, file SourceFile, prefix string, name string) {
+	var current *functionSource
+	// the content has already been trimmed and stripped of comments, which preserves the line numbers
+	for idx, line := range strings.Split(file.Content, "\n") {
+		if match := FUNC_DECLARATION_REGEX.FindStringSubmatch(line); match != nil {
+			current = &functionSource{File: file.Path, Line: idx + 1}
+			functions[fmt.Sprintf("#fn_%v_%v_%v", prefix, name, match[1])] = current
+			continue
+		}
+		// the preprocessor deletes empty lines, so they are not part of the body
+		if current != nil && line != "" {
+			current.Lines = append(current.Lines, idx+1)
+		}
+	}
+}
+
+// RuntimeError is returned by the runtime when the execution of a program fails
+type RuntimeError struct {
+	PC         int      // the pc of the operation that failed
+	Operation  string   // the operation that failed
+	StackTrace []string // the names of the functions on the call stack, beginning with the function that failed
+	Cause      error
+}
+
+func (e *RuntimeError) Error() string {
+	ret := fmt.Sprintf("runtime error at [%v] %v: %v", e.PC, e.Operation, e.Cause)
+	for _, function := range e.StackTrace {
+		ret += fmt.Sprintf("\n\tat %v", function)
+	}
+	return ret
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Cause
+}
+
+// newRuntimeError converts a value that was recovered from a panic into a RuntimeError for the current state of the runtime
+func (r *Runtime) newRuntimeError(recovered any) *RuntimeError {
+	cause, ok := recovered.(error)
+	if !ok {
+		cause = errors.New(fmt.Sprint(recovered))
+	}
+	operation := "<invalid pc>"
+	if r.ProgramCounter >= 0 && r.ProgramCounter < len(r.Program.Operations) {
+		operation = r.Program.Operations[r.ProgramCounter].String()
+	}
+	// the failing function is followed by its callers, which are waiting at the pc they made the call from
+	stackTrace := []string{r.Program.Debug.describe(r.ProgramCounter)}
+	for i := len(r.CallStack) - 1; i >= 0; i-- {
+		stackTrace = append(stackTrace, r.Program.Debug.describe(r.CallStack[i].ReturnPC))
+	}
+	return &RuntimeError{
+		PC:         r.ProgramCounter,
+		Operation:  operation,
+		StackTrace: stackTrace,
+		Cause:      cause,
+	}
+}
+
+// describe returns the name of the function the operation at pc belongs to, followed by its source position if it is known
+func (d *DebugInfo) describe(pc int) string {
+	if position := d.PositionAt(pc); position != "" {
+		return fmt.Sprintf("%v (%v)", d.FunctionAt(pc), position)
+	}
+	return d.FunctionAt(pc)
+}
@@ -0,0 +1,82 @@
+package goscript
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugInfo(t *testing.T) {
+	prog, err := NewCompiler().Compile(CompileJob{
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, "debug.gs"),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
+	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	fmt.Println(prog)
+	fmt.Printf("%+v\n", *prog.Debug)
+	expectLength(prog.Debug.Functions, 2, "both functions should be described")
+	expectValue(prog.Debug.Functions[1].Name, "#fn_0_main_divide")
+	expectValue(prog.Debug.Functions[1].Line, 10)
+	// the lines of the source file are preserved, even though comments and empty lines are removed by the preprocessor
+	expectValue(prog.Debug.LineAt(0), 3)
+	expectValue(prog.Debug.LineAt(prog.Debug.Functions[1].Base-1), 7)
+	expectValue(prog.Debug.LineAt(prog.Debug.Functions[1].Base), 11)
+	// the symbols are reported by the names they were declared with
+	expectValue(prog.Debug.SymbolName("#fn_0_main_main", 0), "divisor")
+	expectValue(prog.Debug.SymbolName("#fn_0_main_divide", 1), "b")
+	expectValue(prog.Debug.SymbolName("#fn_0_main_divide", 3), "")
+	// runtime errors report the source position of every frame
+	_, err = NewRuntime().Exec(*prog)
+	if err == nil {
+		t.Fatalf("execution should have failed")
+	}
+	fmt.Println(err)
+	runtimeErr := err.(*RuntimeError)
+	expectLength(runtimeErr.StackTrace, 2, "the stack trace should contain divide and main")
+	if !strings.HasSuffix(runtimeErr.StackTrace[0], "debug.gs:11)") || !strings.HasSuffix(runtimeErr.StackTrace[1], "debug.gs:6)") {
+		t.Fatalf("unexpected stack trace %v", runtimeErr.StackTrace)
+	}
+}
+
+func TestStripDebugInfo(t *testing.T) {
+	encoded := [][]byte{}
+	hashes := [][]byte{}
+	for _, strip := range []bool{false, true} {
+		prog, err := NewCompiler().Compile(CompileJob{
+			MainFilePath:       filepath.Join(TEST_WORKSPACE, "debug.gs"),
+			LocalWorkspaceRoot: TEST_WORKSPACE,
+			VendorPath:         TEST_VENDOR,
+			StandardLibPath:    TEST_STANDARD,
+			StripDebugInfo:     strip,
+		})
+		if err != nil {
+			t.Fatalf("compilation failed with error %v", err)
+		}
+		if strip && prog.Debug != nil {
+			t.Fatalf("the debug info should have been stripped")
+		}
+		buff, err := EncodeProgram(prog)
+		if err != nil {
+			t.Fatalf("encoding failed with error %v", err)
+		}
+		hash, err := HashProgram(prog)
+		if err != nil {
+			t.Fatalf("hashing failed with error %v", err)
+		}
+		encoded = append(encoded, buff)
+		hashes = append(hashes, hash)
+	}
+	if len(encoded[1]) >= len(encoded[0]) {
+		t.Fatalf("stripping should reduce the size of the program from %v bytes, got %v bytes", len(encoded[0]), len(encoded[1]))
+	}
+	// stripping does not change the identity of the program
+	if !bytes.Equal(hashes[0], hashes[1]) {
+		t.Fatalf("stripping changed the program hash from %x to %x", hashes[0], hashes[1])
+	}
+}
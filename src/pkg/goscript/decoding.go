@@ -0,0 +1,270 @@
+package goscript
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+// argKind is the kind of value an argument of an operation holds, it determines how the argument is decoded
+type argKind byte
+
+const (
+	AK_INT        argKind = 1 // symbol references, jump targets and amounts
+	AK_TYPE       argKind = 2
+	AK_EXPRESSION argKind = 3
+)
+
+// operationArgs specifies the arguments of every operation, in the order in which the New*Op functions create them
+var operationArgs = map[OperationType][]argKind{
+	ASSIGN:       {AK_INT, AK_EXPRESSION},
+	INDEX_ASSIGN: {AK_INT, AK_EXPRESSION, AK_EXPRESSION},
+	BIND:         {AK_INT, AK_TYPE},
+	RETURN:       {AK_EXPRESSION},
+	EXPRESSION:   {AK_EXPRESSION},
+	ENTER_SCOPE:  {},
+	EXIT_SCOPE:   {},
+	JUMP:         {AK_INT},
+	JUMP_IF:      {AK_EXPRESSION, AK_INT},
+	JUMP_IF_NOT:  {AK_EXPRESSION, AK_INT},
+	GROW:         {AK_INT, AK_INT, AK_TYPE},
+	SHRINK:       {AK_INT, AK_INT},
+}
+
+// DecodeProgram decodes a program that was encoded by EncodeProgram.
+// If the encoded program contains a hash, the decoded program must match it.
+func DecodeProgram(buff []byte) (*Program, error) {
+	encProg := &encoding.Program{}
+	err := proto.Unmarshal(buff, encProg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode program with error %v", err)
+	}
+	prog := &Program{
+		SymbolTableSize: int(encProg.SymbolTableSize),
+		Operations:      make([]BinaryOperation, 0, len(encProg.Operations)),
+		Debug:           decodeDebugInfo(encProg.Debug),
+	}
+	for pc, encOp := range encProg.Operations {
+		op, err := decodeOp(encOp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode operation %v with error %v", pc, err)
+		}
+		prog.Operations = append(prog.Operations, op)
+	}
+	// verify that the program is the one that was encoded
+	if len(encProg.Hash) > 0 {
+		hash, err := HashProgram(prog)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(hash, encProg.Hash) {
+			return nil, fmt.Errorf("program hash %x does not match the encoded hash %x", hash, encProg.Hash)
+		}
+	}
+	return prog, nil
+}
+
+func decodeOp(encOp *encoding.BinaryOperation) (BinaryOperation, error) {
+	op := BinaryOperation{Type: OperationType(encOp.Type)}
+	kinds, ok := operationArgs[op.Type]
+	if !ok {
+		return op, fmt.Errorf("unknown operation type %v", encOp.Type)
+	}
+	if len(encOp.Args) != len(kinds) {
+		return op, fmt.Errorf("operation type %v expects %v arguments but has %v", encOp.Type, len(kinds), len(encOp.Args))
+	}
+	op.Args = make([]any, len(kinds))
+	for i, kind := range kinds {
+		switch arg := encOp.Args[i].GetValue().(type) {
+		case *encoding.Argument_Int:
+			if kind != AK_INT {
+				return op, fmt.Errorf("argument %v of operation type %v must not be an integer", i, encOp.Type)
+			}
+			op.Args[i] = int(arg.Int)
+		case *encoding.Argument_Type:
+			if kind != AK_TYPE {
+				return op, fmt.Errorf("argument %v of operation type %v must not be a type", i, encOp.Type)
+			}
+			op.Args[i] = BinaryType(arg.Type)
+		case *encoding.Argument_Expression:
+			if kind != AK_EXPRESSION {
+				return op, fmt.Errorf("argument %v of operation type %v must not be an expression", i, encOp.Type)
+			}
+			expr, err := decodeExpr(arg.Expression)
+			if err != nil {
+				return op, err
+			}
+			op.Args[i] = expr
+		default:
+			return op, fmt.Errorf("argument %v of operation type %v is missing", i, encOp.Type)
+		}
+	}
+	return op, nil
+}
+
+func decodeExpr(encExpr *encoding.Expression) (*Expression, error) {
+	if encExpr == nil {
+		return nil, fmt.Errorf("missing expression")
+	}
+	expr := &Expression{
+		Ref:      int(encExpr.Ref),
+		Operator: BinaryOperator(encExpr.Operator),
+	}
+	var err error
+	if encExpr.Value != nil {
+		if expr.Value, err = decodeTypedValue(encExpr.Value); err != nil {
+			return nil, err
+		}
+	}
+	for _, arg := range encExpr.Args {
+		argExpr, err := decodeExpr(arg.Expression)
+		if err != nil {
+			return nil, err
+		}
+		expr.Args = append(expr.Args, &FunctionArgument{
+			Expression: argExpr,
+			SymbolRef:  int(arg.SymbolRef),
+		})
+	}
+	if encExpr.Left != nil {
+		if expr.LeftExpression, err = decodeExpr(encExpr.Left); err != nil {
+			return nil, err
+		}
+	}
+	if encExpr.Right != nil {
+		if expr.RightExpression, err = decodeExpr(encExpr.Right); err != nil {
+			return nil, err
+		}
+	}
+	return expr, nil
+}
+
+// decodeTypedValue converts the variant of the value back to the go type of its BinaryType, see encoding.go
+func decodeTypedValue(encValue *encoding.BinaryTypedValue) (*BinaryTypedValue, error) {
+	value := &BinaryTypedValue{Type: BinaryType(encValue.Type)}
+	var err error
+	switch val := encValue.Value.(type) {
+	case nil:
+		return value, nil
+	case *encoding.BinaryTypedValue_Signed:
+		switch value.Type {
+		case BT_INT8:
+			value.Value, err = convertExact[int8](val.Signed, value.Type)
+		case BT_INT16:
+			value.Value, err = convertExact[int16](val.Signed, value.Type)
+		case BT_INT32:
+			value.Value, err = convertExact[int32](val.Signed, value.Type)
+		case BT_INT64:
+			value.Value = &val.Signed
+		case BT_UNTYPED:
+			value.Value = int(val.Signed)
+		default:
+			err = fmt.Errorf("a value of type %v cannot be a signed integer", value.Type)
+		}
+	case *encoding.BinaryTypedValue_Unsigned:
+		switch value.Type {
+		case BT_UINT8, BT_BYTE:
+			value.Value, err = convertExact[uint8](val.Unsigned, value.Type)
+		case BT_UINT16:
+			value.Value, err = convertExact[uint16](val.Unsigned, value.Type)
+		case BT_UINT32:
+			value.Value, err = convertExact[uint32](val.Unsigned, value.Type)
+		case BT_UINT64:
+			value.Value = &val.Unsigned
+		default:
+			err = fmt.Errorf("a value of type %v cannot be an unsigned integer", value.Type)
+		}
+	case *encoding.BinaryTypedValue_Float:
+		switch value.Type {
+		case BT_FLOAT32:
+			f := float32(val.Float)
+			// NaN is the only value that is not equal to itself after the conversion
+			if float64(f) != val.Float && val.Float == val.Float {
+				err = fmt.Errorf("value %v overflows type %v", val.Float, value.Type)
+			}
+			value.Value = &f
+		case BT_FLOAT64:
+			value.Value = &val.Float
+		default:
+			err = fmt.Errorf("a value of type %v cannot be a float", value.Type)
+		}
+	case *encoding.BinaryTypedValue_Bool:
+		if value.Type != BT_BOOLEAN {
+			return nil, fmt.Errorf("a value of type %v cannot be a boolean", value.Type)
+		}
+		value.Value = &val.Bool
+	case *encoding.BinaryTypedValue_Char:
+		if value.Type != BT_CHAR {
+			return nil, fmt.Errorf("a value of type %v cannot be a char", value.Type)
+		}
+		value.Value = &val.Char
+	case *encoding.BinaryTypedValue_String_:
+		if value.Type != BT_STRING {
+			return nil, fmt.Errorf("a value of type %v cannot be a string", value.Type)
+		}
+		value.Value = &val.String_
+	case *encoding.BinaryTypedValue_List:
+		if value.Type != BT_LIST {
+			return nil, fmt.Errorf("a value of type %v cannot be a list", value.Type)
+		}
+		elements := []*BinaryTypedValue{}
+		for _, encElem := range val.List.GetValues() {
+			elem, err := decodeTypedValue(encElem)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, elem)
+		}
+		value.Value = &elements
+	case *encoding.BinaryTypedValue_Expression:
+		if value.Type != BT_EXPRESSION {
+			return nil, fmt.Errorf("a value of type %v cannot be an expression", value.Type)
+		}
+		value.Value, err = decodeExpr(val.Expression)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// convertExact converts the integer to T, failing if it does not fit into T
+func convertExact[T int8 | int16 | int32 | uint8 | uint16 | uint32, V int64 | uint64](val V, valueType BinaryType) (*T, error) {
+	converted := T(val)
+	if V(converted) != val {
+		return nil, fmt.Errorf("value %v overflows type %v", val, valueType)
+	}
+	return &converted, nil
+}
+
+func decodeDebugInfo(encDebug *encoding.DebugInfo) *DebugInfo {
+	if encDebug == nil {
+		return nil
+	}
+	debug := &DebugInfo{}
+	for _, function := range encDebug.Functions {
+		debug.Functions = append(debug.Functions, FunctionInfo{
+			Name: function.Name,
+			Base: int(function.Base),
+			End:  int(function.End),
+			File: function.File,
+			Line: int(function.Line),
+		})
+	}
+	for _, line := range encDebug.Lines {
+		debug.Lines = append(debug.Lines, LineInfo{
+			PC:   int(line.PC),
+			Line: int(line.Line),
+		})
+	}
+	for _, symbol := range encDebug.Symbols {
+		debug.Symbols = append(debug.Symbols, SymbolInfo{
+			Function: symbol.Function,
+			Index:    int(symbol.Index),
+			Name:     symbol.Name,
+		})
+	}
+	return debug
+}
@@ -0,0 +1,123 @@
+package goscript
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+/*
+	The module graph records which modules an application requires and which modules each of them imports. It is
+	built by resolveDeps while the imports are resolved and can be exported as JSON or as a Graphviz DOT graph, which
+	can be rendered using `dot -Tsvg modules.dot`.
+
+	Modules are listed in the order in which they were resolved, so every module comes after the modules it imports.
+	A module that is imported by several modules is only listed once. Modules that import each other, directly or
+	through other modules, cannot be ordered like this and are reported as an ImportCycleError.
+*/
+
+// ModuleGraph is the graph of the modules required by an application
+type ModuleGraph struct {
+	Application string       // path of the main application file
+	Imports     []string     // import paths of the modules imported by the main application file
+	Modules     []ModuleNode // every required module, after the modules it imports
+}
+
+// ModuleNode is a module in the module graph
+type ModuleNode struct {
+	ImportPath string
+	Root       string   // the root the module was found under, one of ext, std or loc
+	Imports    []string // import paths of the modules imported by this module
+}
+
+// ImportCycleError is returned when modules import each other
+type ImportCycleError struct {
+	Path []string // import paths of the modules in the cycle, beginning and ending with the same module
+}
+
+func (e *ImportCycleError) Error() string {
+	return fmt.Sprintf("import cycle %v", strings.Join(e.Path, " -> "))
+}
+
+// String returns the prefix with which modules under this root are imported
+func (t ModuleSourceRootType) String() string {
+	switch t {
+	case VENDOR:
+		return "ext"
+	case STANDARD:
+		return "std"
+	case LOCAL:
+		return "loc"
+	default:
+		return fmt.Sprintf("ModuleSourceRootType(%d)", t)
+	}
+}
+
+// parseRootType returns the root type of the import prefix ext, std or loc
+func parseRootType(prefix string) (ModuleSourceRootType, error) {
+	switch prefix {
+	case "loc":
+		return LOCAL, nil
+	case "std":
+		return STANDARD, nil
+	case "ext":
+		return VENDOR, nil
+	default:
+		return 0, fmt.Errorf("unrecognized root type %v", prefix)
+	}
+}
+
+// JSON returns the module graph as indented JSON
+func (g *ModuleGraph) JSON() ([]byte, error) {
+	buff, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode module graph as json with error %v", err)
+	}
+	return buff, nil
+}
+
+// DOT returns the module graph as a DOT digraph, with the modules grouped by their root
+func (g *ModuleGraph) DOT() string {
+	out := &strings.Builder{}
+	out.WriteString("digraph modules {\n")
+	out.WriteString("\tnode [shape=box]\n")
+	fmt.Fprintf(out, "\tapp [label=%v shape=doubleoctagon]\n", dotQuote(filepath.Base(g.Application)))
+	for _, root := range []ModuleSourceRootType{VENDOR, STANDARD, LOCAL} {
+		nodes := []ModuleNode{}
+		for _, node := range g.Modules {
+			if node.Root == root.String() {
+				nodes = append(nodes, node)
+			}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\tsubgraph cluster_%v {\n", root)
+		fmt.Fprintf(out, "\t\tlabel=\"%v\"\n", root)
+		for _, node := range nodes {
+			fmt.Fprintf(out, "\t\t%v\n", dotQuote(node.ImportPath))
+		}
+		out.WriteString("\t}\n")
+	}
+	for _, imp := range g.Imports {
+		fmt.Fprintf(out, "\tapp -> %v\n", dotQuote(imp))
+	}
+	for _, node := range g.Modules {
+		for _, imp := range node.Imports {
+			fmt.Fprintf(out, "\t%v -> %v\n", dotQuote(node.ImportPath), dotQuote(imp))
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// appendUnique appends the value to the list unless it already contains it
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -2,51 +2,83 @@ package goscript
 
 import (
 	"fmt"
-	"os"
+	"io/fs"
 	"strings"
 	"time"
 )
 
-func resolveDeps(mainPath string, vendorIndex map[string]*ModuleSource, localIndex map[string]*ModuleSource, standardIndex map[string]*ModuleSource) ([]*ModuleSource, error) {
+// dependencyResolver resolves the modules required by an application and records the module graph
+type dependencyResolver struct {
+	vendorIndex   map[string]*ModuleSource
+	localIndex    map[string]*ModuleSource
+	standardIndex map[string]*ModuleSource
+	resolved      map[string]bool // import paths of the modules whose imports are resolved
+	path          []string        // import paths of the modules that are currently being resolved, used to report cycles
+	modules       []*ModuleSource // the resolved modules, after the modules they import
+	graph         *ModuleGraph
+}
+
+// resolveDeps returns every module required by the application at main path, after the modules it imports, and the
+// module graph of the application. Modules that import each other are reported as an ImportCycleError.
+func resolveDeps(fsys fs.FS, mainPath string, vendorIndex map[string]*ModuleSource, localIndex map[string]*ModuleSource, standardIndex map[string]*ModuleSource) ([]*ModuleSource, *ModuleGraph, error) {
 	start := time.Now()
 	fmt.Println("[GSC][resolveDeps] begin dependency graph resolution")
 	// read the main app file into memory
-	mainContent, err := os.ReadFile(mainPath)
+	mainContent, err := fs.ReadFile(fsys, mainPath)
 	if err != nil {
-		return nil, fmt.Errorf("could not read main application file with error %v", err)
+		return nil, nil, fmt.Errorf("could not read main application file with error %v", err)
 	}
 	// stip comments
 	mainContent = []byte(stripComments(string(mainContent)))
 	// get the direct imports from main
 	directImports, err := getImportsFromSourceText(string(mainContent))
 	if err != nil {
-		return nil, fmt.Errorf("could not get imports from main application with error %v", err)
+		return nil, nil, fmt.Errorf("could not get imports from main application with error %v", err)
 	}
 	fmt.Printf("[GSC][resolveDeps] main file has %v direct dependencies\n", len(directImports))
+	r := &dependencyResolver{
+		vendorIndex:   vendorIndex,
+		localIndex:    localIndex,
+		standardIndex: standardIndex,
+		resolved:      make(map[string]bool),
+		graph:         &ModuleGraph{Application: mainPath, Imports: []string{}, Modules: []ModuleNode{}},
+	}
 	// resolve our direct dependencies
-	dependencies := []*ModuleSource{}
 	for _, directImport := range directImports {
 		// get the direct module reference
 		module, err := findModuleInIndices(directImport, vendorIndex, localIndex, standardIndex)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		r.graph.Imports = appendUnique(r.graph.Imports, module.ImportPath)
 		// resolve transitive dependencies
-		subModules, err := resolveUntilCompletion(module, vendorIndex, localIndex, standardIndex)
-		if err != nil {
-			return nil, err
+		before := len(r.modules)
+		if err := r.resolveUntilCompletion(module); err != nil {
+			return nil, nil, err
 		}
-		dependencies = append(dependencies, subModules...)
-		fmt.Printf("[GSC][resolveDeps] module %v adds %v transitive dependencies\n", module.ImportPath, len(subModules)-1)
+		fmt.Printf("[GSC][resolveDeps] module %v adds %v modules\n", module.ImportPath, len(r.modules)-before)
 	}
 	fmt.Printf("[GSC][STAGE_COMPLETED] resolveDeps completed in %s\n", time.Since(start))
-	for _, dep := range dependencies {
+	for _, dep := range r.modules {
 		fmt.Printf("[GSC][resolveDeps]    %v\n", dep.ImportPath)
 	}
-	return dependencies, nil
+	return r.modules, r.graph, nil
 }
 
-func resolveUntilCompletion(module *ModuleSource, vendorIndex map[string]*ModuleSource, localIndex map[string]*ModuleSource, standardIndex map[string]*ModuleSource) ([]*ModuleSource, error) {
+// resolveUntilCompletion resolves the imports of the module, then adds the module itself. Modules that were already
+// resolved through another import are skipped.
+func (r *dependencyResolver) resolveUntilCompletion(module *ModuleSource) error {
+	if r.resolved[module.ImportPath] {
+		return nil
+	}
+	// a module that is still being resolved imports itself through the modules on the path
+	for i, importPath := range r.path {
+		if importPath == module.ImportPath {
+			cycle := append(append([]string{}, r.path[i:]...), module.ImportPath)
+			return &ImportCycleError{Path: cycle}
+		}
+	}
+	r.path = append(r.path, module.ImportPath)
 	// combine all the sources of this module
 	moduleBlob := ""
 	for _, sourceFile := range module.Files {
@@ -55,26 +87,28 @@ func resolveUntilCompletion(module *ModuleSource, vendorIndex map[string]*Module
 	// get the imports of the current module
 	imports, err := getImportsFromSourceText(moduleBlob)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get imports from module %v", module.ImportPath)
+		return fmt.Errorf("failed to get imports from module %v with error %v", module.ImportPath, err)
 	}
+	node := ModuleNode{ImportPath: module.ImportPath, Root: module.RootType.String(), Imports: []string{}}
 	// recursively resolve their dependencies
-	modules := []*ModuleSource{}
 	for _, imp := range imports {
 		// grab this module from our indices
-		module, err := findModuleInIndices(imp, vendorIndex, localIndex, standardIndex)
+		dependency, err := findModuleInIndices(imp, r.vendorIndex, r.localIndex, r.standardIndex)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("failed to resolve imports of module %v with error %w", module.ImportPath, err)
 		}
-		subModules, err := resolveUntilCompletion(module, vendorIndex, localIndex, standardIndex)
-		if err != nil {
-			return nil, err
+		if err := r.resolveUntilCompletion(dependency); err != nil {
+			return err
 		}
-		modules = append(modules, subModules...)
-		// save this import in the module
+		// save this import in the importing module
 		module.Imports[imp.Alias] = imp
+		node.Imports = appendUnique(node.Imports, dependency.ImportPath)
 	}
-	modules = append(modules, module)
-	return modules, nil
+	r.path = r.path[:len(r.path)-1]
+	r.resolved[module.ImportPath] = true
+	r.modules = append(r.modules, module)
+	r.graph.Modules = append(r.graph.Modules, node)
+	return nil
 }
 
 func getImportsFromSourceText(source string) ([]*ImportDirective, error) {
@@ -86,16 +120,9 @@ func getImportsFromSourceText(source string) ([]*ImportDirective, error) {
 		}
 		parts := strings.SplitN(match[1], "/", 2)
 		fullparts := strings.Split(match[1], "/")
-		rt := ModuleSourceRootType(0)
-		switch parts[0] {
-		case "loc":
-			rt = LOCAL
-		case "std":
-			rt = STANDARD
-		case "ext":
-			rt = VENDOR
-		default:
-			return nil, fmt.Errorf("unrecognized root type %v in import %v", parts[0], match[1])
+		rt, err := parseRootType(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("%v in import %v", err, match[1])
 		}
 		ret = append(ret, &ImportDirective{
 			RootType:     rt,
@@ -112,16 +139,9 @@ func getImportsFromSourceText(source string) ([]*ImportDirective, error) {
 		}
 		parts := strings.SplitN(match[1], "/", 2)
 		fullparts := strings.Split(match[1], "/")
-		rt := ModuleSourceRootType(0)
-		switch parts[0] {
-		case "loc":
-			rt = LOCAL
-		case "std":
-			rt = STANDARD
-		case "ext":
-			rt = VENDOR
-		default:
-			return nil, fmt.Errorf("unrecognized root type %v in import %v", parts[0], match[1])
+		rt, err := parseRootType(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("%v in import %v", err, match[1])
 		}
 		ret = append(ret, &ImportDirective{
 			RootType:     rt,
@@ -134,10 +154,11 @@ func getImportsFromSourceText(source string) ([]*ImportDirective, error) {
 	return ret, nil
 }
 
-// GetRequiredExternals returns the list of all external modules required by the application at main path
-func getRequiredExternals(mainPath string) ([]*ExternalModuleSource, error) {
+// GetRequiredExternals returns the list of all external modules required by the application at main path of the
+// file system
+func getRequiredExternals(fsys fs.FS, mainPath string) ([]*ExternalModuleSource, error) {
 	// read the main file into memory
-	content, err := os.ReadFile(mainPath)
+	content, err := fs.ReadFile(fsys, mainPath)
 	if err != nil {
 		return nil, fmt.Errorf("could not read main application file from %v with error %v", mainPath, err)
 	}
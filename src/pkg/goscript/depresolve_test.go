@@ -1,13 +1,16 @@
 package goscript
 
 import (
-	"path/filepath"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
 )
 
 // TestGetRequiredExternals parses the externals of the specified file
 func TestGetRequiredExternals(t *testing.T) {
-	ret, err := getRequiredExternals(filepath.Join(TESTS, "imports.gs"))
+	ret, err := getRequiredExternals(os.DirFS(TEST_WORKSPACE), "imports.gs")
 	if err != nil {
 		t.Fatalf("got error %v", err)
 	}
@@ -15,3 +18,66 @@ func TestGetRequiredExternals(t *testing.T) {
 		t.Fatalf("expected one externals but got %v", len(ret))
 	}
 }
+
+func TestModuleGraph(t *testing.T) {
+	// a and b both import c, which must only be compiled once
+	workspace := mapFS(map[string]string{
+		"main.gs": "import \"loc/a\"\nimport \"loc/b\"\nimport \"std/math\"\n\nfunc main() {\n    return math.add(a.twice(2), b.thrice(2))\n}",
+		"a/a.gs":  "import \"loc/c\"\n\nfunc twice(x: u64) => u64 {\n    return c.times(x, 2)\n}",
+		"b/b.gs":  "import \"loc/c\"\n\nfunc thrice(x: u64) => u64 {\n    return c.times(x, 3)\n}",
+		"c/c.gs":  "import \"std/math\"\n\nfunc times(x: u64, n: u64) => u64 {\n    return math.mult(x, n)\n}",
+	})
+	compiler := NewCompiler()
+	prog, err := compiler.Compile(CompileJob{
+		MainFilePath:    "main.gs",
+		Workspace:       workspace,
+		StandardLibPath: TEST_STANDARD,
+	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	ret, err := NewRuntime().Exec(*prog)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectValue(*ret.Value.(*uint64), uint64(10))
+	graph := compiler.ModuleGraph()
+	expectValue(fmt.Sprint(graph.Imports), "[loc/a loc/b std/math]")
+	expectLength(graph.Modules, 4, "modules")
+	expectValue(fmt.Sprint(graph.Modules), "[{std/math std []} {loc/c loc [std/math]} {loc/a loc [loc/c]} {loc/b loc [loc/c]}]")
+	dot := graph.DOT()
+	fmt.Println(dot)
+	expectValue(strings.Contains(dot, "\tsubgraph cluster_std {\n\t\tlabel=\"std\"\n\t\t\"std/math\"\n\t}\n"), true)
+	expectValue(strings.Contains(dot, "\tapp -> \"loc/a\"\n"), true)
+	expectValue(strings.Contains(dot, "\t\"loc/b\" -> \"loc/c\"\n"), true)
+	buff, err := graph.JSON()
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	expectValue(strings.Contains(string(buff), `"ImportPath": "loc/c",`), true)
+	expectValue(strings.Contains(string(buff), `"Root": "loc",`), true)
+}
+
+func TestImportCycle(t *testing.T) {
+	_, err := discoverSources(CompileJob{MainFilePath: "main.gs", Workspace: mapFS(map[string]string{
+		"main.gs": "import \"loc/a\"\n\nfunc main() {\n    return a.f()\n}",
+		"a/a.gs":  "import \"loc/b\"\n\nfunc f() => u64 {\n    return b.g()\n}",
+		"b/b.gs":  "import \"loc/c\"\n\nfunc g() => u64 {\n    return c.h()\n}",
+		"c/c.gs":  "import \"loc/b\"\n\nfunc h() => u64 {\n    return b.g()\n}",
+	})})
+	cycle := &ImportCycleError{}
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected an import cycle but got %v", err)
+	}
+	expectValue(strings.Join(cycle.Path, " -> "), "loc/b -> loc/c -> loc/b")
+	expectValue(strings.HasSuffix(err.Error(), "import cycle loc/b -> loc/c -> loc/b"), true)
+	// a module importing itself is the shortest cycle
+	_, err = discoverSources(CompileJob{MainFilePath: "main.gs", Workspace: mapFS(map[string]string{
+		"main.gs": "import \"loc/a\"\n\nfunc main() {\n    return a.f()\n}",
+		"a/a.gs":  "import \"loc/a\"\n\nfunc f() => u64 {\n    return 1\n}",
+	})})
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected an import cycle but got %v", err)
+	}
+	expectValue(strings.Join(cycle.Path, " -> "), "loc/a -> loc/a")
+}
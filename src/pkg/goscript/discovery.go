@@ -4,10 +4,16 @@ import (
 	"encoding/hex"
 	"fmt"
 	"hash/fnv"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/Yoshi-Exeler/goscript/goscript/gs_standard"
 )
 
 type ModuleSourceRootType byte
@@ -38,14 +44,16 @@ type ImportDirective struct {
 }
 
 type SourceFile struct {
-	Path    string                      // path to the source file
+	Path    string                      // path to the main file, or import path of the module followed by the file name
 	Content string                      // content of the source file
 	Imports map[string]*ImportDirective // aliased imports only set in the main file
 }
 
 type ApplicationSource struct {
-	ApplicationFile SourceFile      // path to the main file
-	Modules         []*ModuleSource // list of local and standard libarary modules
+	ApplicationFile SourceFile        // path to the main file
+	Modules         []*ModuleSource   // list of local and standard libarary modules
+	Graph           *ModuleGraph      // the modules imported by the application and each of its modules
+	Externals       []*ExternalModule // the externals that were fetched into the module cache
 }
 
 // ExternalModuleSource will be used by the depgraph resolver to load this module
@@ -61,33 +69,87 @@ var VERSION_TAG_REGEX = regexp.MustCompile(`(?m)@version=([\^0-9\.]*)`)
 var BRANCH_TAG_REGEX = regexp.MustCompile(`(?m)@branch=([0-9a-zA-Z\.]*)`)
 
 // discoverSources will discover source files and parse the imports required by the application
-// at the specified path  If a required external import is not present in the vendor directory,
-// this function will treat that as an error.
-func discoverSources(mainPath string, workspace string) (*ApplicationSource, error) {
+// at the main file path of the job, using the workspace, vendor and standard library roots of the job.
+// Externals that are not vendored are fetched into the module cache of the job, if it has one (see externals.go).
+// If a required external import is neither vendored nor fetched, this function will treat that as an error.
+func discoverSources(job CompileJob) (*ApplicationSource, error) {
 	start := time.Now()
+	roots := rootsOf(job)
 	fmt.Println("[GSC][discoverSources] begin discoverSources")
 	// get the external modules required by our app
-	dependencies, err := getRequiredExternals(mainPath)
+	dependencies, err := getRequiredExternals(roots.main, roots.mainPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get required external modules with error %v", err)
 	}
 	fmt.Printf("[GSC][discoverSources] application requires %v direct external dependencies\n", len(dependencies))
-	// TODO: call external dependency resolver here
-	fmt.Printf("[GSC][discoverSources] begin indexing. local=%v vendor=%v standard=%v\n", workspace, VENDORPATH, STDPATH)
+	standardRoot := job.StandardLibPath
+	if job.Standard == nil && standardRoot == "" {
+		standardRoot = "<embedded>"
+	}
+	fmt.Printf("[GSC][discoverSources] begin indexing. local=%v vendor=%v standard=%v\n", job.LocalWorkspaceRoot, job.VendorPath, standardRoot)
 	// index the vendor directory
-	vendorIndex, err := indexModuleCollection(VENDORPATH, "ext")
+	vendorIndex, err := indexModuleCollection(roots.vendor, "ext")
+	if err != nil {
+		return nil, fmt.Errorf("failed to index vendor directory %v with error %v", job.VendorPath, err)
+	}
+	vendored := make(map[string]bool)
+	for importPath := range vendorIndex {
+		vendored[strings.TrimPrefix(importPath, "ext/")] = true
+	}
+	// the lockfile is read from the same root as the main file, but can only be written on disk
+	lock, err := readLockfile(roots.main, lockfileName(roots.mainPath))
+	if err != nil {
+		return nil, err
+	}
+	// fetch the externals that are not vendored into the module cache
+	externals := []*ExternalModule{}
+	vendoredExternals := []*ExternalModuleSource{}
+	if job.ModuleCachePath != "" {
+		externals, vendoredExternals, err = fetchExternals(job.ModuleCachePath, dependencies, vendored, lock.lockedByName())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// without a module cache only the vendored externals of the application can be used
+		for _, dependency := range dependencies {
+			if vendored[dependency.Name] {
+				vendoredExternals = append(vendoredExternals, dependency)
+			}
+		}
+	}
+	// the lockfile is verified on every compilation, regardless of whether externals were fetched
+	vendoredLocks, err := lockVendored(roots.vendor, vendoredExternals)
+	if err != nil {
+		return nil, err
+	}
+	lockPath := lockfileName(job.MainFilePath)
+	updated, err := verifyLockfile(lock, lockPath, externals, vendoredLocks)
 	if err != nil {
-		return nil, fmt.Errorf("failed to index vendor directory with error %v", err)
+		return nil, err
+	}
+	if job.Workspace == nil && (lock != nil || len(updated.Externals) > 0) {
+		if err := writeLockfile(updated, lock, lockPath); err != nil {
+			return nil, err
+		}
+	}
+	for _, module := range externals {
+		err = recAddModule(os.DirFS(module.Dir), ".", "ext/"+module.Name, module.Name, vendorIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to index external %v with error %v", module.Name, err)
+		}
+	}
+	for _, module := range vendorIndex {
+		module.RootType = VENDOR
 	}
 	// index the local directory (mainPath)
-	localIndex, err := indexModuleCollection(workspace, "loc")
+	localIndex, err := indexModuleCollection(roots.workspace, "loc")
 	if err != nil {
-		return nil, fmt.Errorf("failed to index local directory with error %v", err)
+		return nil, fmt.Errorf("failed to index local directory %v with error %v", job.LocalWorkspaceRoot, err)
 	}
 	// index the standard directory
-	standardIndex, err := indexModuleCollection(STDPATH, "std")
+	standardIndex, err := indexModuleCollection(roots.standard, "std")
 	if err != nil {
-		return nil, fmt.Errorf("failed to index standard directory with error %v", err)
+		return nil, fmt.Errorf("failed to index standard directory %v with error %v", standardRoot, err)
 	}
 	fmt.Printf("[GSC][discoverSources] finished indexing. local=%v vendor=%v standard=%v\n", len(localIndex), len(vendorIndex), len(standardIndex))
 	// now ensure all packages that the application uses actually exist locally
@@ -97,13 +159,14 @@ func discoverSources(mainPath string, workspace string) (*ApplicationSource, err
 		}
 	}
 	// resolve our dependencies
-	flatDeps, err := resolveDeps(mainPath, vendorIndex, localIndex, standardIndex)
+	flatDeps, graph, err := resolveDeps(roots.main, roots.mainPath, vendorIndex, localIndex, standardIndex)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve dependencies with error %v", err)
+		return nil, fmt.Errorf("failed to resolve dependencies with error %w", err)
 	}
+	graph.Application = job.MainFilePath
 
 	// grab the main file
-	mainContent, err := os.ReadFile(mainPath)
+	mainContent, err := fs.ReadFile(roots.main, roots.mainPath)
 	if err != nil {
 		return nil, fmt.Errorf("could not read main application file with error %v", err)
 	}
@@ -112,11 +175,13 @@ func discoverSources(mainPath string, workspace string) (*ApplicationSource, err
 	// declare app source
 	src := &ApplicationSource{
 		ApplicationFile: SourceFile{
-			Path:    mainPath,
+			Path:    job.MainFilePath,
 			Content: string(mainContent),
 			Imports: make(map[string]*ImportDirective),
 		},
-		Modules: flatDeps,
+		Modules:   flatDeps,
+		Graph:     graph,
+		Externals: externals,
 	}
 	// add import information to the main file
 	mainImports, err := getImportsFromSourceText(string(mainContent))
@@ -132,6 +197,47 @@ func discoverSources(mainPath string, workspace string) (*ApplicationSource, err
 	return src, nil
 }
 
+// sourceRoots are the file systems from which the sources of a compile job are read, roots that are nil contain no
+// modules
+type sourceRoots struct {
+	main      fs.FS  // contains the main application file
+	mainPath  string // path of the main application file in main
+	workspace fs.FS
+	vendor    fs.FS
+	standard  fs.FS
+}
+
+// rootsOf returns the file systems of the job, the directories of the job are opened for the roots it does not provide
+func rootsOf(job CompileJob) *sourceRoots {
+	roots := &sourceRoots{
+		main:      job.Workspace,
+		mainPath:  job.MainFilePath,
+		workspace: openRoot(job.Workspace, job.LocalWorkspaceRoot),
+		vendor:    openRoot(job.Vendor, job.VendorPath),
+		standard:  openRoot(job.Standard, job.StandardLibPath),
+	}
+	// the standard library is always available
+	if roots.standard == nil {
+		roots.standard = gs_standard.FS
+	}
+	// the main file on disk does not have to be inside of the workspace directory
+	if job.Workspace == nil {
+		roots.main = os.DirFS(filepath.Dir(job.MainFilePath))
+		roots.mainPath = filepath.Base(job.MainFilePath)
+	}
+	return roots
+}
+
+func openRoot(fsys fs.FS, dir string) fs.FS {
+	if fsys != nil {
+		return fsys
+	}
+	if dir == "" {
+		return nil
+	}
+	return os.DirFS(dir)
+}
+
 var IMPORT_REGEX = regexp.MustCompile(`import "(.*)"\s*\n`)
 var IMPORT_ALIAS_REGEX = regexp.MustCompile(`import "(.*)" as (.*)`)
 
@@ -160,42 +266,82 @@ func findModuleInIndices(imp *ImportDirective, vendorIndex map[string]*ModuleSou
 	}
 }
 
-// indexModuleCollection will create an index of a module collection directory such as $VENDORPATH or the local path
-func indexModuleCollection(path string, relativeRoot string) (map[string]*ModuleSource, error) {
+// indexModuleCollection will create an index of a module collection such as the vendor directory or the local
+// workspace. A collection without a file system is empty, a collection whose directory does not exist is an error.
+func indexModuleCollection(fsys fs.FS, relativeRoot string) (map[string]*ModuleSource, error) {
+	rootType, err := parseRootType(relativeRoot)
+	if err != nil {
+		return nil, err
+	}
+	// create a collection for the modules
+	modules := make(map[string]*ModuleSource)
+	if fsys == nil {
+		return modules, nil
+	}
 	// index the directory
-	entries, err := os.ReadDir(path)
+	entries, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return nil, fmt.Errorf("cannot read directory with error %v", err)
 	}
-	// create a collection for vendor modules
-	modules := make(map[string]*ModuleSource)
 	// filter the entries
 	for _, entry := range entries {
 		// skip everything that isnt a directory
 		if !entry.IsDir() {
 			continue
 		}
-		// add this module and all its submodules to the vendor module collection
-		err = recAddModule(filepath.Join(path, entry.Name()), relativeRoot+"/"+entry.Name(), entry.Name(), modules)
+		// add this module and all its submodules to the module collection
+		err = recAddModule(fsys, entry.Name(), relativeRoot+"/"+entry.Name(), entry.Name(), modules)
 		if err != nil {
 			return nil, fmt.Errorf("failed to index directory %v", err)
 		}
 	}
+	for _, module := range modules {
+		module.RootType = rootType
+	}
 	return modules, nil
 }
 
-// recAddModule will add the module at the specified path and all its submodules to the out map
-func recAddModule(path string, importPath string, name string, out map[string]*ModuleSource) error {
+// ModuleInfo describes a module of a module collection
+type ModuleInfo struct {
+	ImportPath string
+	Files      []string // names of the source files of the module
+	Functions  []string // names of the functions declared by the module
+}
+
+// ListModules returns the modules of the module collection, which is imported with the prefix ext, std or loc, ordered
+// by their import path
+func ListModules(fsys fs.FS, relativeRoot string) ([]ModuleInfo, error) {
+	index, err := indexModuleCollection(fsys, relativeRoot)
+	if err != nil {
+		return nil, err
+	}
+	modules := make([]ModuleInfo, 0, len(index))
+	for _, module := range index {
+		info := ModuleInfo{ImportPath: module.ImportPath, Files: []string{}, Functions: []string{}}
+		for _, file := range module.Files {
+			info.Files = append(info.Files, path.Base(file.Path))
+			for _, match := range FUNC_NAME_REGEX.FindAllStringSubmatch(file.Content, -1) {
+				info.Functions = append(info.Functions, match[1])
+			}
+		}
+		modules = append(modules, info)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].ImportPath < modules[j].ImportPath })
+	return modules, nil
+}
+
+// recAddModule will add the module at the specified path of the file system and all its submodules to the out map
+func recAddModule(fsys fs.FS, dir string, importPath string, name string, out map[string]*ModuleSource) error {
 	this := ModuleSource{
 		Name:       name,
-		Path:       path,
+		Path:       dir,
 		ImportPath: importPath,
 		Imports:    make(map[string]*ImportDirective),
 	}
 	// list the specified directory
-	entries, err := os.ReadDir(path)
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
-		return fmt.Errorf("cannot read module directory %v with error %v", path, err)
+		return fmt.Errorf("cannot read module directory %v with error %v", dir, err)
 	}
 	// prepare the module hash
 	hash := fnv.New32a()
@@ -203,23 +349,28 @@ func recAddModule(path string, importPath string, name string, out map[string]*M
 	for _, entry := range entries {
 		// if we have found a submodule recursively add it too
 		if entry.IsDir() {
-			recAddModule(filepath.Join(path, entry.Name()), importPath+"/"+entry.Name(), entry.Name(), out)
+			recAddModule(fsys, path.Join(dir, entry.Name()), importPath+"/"+entry.Name(), entry.Name(), out)
+			continue
+		}
+		// only goscript files are sources, anything else like a readme or license is ignored
+		if path.Ext(entry.Name()) != ".gs" {
 			continue
 		}
 		// read its content
-		content, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
 		if err != nil {
-			return fmt.Errorf("cannot read source file %v with error %v", filepath.Join(path, entry.Name()), err)
+			return fmt.Errorf("cannot read source file %v with error %v", path.Join(dir, entry.Name()), err)
 		}
 		// stip comments
 		content = []byte(stripComments(trimWhitespace(string(content))))
 		// otherwise, we add this entry to the file list
 		this.Files = append(this.Files, SourceFile{
-			Path:    filepath.Join(path, entry.Name()),
+			Path:    importPath + "/" + entry.Name(),
 			Content: string(content),
 		})
-		// and write its name and path into the hash
-		hash.Write([]byte(filepath.Join(path, entry.Name())))
+		// and write its import path and name into the hash, which unlike the path on disk does not depend on
+		// where the compiler was invoked from
+		hash.Write([]byte(importPath + "/" + entry.Name()))
 	}
 	// set the module hash
 	this.Hash = hex.EncodeToString(hash.Sum(nil))
@@ -1,13 +1,89 @@
 package goscript
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
+
+	"github.com/Yoshi-Exeler/goscript/goscript/gs_standard"
 )
 
+// mapFS returns an in memory file system containing the files
+func mapFS(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
 func TestSourceWalk(t *testing.T) {
-	_, err := discoverSources(filepath.Join(TESTS, "imports.gs"), TESTS)
+	_, err := discoverSources(workspaceJob("imports.gs"))
 	if err != nil {
 		t.Fatalf("got error %v", err)
 	}
 }
+
+func TestIndexModuleCollection(t *testing.T) {
+	index, err := indexModuleCollection(mapFS(map[string]string{
+		"math/math.gs":       "func add(a: u64, b: u64) => u64 {\n    return a + b\n}",
+		"math/bits/bits.gs":  "func shift(a: u64) => u64 {\n    return a * 2\n}",
+		"math/bits/extra.gs": "func unshift(a: u64) => u64 {\n    return a / 2\n}",
+		"notes.txt":          "files outside of modules are ignored",
+	}), "std")
+	if err != nil {
+		t.Fatalf("indexing failed with error %v", err)
+	}
+	expectValue(len(index), 2)
+	bits := index["std/math/bits"]
+	expectValue(bits.Name, "bits")
+	expectValue(bits.Path, "math/bits")
+	expectValue(bits.RootType, STANDARD)
+	expectLength(bits.Files, 2, "files")
+	expectValue(bits.Files[0].Path, "std/math/bits/bits.gs")
+	// the hash only depends on the import paths, so the in memory module matches the one on disk
+	disk, err := indexModuleCollection(os.DirFS(TEST_STANDARD), "std")
+	if err != nil {
+		t.Fatalf("indexing failed with error %v", err)
+	}
+	expectValue(index["std/math"].Hash, disk["std/math"].Hash)
+	// a collection without a file system has no modules
+	index, err = indexModuleCollection(nil, "ext")
+	if err != nil {
+		t.Fatalf("indexing failed with error %v", err)
+	}
+	expectValue(len(index), 0)
+	// a collection whose directory does not exist is most likely a typo in its path
+	if _, err := indexModuleCollection(os.DirFS(filepath.Join(t.TempDir(), "missing")), "ext"); err == nil {
+		t.Fatalf("indexing a directory that does not exist should fail")
+	}
+	if _, err := indexModuleCollection(mapFS(nil), "usr"); err == nil {
+		t.Fatalf("indexing an unknown root should fail")
+	}
+}
+
+func TestEmbeddedStandard(t *testing.T) {
+	modules, err := ListModules(gs_standard.FS, "std")
+	if err != nil {
+		t.Fatalf("listing failed with error %v", err)
+	}
+	fmt.Println(modules)
+	expectValue(len(modules) > 0, true)
+	expectValue(fmt.Sprint(modules[0]), "{std/math [math.gs] [add sub mult div]}")
+	// compiling without a standard library root uses the embedded one, which is the same as the one on disk
+	hashes := []string{}
+	for _, standard := range []string{"", TEST_STANDARD} {
+		prog, err := NewCompiler().Compile(CompileJob{
+			MainFilePath:       filepath.Join(TEST_WORKSPACE, "inline.gs"),
+			LocalWorkspaceRoot: TEST_WORKSPACE,
+			StandardLibPath:    standard,
+		})
+		if err != nil {
+			t.Fatalf("compilation failed with error %v", err)
+		}
+		hashes = append(hashes, string(mustHash(t, prog)))
+	}
+	expectValue(hashes[0], hashes[1])
+}
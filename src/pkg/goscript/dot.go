@@ -0,0 +1,185 @@
+package goscript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*
+	Compiled programs can be exported as Graphviz DOT graphs, which can be rendered using `dot -Tsvg graph.dot`.
+
+	The control flow graph of a function is rebuilt from its bytecode. A block begins at the base of the function, at
+	every jump target and after every jump or return, edges are labeled with the outcome of the condition that takes
+	them. Operations are rendered in the assembly syntax (see assembly.go).
+
+	The call graph has a node for every function and an edge from every caller to every function it calls, labeled
+	with the number of call sites. Functions are the ones returned by FunctionSizes.
+*/
+
+// ControlFlowDOT returns the control flow graph of the function as a DOT digraph
+func (p *Program) ControlFlowDOT(function FunctionSize) string {
+	d := &disassembler{program: p, labels: map[int]string{}}
+	d.collectLabels()
+	end := function.Base + function.Size
+	leaders := map[int]bool{function.Base: true}
+	for pc := function.Base; pc < end; pc++ {
+		if target, ok := jumpTarget(&p.Operations[pc]); ok && target >= function.Base && target < end {
+			leaders[target] = true
+		}
+		switch p.Operations[pc].Type {
+		case JUMP, JUMP_IF, JUMP_IF_NOT, RETURN:
+			leaders[pc+1] = true
+		}
+	}
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "digraph %v {\n", dotQuote(function.Name))
+	fmt.Fprintf(out, "\tnode [shape=box fontname=monospace]\n")
+	for pc := function.Base; pc < end; {
+		block := pc
+		label := &strings.Builder{}
+		if name, ok := d.labels[block]; ok {
+			fmt.Fprintf(label, "%v:\\l", dotEscape(name))
+		}
+		for {
+			line, err := d.operation(pc)
+			if err != nil {
+				line = p.Operations[pc].String()
+			}
+			fmt.Fprintf(label, "[%v] %v\\l", pc, dotEscape(line))
+			pc++
+			if pc >= end || leaders[pc] {
+				break
+			}
+		}
+		fmt.Fprintf(out, "\tB%v [label=\"%v\"]\n", block, label)
+		// the edges leaving the block depend on its last operation
+		last := &p.Operations[pc-1]
+		target, _ := jumpTarget(last)
+		switch last.Type {
+		case JUMP:
+			fmt.Fprintf(out, "\tB%v -> B%v\n", block, target)
+		case JUMP_IF:
+			fmt.Fprintf(out, "\tB%v -> B%v [label=\"true\"]\n", block, target)
+			dotFallthrough(out, block, pc, end, "false")
+		case JUMP_IF_NOT:
+			fmt.Fprintf(out, "\tB%v -> B%v [label=\"false\"]\n", block, target)
+			dotFallthrough(out, block, pc, end, "true")
+		case RETURN:
+		default:
+			dotFallthrough(out, block, pc, end, "")
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// jumpTarget returns the address of the operation executed after the jump is taken
+func jumpTarget(op *BinaryOperation) (int, bool) {
+	index := -1
+	switch op.Type {
+	case JUMP:
+		index = 0
+	case JUMP_IF, JUMP_IF_NOT:
+		index = 1
+	}
+	if index < 0 || index >= len(op.Args) {
+		return 0, false
+	}
+	target, ok := op.Args[index].(int)
+	return target + 1, ok
+}
+
+// dotFallthrough adds the edge to the next block, unless execution runs off the end of the function
+func dotFallthrough(out *strings.Builder, block int, next int, end int, label string) {
+	if next >= end {
+		return
+	}
+	if label == "" {
+		fmt.Fprintf(out, "\tB%v -> B%v\n", block, next)
+		return
+	}
+	fmt.Fprintf(out, "\tB%v -> B%v [label=\"%v\"]\n", block, next, label)
+}
+
+// CallGraphDOT returns the call graph of the program as a DOT digraph
+func (p *Program) CallGraphDOT() string {
+	functions := p.FunctionSizes()
+	// functionAt returns the index of the function the pc belongs to
+	functionAt := func(pc int) int {
+		return sort.Search(len(functions), func(i int) bool { return functions[i].Base+functions[i].Size > pc })
+	}
+	calls := map[[2]int]int{}
+	p.forEachExpression(func(pc int, expr *Expression) {
+		if expr.Operator == BO_FUNCTION_CALL {
+			calls[[2]int{functionAt(pc), functionAt(expr.Ref)}]++
+		}
+	})
+	edges := make([][2]int, 0, len(calls))
+	for edge := range calls {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	out := &strings.Builder{}
+	out.WriteString("digraph calls {\n")
+	out.WriteString("\tnode [shape=box]\n")
+	for i, function := range functions {
+		fmt.Fprintf(out, "\tF%v [label=\"%v\\n%v operations\"]\n", i, dotEscape(function.Name), function.Size)
+	}
+	for _, edge := range edges {
+		caller, callee := edge[0], edge[1]
+		if callee >= len(functions) {
+			continue
+		}
+		if count := calls[edge]; count > 1 {
+			fmt.Fprintf(out, "\tF%v -> F%v [label=\"%v\"]\n", caller, callee, count)
+		} else {
+			fmt.Fprintf(out, "\tF%v -> F%v\n", caller, callee)
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// WriteControlFlowDOT writes the control flow graph of every function into a file in the directory, named after the
+// function, and returns the paths of the files
+func (p *Program) WriteControlFlowDOT(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %v with error %v", dir, err)
+	}
+	paths := []string{}
+	for _, function := range p.FunctionSizes() {
+		path := filepath.Join(dir, dotFileName(function.Name)+".dot")
+		if err := os.WriteFile(path, []byte(p.ControlFlowDOT(function)), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write control flow graph with error %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// dotFileName replaces the characters of generated function names that are inconvenient in file names
+func dotFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.TrimLeft(name, "#"))
+}
+
+func dotQuote(text string) string {
+	return "\"" + dotEscape(text) + "\""
+}
+
+// dotEscape escapes the text for a quoted DOT string
+func dotEscape(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(text)
+}
@@ -0,0 +1,73 @@
+package goscript
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+const dotTestProgram = `
+	BIND $0, UINT64
+loop:
+	JUMP_IF_NOT ($0 < UINT64 3), @done
+	ASSIGN $0, call @inc($1 = $0):UINT64
+	JUMP @loop
+done:
+	RETURN call @inc($1 = call @inc($1 = $0):UINT64):UINT64
+inc:
+	RETURN ($1 + UINT64 1):UINT64
+`
+
+func TestControlFlowDOT(t *testing.T) {
+	prog := assemble(t, dotTestProgram)
+	functions := prog.FunctionSizes()
+	expectLength(functions, 2, "functions")
+	expectValue(prog.ControlFlowDOT(functions[0]), `digraph "L0" {
+	node [shape=box fontname=monospace]
+	B0 [label="L0:\l[0] BIND $0, UINT64\l"]
+	B0 -> B1
+	B1 [label="L1:\l[1] JUMP_IF_NOT ($0 < UINT64 3), @L4\l"]
+	B1 -> B4 [label="false"]
+	B1 -> B2 [label="true"]
+	B2 [label="[2] ASSIGN $0, call @L5($1 = $0):UINT64\l[3] JUMP @L1\l"]
+	B2 -> B1
+	B4 [label="L4:\l[4] RETURN call @L5($1 = call @L5($1 = $0):UINT64):UINT64\l"]
+}
+`)
+	expectValue(prog.CallGraphDOT(), `digraph calls {
+	node [shape=box]
+	F0 [label="L0\n5 operations"]
+	F1 [label="L5\n1 operations"]
+	F0 -> F1 [label="3"]
+}
+`)
+}
+
+func TestWorkspaceDOT(t *testing.T) {
+	for name, prog := range workspacePrograms(t) {
+		dir := t.TempDir()
+		paths, err := prog.WriteControlFlowDOT(dir)
+		if err != nil {
+			t.Fatalf("writing the graphs of %v failed with error %v", name, err)
+		}
+		expectLength(paths, len(prog.Debug.Functions), "graphs of "+name)
+		for i, path := range paths {
+			buff, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading %v failed with error %v", path, err)
+			}
+			// every operation of the function is part of exactly one block
+			function := prog.Debug.Functions[i]
+			for pc := function.Base; pc < function.End; pc++ {
+				expectValue(strings.Count(string(buff), fmt.Sprintf("[%v] ", pc)), 1)
+			}
+		}
+		calls := prog.CallGraphDOT()
+		expectValue(strings.Count(calls, "operations\"]"), len(prog.Debug.Functions))
+	}
+	calls := workspacePrograms(t)["mutual.gs"].CallGraphDOT()
+	fmt.Println(calls)
+	expectValue(strings.Contains(calls, "F1 -> F2"), true)
+	expectValue(strings.Contains(calls, "F2 -> F1"), true)
+}
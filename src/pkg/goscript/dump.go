@@ -0,0 +1,103 @@
+package goscript
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+/*
+	The statistics used by gsdump to inspect compiled programs. Constants are rendered in the assembly syntax (see
+	assembly.go), functions are named after the debug info if the program has any and L<address> otherwise, which
+	matches the labels of the disassembler.
+*/
+
+// ConstantUsage is the number of times a constant occurs in the expressions of a program
+type ConstantUsage struct {
+	Constant string // the constant in the assembly syntax, for example UINT64 1
+	Count    int
+}
+
+// ConstantUsage returns all constants of the program, the most frequently used first
+func (p *Program) ConstantUsage() []ConstantUsage {
+	d := &disassembler{program: p}
+	counts := map[string]int{}
+	p.forEachExpression(func(pc int, expr *Expression) {
+		if expr.Operator != BO_CONSTANT || expr.Value == nil {
+			return
+		}
+		constant, err := d.value(pc, expr.Value)
+		if err != nil {
+			constant = fmt.Sprintf("<invalid constant: %v>", err)
+		}
+		counts[constant]++
+	})
+	usage := make([]ConstantUsage, 0, len(counts))
+	for constant, n := range counts {
+		usage = append(usage, ConstantUsage{Constant: constant, Count: n})
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Count != usage[j].Count {
+			return usage[i].Count > usage[j].Count
+		}
+		return usage[i].Constant < usage[j].Constant
+	})
+	return usage
+}
+
+// FunctionSize is the number of operations a function was compiled to
+type FunctionSize struct {
+	Name string
+	Base int // the pc of the first operation of the function
+	Size int
+}
+
+// FunctionSizes returns the sizes of all functions ordered by their base address. Without debug info, functions
+// begin at pc 0 and at every call target and end where the next function begins.
+func (p *Program) FunctionSizes() []FunctionSize {
+	if p.Debug != nil && len(p.Debug.Functions) > 0 {
+		sizes := make([]FunctionSize, len(p.Debug.Functions))
+		for i, function := range p.Debug.Functions {
+			sizes[i] = FunctionSize{Name: function.Name, Base: function.Base, Size: function.End - function.Base}
+		}
+		return sizes
+	}
+	d := &disassembler{program: p, labels: map[int]string{}}
+	d.collectLabels()
+	bases := []int{0}
+	p.forEachExpression(func(pc int, expr *Expression) {
+		if expr.Operator == BO_FUNCTION_CALL && expr.Ref > 0 && expr.Ref < len(p.Operations) {
+			bases = append(bases, expr.Ref)
+		}
+	})
+	sort.Ints(bases)
+	sizes := []FunctionSize{}
+	for i, base := range bases {
+		if i > 0 && base == bases[i-1] {
+			continue
+		}
+		sizes = append(sizes, FunctionSize{Name: d.labels[base], Base: base})
+	}
+	for i := range sizes {
+		end := len(p.Operations)
+		if i+1 < len(sizes) {
+			end = sizes[i+1].Base
+		}
+		sizes[i].Size = end - sizes[i].Base
+	}
+	return sizes
+}
+
+// ProgramJSON returns the protobuf encoding of the program, including its hash and debug info, as indented JSON
+func ProgramJSON(program *Program) ([]byte, error) {
+	encProg, err := encodeFullProgram(program)
+	if err != nil {
+		return nil, err
+	}
+	buff, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(encProg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode program as json with error %v", err)
+	}
+	return buff, nil
+}
@@ -0,0 +1,61 @@
+package goscript
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestConstantUsage(t *testing.T) {
+	usage := workspacePrograms(t)["fib.gs"].ConstantUsage()
+	expectLength(usage, 4, "constants")
+	expectValue(usage[0], ConstantUsage{Constant: "UINT64 1", Count: 2})
+	expectValue(usage[1], ConstantUsage{Constant: "UINT64 0", Count: 1})
+	prog := assemble(t, `
+		ASSIGN $0, $1[INT64 0]
+		RETURN println(STRING "a\n")
+	`)
+	expectValue(prog.ConstantUsage()[0], ConstantUsage{Constant: `INT64 0`, Count: 1})
+	expectValue(prog.ConstantUsage()[1], ConstantUsage{Constant: `STRING "a\n"`, Count: 1})
+}
+
+func TestFunctionSizes(t *testing.T) {
+	for name, prog := range workspacePrograms(t) {
+		sizes := prog.FunctionSizes()
+		expectLength(sizes, len(prog.Debug.Functions), "functions of "+name)
+		// without debug info the functions are derived from the calls, which finds the same ones in compiled programs
+		debug := prog.Debug
+		prog.StripDebugInfo()
+		derived := prog.FunctionSizes()
+		expectLength(derived, len(sizes), "derived functions of "+name)
+		total := 0
+		for i, function := range derived {
+			expectValue(function.Base, sizes[i].Base)
+			expectValue(function.Size, sizes[i].Size)
+			expectValue(function.Name, fmt.Sprintf("L%v", debug.Functions[i].Base))
+			total += function.Size
+		}
+		expectValue(total, len(prog.Operations))
+	}
+}
+
+func TestProgramJSON(t *testing.T) {
+	prog := workspacePrograms(t)["fib.gs"]
+	buff, err := ProgramJSON(prog)
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	decoded := struct {
+		SymbolTableSize string
+		Operations      []any
+		Hash            string
+		Debug           struct{ Functions []any }
+	}{}
+	if err := json.Unmarshal(buff, &decoded); err != nil {
+		t.Fatalf("the dump is not valid json, %v", err)
+	}
+	expectValue(decoded.SymbolTableSize, "2")
+	expectLength(decoded.Operations, len(prog.Operations), "operations")
+	expectValue(decoded.Hash != "", true)
+	expectLength(decoded.Debug.Functions, 2, "functions")
+}
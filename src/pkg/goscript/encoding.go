@@ -1,137 +1,287 @@
 package goscript
 
 import (
+	"crypto/sha256"
 	"fmt"
 
 	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/types/known/anypb"
 )
 
+/*
+	Every value is encoded into the variant of the BinaryTypedValue oneof that matches its type:
+	- INT8, INT16, INT32, INT64 => Signed
+	- UINT8, UINT16, UINT32, UINT64, BYTE => Unsigned
+	- FLOAT32, FLOAT64 => Float
+	- BOOLEAN => Bool
+	- CHAR => Char
+	- STRING => String
+	- LIST => List
+	- EXPRESSION => Expression
+	- untyped integers, like the value of NewVSymbolExpression => Signed
+	A value without a variant is missing, which is different from a zero value.
+	The decoder converts each variant back to the go type of the BinaryType, so encoding is lossless.
+*/
+
+// EncodeProgram encodes the program into the protobuf format and embeds the content hash of the program.
+// Identical programs always yield identical bytes.
+// The debug info is encoded if present but is not part of the hash, so stripping it does not change the identity of the program.
 func EncodeProgram(program *Program) ([]byte, error) {
+	encProg, err := encodeFullProgram(program)
+	if err != nil {
+		return nil, err
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(encProg)
+}
+
+// encodeFullProgram encodes the program together with its hash and debug info
+func encodeFullProgram(program *Program) (*encoding.Program, error) {
+	encProg, err := encodeProgram(program)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hashEncodedProgram(encProg)
+	if err != nil {
+		return nil, err
+	}
+	encProg.Hash = hash
+	encProg.Debug = encodeDebugInfo(program.Debug)
+	return encProg, nil
+}
+
+// HashProgram returns the sha256 hash over the deterministic encoding of the program
+func HashProgram(program *Program) ([]byte, error) {
+	encProg, err := encodeProgram(program)
+	if err != nil {
+		return nil, err
+	}
+	return hashEncodedProgram(encProg)
+}
+
+func hashEncodedProgram(encProg *encoding.Program) ([]byte, error) {
+	buff, err := proto.MarshalOptions{Deterministic: true}.Marshal(encProg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode program for hashing with error %v", err)
+	}
+	sum := sha256.Sum256(buff)
+	return sum[:], nil
+}
+
+func encodeProgram(program *Program) (*encoding.Program, error) {
 	encProg := encoding.Program{
 		SymbolTableSize: uint64(program.SymbolTableSize),
 	}
-	for _, op := range program.Operations {
-		encProg.Operations = append(encProg.Operations, encodeOp(op))
+	for pc, op := range program.Operations {
+		encOp, err := encodeOp(op)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode operation %v with error %v", pc, err)
+		}
+		encProg.Operations = append(encProg.Operations, encOp)
 	}
-	return proto.Marshal(&encProg)
+	return &encProg, nil
 }
 
-func encodeOp(op BinaryOperation) *encoding.BinaryOperation {
+func encodeOp(op BinaryOperation) (*encoding.BinaryOperation, error) {
 	encOp := encoding.BinaryOperation{
 		Type: uint32(op.Type),
 	}
 	for _, arg := range op.Args {
-		encOp.Args = append(encOp.Args, encodeAny(arg))
+		encArg, err := encodeArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		encOp.Args = append(encOp.Args, encArg)
 	}
-	return &encOp
+	return &encOp, nil
 }
 
-func encodeAny(arg any) *anypb.Any {
+func encodeArg(arg any) (*encoding.Argument, error) {
 	switch val := arg.(type) {
+	case int:
+		return &encoding.Argument{Value: &encoding.Argument_Int{Int: int64(val)}}, nil
+	case BinaryType:
+		return &encoding.Argument{Value: &encoding.Argument_Type{Type: uint32(val)}}, nil
 	case *Expression:
-		buff, err := proto.Marshal(encodeExpr(val))
+		expr, err := encodeExpr(val)
 		if err != nil {
-			panic("failed to encode expressiont to proto buffer expression")
+			return nil, err
 		}
-		return &anypb.Any{
-			Value: buff,
-		}
-	case BinaryType:
-		return encodeU64Container(uint64(val))
-	case *uint8:
-		return encodeU64Container(uint64(*val))
-	case *uint16:
-		return encodeU64Container(uint64(*val))
-	case *uint32:
-		return encodeU64Container(uint64(*val))
-	case *uint64:
-		return encodeU64Container(*val)
-	case *int8:
-		return encodeU64Container(uint64(*val))
-	case *int16:
-		return encodeU64Container(uint64(*val))
-	case *int32:
-		return encodeU64Container(uint64(*val))
-	case *int64:
-		return encodeU64Container(uint64(*val))
-	case *[]*BinaryTypedValue:
-		arr := &encoding.ArrayContainer{}
-		for _, elem := range *val {
-			arr.Values = append(arr.Values, &encoding.BinaryTypedValue{
-				Type:  uint32(elem.Type),
-				Value: encodeAny(elem.Value),
-			})
+		return &encoding.Argument{Value: &encoding.Argument_Expression{Expression: expr}}, nil
+	default:
+		return nil, fmt.Errorf("cannot encode argument of type %T", arg)
+	}
+}
+
+func encodeExpr(expr *Expression) (*encoding.Expression, error) {
+	if expr == nil {
+		return nil, fmt.Errorf("cannot encode a missing expression")
+	}
+	encExpr := encoding.Expression{
+		Ref:      uint64(expr.Ref),
+		Operator: uint32(expr.Operator),
+	}
+	var err error
+	if expr.Value != nil {
+		if encExpr.Value, err = encodeTypedValue(expr.Value); err != nil {
+			return nil, err
 		}
-		buff, err := proto.Marshal(arr)
+	}
+	for _, arg := range expr.Args {
+		argExpr, err := encodeExpr(arg.Expression)
 		if err != nil {
-			panic("failed to encode array to proto buffer array")
-		}
-		return &anypb.Any{
-			Value: buff,
+			return nil, err
 		}
-	case *FunctionArgument:
-		buff, err := proto.Marshal(&encoding.FunctionArgument{
-			Expression: encodeExpr(val.Expression),
-			SymbolRef:  uint64(val.SymbolRef),
+		encExpr.Args = append(encExpr.Args, &encoding.FunctionArgument{
+			Expression: argExpr,
+			SymbolRef:  uint64(arg.SymbolRef),
 		})
-		if err != nil {
-			panic("failed to encode function arg to proto buffer function arg")
+	}
+	if expr.LeftExpression != nil {
+		if encExpr.Left, err = encodeExpr(expr.LeftExpression); err != nil {
+			return nil, err
 		}
-		return &anypb.Any{
-			Value: buff,
+	}
+	if expr.RightExpression != nil {
+		if encExpr.Right, err = encodeExpr(expr.RightExpression); err != nil {
+			return nil, err
 		}
-	case nil:
-		return &anypb.Any{}
-	case int:
-		return encodeU64Container(uint64(val))
-	case *string:
-		buff, err := proto.Marshal(&encoding.StringContainer{
-			Value: *val,
-		})
-		if err != nil {
-			panic("failed to encode string to proto buffer string")
+	}
+	return &encExpr, nil
+}
+
+// encodeTypedValue encodes the value into the variant matching its type, the value must have the go type of its BinaryType
+func encodeTypedValue(value *BinaryTypedValue) (*encoding.BinaryTypedValue, error) {
+	encValue := &encoding.BinaryTypedValue{
+		Type: uint32(value.Type),
+	}
+	if value.Value == nil {
+		return encValue, nil
+	}
+	var err error
+	switch value.Type {
+	case BT_INT8:
+		var val int8
+		val, err = valueOf[int8](value)
+		encValue.Value = &encoding.BinaryTypedValue_Signed{Signed: int64(val)}
+	case BT_INT16:
+		var val int16
+		val, err = valueOf[int16](value)
+		encValue.Value = &encoding.BinaryTypedValue_Signed{Signed: int64(val)}
+	case BT_INT32:
+		var val int32
+		val, err = valueOf[int32](value)
+		encValue.Value = &encoding.BinaryTypedValue_Signed{Signed: int64(val)}
+	case BT_INT64:
+		var val int64
+		val, err = valueOf[int64](value)
+		encValue.Value = &encoding.BinaryTypedValue_Signed{Signed: val}
+	case BT_UINT8, BT_BYTE:
+		var val uint8
+		val, err = valueOf[uint8](value)
+		encValue.Value = &encoding.BinaryTypedValue_Unsigned{Unsigned: uint64(val)}
+	case BT_UINT16:
+		var val uint16
+		val, err = valueOf[uint16](value)
+		encValue.Value = &encoding.BinaryTypedValue_Unsigned{Unsigned: uint64(val)}
+	case BT_UINT32:
+		var val uint32
+		val, err = valueOf[uint32](value)
+		encValue.Value = &encoding.BinaryTypedValue_Unsigned{Unsigned: uint64(val)}
+	case BT_UINT64:
+		var val uint64
+		val, err = valueOf[uint64](value)
+		encValue.Value = &encoding.BinaryTypedValue_Unsigned{Unsigned: val}
+	case BT_FLOAT32:
+		var val float32
+		val, err = valueOf[float32](value)
+		encValue.Value = &encoding.BinaryTypedValue_Float{Float: float64(val)}
+	case BT_FLOAT64:
+		var val float64
+		val, err = valueOf[float64](value)
+		encValue.Value = &encoding.BinaryTypedValue_Float{Float: val}
+	case BT_BOOLEAN:
+		var val bool
+		val, err = valueOf[bool](value)
+		encValue.Value = &encoding.BinaryTypedValue_Bool{Bool: val}
+	case BT_CHAR:
+		var val rune
+		val, err = valueOf[rune](value)
+		encValue.Value = &encoding.BinaryTypedValue_Char{Char: val}
+	case BT_STRING:
+		var val string
+		val, err = valueOf[string](value)
+		encValue.Value = &encoding.BinaryTypedValue_String_{String_: val}
+	case BT_LIST:
+		var val []*BinaryTypedValue
+		val, err = valueOf[[]*BinaryTypedValue](value)
+		list := &encoding.ListContainer{}
+		for _, elem := range val {
+			encElem, elemErr := encodeTypedValue(elem)
+			if elemErr != nil {
+				return nil, elemErr
+			}
+			list.Values = append(list.Values, encElem)
 		}
-		return &anypb.Any{
-			Value: buff,
+		encValue.Value = &encoding.BinaryTypedValue_List{List: list}
+	case BT_EXPRESSION:
+		expr, ok := value.Value.(*Expression)
+		if !ok {
+			return nil, fmt.Errorf("cannot encode %T as a value of type %v", value.Value, value.Type)
 		}
+		var encExpr *encoding.Expression
+		encExpr, err = encodeExpr(expr)
+		encValue.Value = &encoding.BinaryTypedValue_Expression{Expression: encExpr}
+	case BT_UNTYPED:
+		val, ok := value.Value.(int)
+		if !ok {
+			return nil, fmt.Errorf("cannot encode untyped value %T", value.Value)
+		}
+		encValue.Value = &encoding.BinaryTypedValue_Signed{Signed: int64(val)}
 	default:
-		panic(fmt.Sprintf("unknown type %#v in encode arg", arg))
+		return nil, fmt.Errorf("cannot encode values of type %v", value.Type)
 	}
-}
-
-func encodeU64Container(u uint64) *anypb.Any {
-	buff, err := proto.Marshal(&encoding.U64Container{
-		Value: u,
-	})
 	if err != nil {
-		panic("failed to encode int to proto buffer uint32")
-	}
-	return &anypb.Any{
-		Value: buff,
+		return nil, err
 	}
+	return encValue, nil
 }
 
-func encodeExpr(expr *Expression) *encoding.Expression {
-	encExpr := encoding.Expression{
-		Ref:      uint64(expr.Ref),
-		Operator: uint32(expr.Operator),
+// valueOf dereferences the value, which must be a pointer to T
+func valueOf[T any](value *BinaryTypedValue) (T, error) {
+	ptr, ok := value.Value.(*T)
+	if !ok || ptr == nil {
+		var zero T
+		return zero, fmt.Errorf("cannot encode %T as a value of type %v", value.Value, value.Type)
 	}
-	if expr.Value != nil {
-		encExpr.Value = &encoding.BinaryTypedValue{
-			Type:  uint32(expr.Value.Type),
-			Value: encodeAny(expr.Value.Value),
-		}
+	return *ptr, nil
+}
+
+func encodeDebugInfo(debug *DebugInfo) *encoding.DebugInfo {
+	if debug == nil {
+		return nil
 	}
-	for _, arg := range expr.Args {
-		encExpr.Args = append(encExpr.Args, encodeAny(arg))
+	encDebug := encoding.DebugInfo{}
+	for _, function := range debug.Functions {
+		encDebug.Functions = append(encDebug.Functions, &encoding.FunctionInfo{
+			Name: function.Name,
+			Base: uint64(function.Base),
+			End:  uint64(function.End),
+			File: function.File,
+			Line: uint64(function.Line),
+		})
 	}
-	if expr.LeftExpression != nil {
-		encExpr.Left = encodeExpr(expr.LeftExpression)
+	for _, line := range debug.Lines {
+		encDebug.Lines = append(encDebug.Lines, &encoding.LineInfo{
+			PC:   uint64(line.PC),
+			Line: uint64(line.Line),
+		})
 	}
-	if expr.RightExpression != nil {
-		encExpr.Right = encodeExpr(expr.RightExpression)
+	for _, symbol := range debug.Symbols {
+		encDebug.Symbols = append(encDebug.Symbols, &encoding.SymbolInfo{
+			Function: symbol.Function,
+			Index:    uint64(symbol.Index),
+			Name:     symbol.Name,
+		})
 	}
-	return &encExpr
+	return &encDebug
 }
@@ -0,0 +1,244 @@
+package goscript
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
+)
+
+// workspacePrograms compiles every program in the test workspace
+func workspacePrograms(t testing.TB) map[string]*Program {
+	entries, err := os.ReadDir(TEST_WORKSPACE)
+	if err != nil {
+		t.Fatalf("failed to read the workspace with error %v", err)
+	}
+	programs := make(map[string]*Program)
+	for _, entry := range entries {
+		// array.gs uses list literals in assignments, which the parser does not support yet
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".gs") || entry.Name() == "array.gs" {
+			continue
+		}
+		job := workspaceJob(entry.Name())
+		job.InlineThreshold = 8
+		prog, err := NewCompiler().Compile(job)
+		if err != nil {
+			t.Fatalf("compilation of %v failed with error %v", entry.Name(), err)
+		}
+		programs[entry.Name()] = prog
+	}
+	return programs
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	for name, prog := range workspacePrograms(t) {
+		encoded, err := EncodeProgram(prog)
+		if err != nil {
+			t.Fatalf("encoding %v failed with error %v", name, err)
+		}
+		decoded, err := DecodeProgram(encoded)
+		if err != nil {
+			t.Fatalf("decoding %v failed with error %v", name, err)
+		}
+		// the decoded program must encode to the exact same bytes
+		reencoded, err := EncodeProgram(decoded)
+		if err != nil {
+			t.Fatalf("encoding decoded %v failed with error %v", name, err)
+		}
+		if !bytes.Equal(encoded, reencoded) {
+			t.Fatalf("decoding %v is not lossless\n%v\n%v", name, prog, decoded)
+		}
+		// and it must behave like the compiled program
+		expected, expectedErr := NewRuntime().Exec(*prog)
+		actual, actualErr := NewRuntime().Exec(*decoded)
+		expectValue(fmt.Sprint(actualErr), fmt.Sprint(expectedErr))
+		if expected != nil && actual != nil {
+			expectValue(actual.String(), expected.String())
+		}
+		fmt.Printf("%v: %v bytes, returned %v\n", name, len(encoded), actual)
+	}
+}
+
+func TestDecodeInvalidProgram(t *testing.T) {
+	prog := workspacePrograms(t)["fib.gs"]
+	encoded, err := EncodeProgram(prog)
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	// truncated or corrupted input must never panic, a truncated program that ends between two operations
+	// and before its hash is still valid protobuf, so it may be decoded successfully
+	for i := 0; i < len(encoded); i++ {
+		DecodeProgram(encoded[:i])
+		corrupted := append([]byte{}, encoded...)
+		corrupted[i] ^= 0xff
+		DecodeProgram(corrupted)
+	}
+	// modifying the program must be detected by the hash
+	prog.Operations[0] = NewExitScopeOp()
+	tampered, err := EncodeProgram(prog)
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	original, err := DecodeProgram(encoded)
+	if err != nil {
+		t.Fatalf("decoding failed with error %v", err)
+	}
+	hash, err := HashProgram(original)
+	if err != nil {
+		t.Fatalf("hashing failed with error %v", err)
+	}
+	tampered = bytes.Replace(tampered, mustHash(t, prog), hash, 1)
+	if _, err := DecodeProgram(tampered); err == nil {
+		t.Fatalf("decoding a program that does not match its hash should fail")
+	}
+}
+
+func mustHash(t *testing.T, prog *Program) []byte {
+	hash, err := HashProgram(prog)
+	if err != nil {
+		t.Fatalf("hashing failed with error %v", err)
+	}
+	return hash
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// typedValues returns values of every type, including the extremes of every numeric type
+func typedValues() []*BinaryTypedValue {
+	return []*BinaryTypedValue{
+		{Type: BT_INT8, Value: ptr(int8(math.MinInt8))},
+		{Type: BT_INT8, Value: ptr(int8(math.MaxInt8))},
+		{Type: BT_INT16, Value: ptr(int16(math.MinInt16))},
+		{Type: BT_INT16, Value: ptr(int16(-1))},
+		{Type: BT_INT32, Value: ptr(int32(math.MinInt32))},
+		{Type: BT_INT32, Value: ptr(int32(math.MaxInt32))},
+		{Type: BT_INT64, Value: ptr(int64(math.MinInt64))},
+		{Type: BT_INT64, Value: ptr(int64(math.MaxInt64))},
+		{Type: BT_UINT8, Value: ptr(uint8(math.MaxUint8))},
+		{Type: BT_UINT16, Value: ptr(uint16(math.MaxUint16))},
+		{Type: BT_UINT32, Value: ptr(uint32(math.MaxUint32))},
+		{Type: BT_UINT64, Value: ptr(uint64(math.MaxUint64))},
+		{Type: BT_UINT64, Value: ptr(uint64(0))},
+		{Type: BT_BYTE, Value: ptr(byte(0x7f))},
+		{Type: BT_FLOAT32, Value: ptr(float32(math.MaxFloat32))},
+		{Type: BT_FLOAT32, Value: ptr(float32(-math.SmallestNonzeroFloat32))},
+		{Type: BT_FLOAT32, Value: ptr(float32(0.1))},
+		{Type: BT_FLOAT64, Value: ptr(-math.MaxFloat64)},
+		{Type: BT_FLOAT64, Value: ptr(math.SmallestNonzeroFloat64)},
+		{Type: BT_FLOAT64, Value: ptr(math.Inf(-1))},
+		{Type: BT_FLOAT64, Value: ptr(0.1)},
+		{Type: BT_BOOLEAN, Value: ptr(true)},
+		{Type: BT_BOOLEAN, Value: ptr(false)},
+		{Type: BT_CHAR, Value: ptr('a')},
+		{Type: BT_CHAR, Value: ptr('\U0001F600')},
+		{Type: BT_STRING, Value: ptr("")},
+		{Type: BT_STRING, Value: ptr("hello wörld\n")},
+		{Type: BT_LIST, Value: &[]*BinaryTypedValue{}},
+		{Type: BT_LIST, Value: &[]*BinaryTypedValue{
+			{Type: BT_INT64, Value: ptr(int64(-5))},
+			{Type: BT_STRING, Value: ptr("nested")},
+			{Type: BT_LIST, Value: &[]*BinaryTypedValue{{Type: BT_BOOLEAN, Value: ptr(true)}}},
+			{Type: BT_UINT64},
+		}},
+		{Type: BT_EXPRESSION, Value: NewVSymbolExpression(3)},
+		{Type: BT_UINT64},
+		{Type: BT_NOTYPE},
+	}
+}
+
+func TestEncodeTypedValues(t *testing.T) {
+	values := typedValues()
+	// every type that can be held by a value must be covered
+	covered := make(map[BinaryType]bool)
+	for _, value := range values {
+		covered[value.Type] = true
+	}
+	for _, valueType := range []BinaryType{BT_INT8, BT_INT16, BT_INT32, BT_INT64, BT_UINT8, BT_UINT16, BT_UINT32, BT_UINT64, BT_BYTE, BT_FLOAT32, BT_FLOAT64, BT_BOOLEAN, BT_CHAR, BT_STRING, BT_LIST, BT_EXPRESSION} {
+		if !covered[valueType] {
+			t.Fatalf("type %v is not covered", valueType)
+		}
+	}
+	for _, value := range values {
+		prog := &Program{Operations: []BinaryOperation{NewReturnValueOp(&Expression{Operator: BO_CONSTANT, Value: value})}}
+		encoded, err := EncodeProgram(prog)
+		if err != nil {
+			t.Fatalf("encoding %v failed with error %v", value.Type, err)
+		}
+		decoded, err := DecodeProgram(encoded)
+		if err != nil {
+			t.Fatalf("decoding %v failed with error %v", value.Type, err)
+		}
+		actual := decoded.Operations[0].Args[0].(*Expression).Value
+		if !reflect.DeepEqual(actual, value) {
+			t.Fatalf("value %#v of type %v was decoded as %#v", value.Value, value.Type, actual.Value)
+		}
+	}
+	// NaN is not equal to itself, so it cannot be compared
+	encoded, err := encodeTypedValue(&BinaryTypedValue{Type: BT_FLOAT32, Value: ptr(float32(math.NaN()))})
+	if err != nil {
+		t.Fatalf("encoding NaN failed with error %v", err)
+	}
+	decoded, err := decodeTypedValue(encoded)
+	if err != nil || !math.IsNaN(float64(*decoded.Value.(*float32))) {
+		t.Fatalf("NaN was decoded as %v with error %v", decoded, err)
+	}
+}
+
+func TestEncodeMismatchedValue(t *testing.T) {
+	prog := &Program{Operations: []BinaryOperation{NewReturnValueOp(NewConstantExpression(ptr(true), BT_UINT64))}}
+	if _, err := EncodeProgram(prog); err == nil {
+		t.Fatalf("encoding a boolean as UINT64 should fail")
+	}
+	prog = &Program{Operations: []BinaryOperation{{Type: JUMP, Args: []any{"5"}}}}
+	if _, err := EncodeProgram(prog); err == nil {
+		t.Fatalf("encoding a string jump target should fail")
+	}
+}
+
+func TestDecodeInvalidValues(t *testing.T) {
+	invalidValues := []*encoding.BinaryTypedValue{
+		{Type: uint32(BT_INT8), Value: &encoding.BinaryTypedValue_Signed{Signed: 128}},
+		{Type: uint32(BT_UINT32), Value: &encoding.BinaryTypedValue_Unsigned{Unsigned: math.MaxUint32 + 1}},
+		{Type: uint32(BT_FLOAT32), Value: &encoding.BinaryTypedValue_Float{Float: math.MaxFloat64}},
+		{Type: uint32(BT_UINT8), Value: &encoding.BinaryTypedValue_Signed{Signed: 1}},
+		{Type: uint32(BT_INT64), Value: &encoding.BinaryTypedValue_Unsigned{Unsigned: 1}},
+		{Type: uint32(BT_STRING), Value: &encoding.BinaryTypedValue_Bool{Bool: true}},
+		{Type: uint32(BT_BOOLEAN), Value: &encoding.BinaryTypedValue_Char{Char: 1}},
+		{Type: uint32(BT_CHAR), Value: &encoding.BinaryTypedValue_String_{String_: "a"}},
+		{Type: uint32(BT_LIST), Value: &encoding.BinaryTypedValue_Float{Float: 1}},
+		{Type: uint32(BT_INT8), Value: &encoding.BinaryTypedValue_List{List: &encoding.ListContainer{}}},
+		{Type: uint32(BT_STRING), Value: &encoding.BinaryTypedValue_Expression{Expression: &encoding.Expression{}}},
+		{Type: uint32(BT_EXPRESSION), Value: &encoding.BinaryTypedValue_Expression{}},
+		{Type: uint32(BT_LIST), Value: &encoding.BinaryTypedValue_List{List: &encoding.ListContainer{Values: []*encoding.BinaryTypedValue{
+			{Type: uint32(BT_UINT8), Value: &encoding.BinaryTypedValue_Unsigned{Unsigned: 256}},
+		}}}},
+	}
+	for _, encValue := range invalidValues {
+		if value, err := decodeTypedValue(encValue); err == nil {
+			t.Fatalf("decoding %v should fail but returned %v", encValue, value)
+		} else {
+			fmt.Println(err)
+		}
+	}
+	invalidOps := []*encoding.BinaryOperation{
+		{Type: 0},
+		{Type: uint32(JUMP)},
+		{Type: uint32(JUMP), Args: []*encoding.Argument{{Value: &encoding.Argument_Type{Type: 1}}}},
+		{Type: uint32(RETURN), Args: []*encoding.Argument{{}}},
+		{Type: uint32(BIND), Args: []*encoding.Argument{{Value: &encoding.Argument_Int{Int: 0}}, {Value: &encoding.Argument_Int{Int: 0}}}},
+	}
+	for _, encOp := range invalidOps {
+		if op, err := decodeOp(encOp); err == nil {
+			t.Fatalf("decoding %v should fail but returned %v", encOp, op)
+		} else {
+			fmt.Println(err)
+		}
+	}
+}
@@ -0,0 +1,479 @@
+package goscript
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	External modules are fetched with the git binary of the system into the module cache, which has two directories:
+		- repos/<key> holds a bare clone of every repository, which is fetched again whenever a version is selected
+		- modules/<key>@<commit> holds the files of the repository at a commit, which never change once extracted
+	where the key is derived from the url of the repository.
+
+	The version of an external is the newest tag on its branch (master if the directive has none) that matches its
+	version constraint. Tags are semantic versions with an optional v prefix, the constraint is either an exact version
+	like 1.2.3 or a caret range like ^1.2.3, which allows every newer version with the same major version (or the same
+	minor version for major version 0). Without a constraint the newest tag is used, or the head of the branch if the
+	branch has no tags.
+
+	The repository is the module, its externals are declared in the application file of the module, which is the
+	source file in the root of the repository that contains the application directive. They are resolved after the
+	externals of the application, so the application selects the version of a module that is required more than once
+	and every other module requiring it must accept that version. Modules found in the vendor directory are used as
+	they are, they are neither fetched nor are their externals resolved.
+
+	Repositories can only be fetched from https, ssh and file urls. Several compilations may share the module cache,
+	so fetching a repository is serialized by a lock file next to it, and a repository or module that was moved into
+	the cache by another compilation first is used instead of the own copy.
+*/
+
+// ExternalModule is an external module that was fetched into the module cache
+type ExternalModule struct {
+	Name   string // the name with which the module is imported
+	URL    string
+	Branch string
+	Tag    string // the selected version tag, empty if the branch has no tags
+	Commit string // the commit the module was extracted from
+	Dir    string // the directory of the module in the module cache
+	Hash   string // the hash of the content of the module, see hashModuleContent
+}
+
+// semanticVersion is a version tag of the form major.minor.patch
+type semanticVersion [3]int
+
+// parseSemanticVersion parses versions with an optional v prefix, missing minor and patch versions are 0
+func parseSemanticVersion(text string) (semanticVersion, bool) {
+	version := semanticVersion{}
+	parts := strings.Split(strings.TrimPrefix(text, "v"), ".")
+	if len(parts) > 3 {
+		return version, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+func (v semanticVersion) less(other semanticVersion) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] < other[i]
+		}
+	}
+	return false
+}
+
+// matchesConstraint returns wether the version satisfies an exact or caret constraint, see the top of this file
+func (v semanticVersion) matchesConstraint(constraint string) (bool, error) {
+	if constraint == "" {
+		return true, nil
+	}
+	caret := strings.HasPrefix(constraint, "^")
+	base, ok := parseSemanticVersion(strings.TrimPrefix(constraint, "^"))
+	if !ok {
+		return false, fmt.Errorf("invalid version constraint %v", constraint)
+	}
+	if !caret {
+		return v == base, nil
+	}
+	if v.less(base) {
+		return false, nil
+	}
+	if base[0] == 0 {
+		return v[0] == 0 && v[1] == base[1], nil
+	}
+	return v[0] == base[0], nil
+}
+
+// moduleCache fetches external modules into the module cache at its root
+type moduleCache struct {
+	root string
+}
+
+// fetchExternals fetches the externals and the externals they require transitively into the module cache. The
+// externals in skip are vendored and therefore not fetched, but returned as vendored requirements. The locked
+// externals are fetched in their locked version if it satisfies their requirement (see lockfile.go).
+func fetchExternals(cacheRoot string, externals []*ExternalModuleSource, skip map[string]bool, locked map[string]*LockedExternal) ([]*ExternalModule, []*ExternalModuleSource, error) {
+	start := time.Now()
+	fmt.Printf("[GSC][fetchExternals] begin fetching externals into %v\n", cacheRoot)
+	cache := &moduleCache{root: cacheRoot}
+	fetched := []*ExternalModule{}
+	vendored := []*ExternalModuleSource{}
+	byName := make(map[string]*ExternalModule)
+	queue := append([]*ExternalModuleSource{}, externals...)
+	for len(queue) > 0 {
+		required := queue[0]
+		queue = queue[1:]
+		if skip[required.Name] {
+			vendored = append(vendored, required)
+			continue
+		}
+		branch := required.Branch
+		if branch == "" {
+			branch = "master"
+		}
+		// a module that is required again must be the same module in a version that satisfies this requirement too
+		if module := byName[required.Name]; module != nil {
+			if module.URL != required.URL || module.Branch != branch {
+				return nil, nil, fmt.Errorf("external %v is required from both %v@%v and %v@%v", required.Name, module.URL, module.Branch, required.URL, branch)
+			}
+			version, _ := parseSemanticVersion(module.Tag)
+			matches, err := version.matchesConstraint(required.Version)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !matches || (module.Tag == "" && required.Version != "") {
+				return nil, nil, fmt.Errorf("external %v is required in version %v, but version %v was selected", required.Name, required.Version, module.Tag)
+			}
+			continue
+		}
+		lock := locked[required.Name]
+		if lock != nil && !lockSatisfies(lock, required.URL, branch, required.Version) {
+			fmt.Printf("[GSC][fetchExternals] the lock of %v does not satisfy %v@%v@%v, resolving it again\n", required.Name, required.URL, branch, required.Version)
+			lock = nil
+		}
+		module, err := cache.fetch(required.Name, required.URL, branch, required.Version, lock)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch external %v with error %v", required.Name, err)
+		}
+		if module.Hash, err = hashModuleContent(os.DirFS(module.Dir), "."); err != nil {
+			return nil, nil, err
+		}
+		fmt.Printf("[GSC][fetchExternals] %v resolved to %v %v\n", module.Name, module.Tag, module.Commit)
+		byName[module.Name] = module
+		fetched = append(fetched, module)
+		// queue the externals of the module
+		transitive, err := moduleExternals(module.Dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get the externals of external %v with error %v", module.Name, err)
+		}
+		queue = append(queue, transitive...)
+	}
+	fmt.Printf("[GSC][STAGE_COMPLETION] fetchExternals completed in %s\n", time.Since(start))
+	return fetched, vendored, nil
+}
+
+// moduleExternals returns the externals declared in the application file of the module in the directory
+func moduleExternals(dir string) ([]*ExternalModuleSource, error) {
+	fsys := os.DirFS(dir)
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read module directory with error %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("cannot read source file %v with error %v", entry.Name(), err)
+		}
+		if APPLICATION_REGEX.MatchString(stripComments(string(content))) {
+			return getRequiredExternals(fsys, entry.Name())
+		}
+	}
+	return nil, nil
+}
+
+// lockSatisfies returns wether the locked version of an external satisfies the requirement
+func lockSatisfies(lock *LockedExternal, url string, branch string, constraint string) bool {
+	if lock.URL != url || lock.Branch != branch || !isObjectID(lock.Commit) {
+		return false
+	}
+	if lock.Tag == "" {
+		return constraint == ""
+	}
+	version, ok := parseSemanticVersion(lock.Tag)
+	if !ok {
+		return false
+	}
+	matches, err := version.matchesConstraint(constraint)
+	return err == nil && matches
+}
+
+// fetch selects the version of the module and extracts it into the module cache, a locked module is extracted at its
+// locked commit and only fetched if that commit is not in the cache yet
+func (c *moduleCache) fetch(name string, url string, branch string, constraint string, lock *LockedExternal) (*ExternalModule, error) {
+	if err := checkExternalURL(url); err != nil {
+		return nil, err
+	}
+	key := cacheKey(url)
+	repo := filepath.Join(c.root, "repos", key)
+	module := &ExternalModule{Name: name, URL: url, Branch: branch}
+	if lock != nil {
+		module.Tag, module.Commit = lock.Tag, lock.Commit
+		dir, err := c.moduleDir(key, module.Commit)
+		if err != nil {
+			return nil, err
+		}
+		module.Dir = dir
+		if _, err := os.Stat(module.Dir); err == nil {
+			return module, nil
+		}
+		if err := c.update(url, repo); err != nil {
+			return nil, err
+		}
+		if err := c.extract(repo, module.Commit, module.Dir); err != nil {
+			return nil, err
+		}
+		return module, nil
+	}
+	if err := c.update(url, repo); err != nil {
+		return nil, err
+	}
+	head, err := runGit(repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("branch %v not found in %v", branch, url)
+	}
+	tags, err := runGit(repo, "tag", "--merged", "refs/heads/"+branch)
+	if err != nil {
+		return nil, err
+	}
+	// select the newest tag that matches the constraint
+	var newest semanticVersion
+	available := []string{}
+	for _, tag := range strings.Fields(string(tags)) {
+		version, ok := parseSemanticVersion(tag)
+		if !ok {
+			continue
+		}
+		available = append(available, tag)
+		matches, err := version.matchesConstraint(constraint)
+		if err != nil {
+			return nil, err
+		}
+		if matches && (module.Tag == "" || newest.less(version)) {
+			module.Tag, newest = tag, version
+		}
+	}
+	switch {
+	case module.Tag != "":
+		commit, err := runGit(repo, "rev-parse", "refs/tags/"+module.Tag+"^{commit}")
+		if err != nil {
+			return nil, err
+		}
+		module.Commit = strings.TrimSpace(string(commit))
+	case constraint == "" && len(available) == 0:
+		module.Commit = strings.TrimSpace(string(head))
+	default:
+		return nil, fmt.Errorf("no version on branch %v of %v matches %v, the available versions are %v", branch, url, constraint, available)
+	}
+	if module.Dir, err = c.moduleDir(key, module.Commit); err != nil {
+		return nil, err
+	}
+	if err := c.extract(repo, module.Commit, module.Dir); err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// moduleDir returns the directory the commit of the repository is extracted to, the commit must be an object id so
+// it can neither leave the module cache nor be mistaken for an option by git archive
+func (c *moduleCache) moduleDir(key string, commit string) (string, error) {
+	if !isObjectID(commit) {
+		return "", fmt.Errorf("invalid commit %v", commit)
+	}
+	modules := filepath.Join(c.root, "modules")
+	dir := filepath.Join(modules, key+"@"+commit)
+	if relative, err := filepath.Rel(modules, dir); err != nil || relative != filepath.Base(dir) {
+		return "", fmt.Errorf("module directory %v is outside of the module cache", dir)
+	}
+	return dir, nil
+}
+
+// update clones the repository into the cache or fetches it again if it was cloned before
+func (c *moduleCache) update(url string, repo string) error {
+	if err := os.MkdirAll(filepath.Dir(repo), 0755); err != nil {
+		return fmt.Errorf("failed to create module cache with error %v", err)
+	}
+	unlock, err := lockPath(repo)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := os.Stat(repo); err == nil {
+		_, err := runGit(repo, "fetch", "--quiet", "--prune", "--tags", "--", url, "+refs/heads/*:refs/heads/*")
+		return err
+	}
+	// clone into a temporary directory, so an interrupted clone is not mistaken for a repository
+	tmp, err := os.MkdirTemp(filepath.Dir(repo), ".clone-")
+	if err != nil {
+		return fmt.Errorf("failed to create module cache with error %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	if _, err := runGit("", "clone", "--quiet", "--bare", "--", url, tmp); err != nil {
+		return err
+	}
+	return moveIntoCache(tmp, repo)
+}
+
+// extract writes the files of the repository at the commit into the directory, unless it exists already
+func (c *moduleCache) extract(repo string, commit string, dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	archive, err := runGit(repo, "archive", "--format=zip", commit)
+	if err != nil {
+		return err
+	}
+	files, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return fmt.Errorf("failed to read archive of commit %v with error %v", commit, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create module cache with error %v", err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".extract-")
+	if err != nil {
+		return fmt.Errorf("failed to create module cache with error %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	for _, file := range files.File {
+		name := path.Clean(file.Name)
+		if strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return fmt.Errorf("archive of commit %v contains invalid path %v", commit, file.Name)
+		}
+		target := filepath.Join(tmp, filepath.FromSlash(name))
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to extract %v with error %v", name, err)
+			}
+			continue
+		}
+		if err := extractFile(file, target); err != nil {
+			return fmt.Errorf("failed to extract %v with error %v", name, err)
+		}
+	}
+	return moveIntoCache(tmp, dir)
+}
+
+// moveIntoCache moves the temporary directory to its path in the module cache, a directory that was moved there by
+// another compilation in the meantime is used instead
+func moveIntoCache(tmp string, dir string) error {
+	err := os.Rename(tmp, dir)
+	if err == nil {
+		return nil
+	}
+	if _, statErr := os.Stat(dir); statErr == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to move %v into the module cache with error %v", filepath.Base(dir), err)
+}
+
+// lockTimeout is the age after which a lock file is considered to be left behind by a compilation that was killed
+const lockTimeout = 10 * time.Minute
+
+// lockPath waits until no other compilation holds the lock of the path and returns the function that releases it
+func lockPath(target string) (func(), error) {
+	name := target + ".lock"
+	for {
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock %v with error %v", filepath.Base(target), err)
+		}
+		if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > lockTimeout {
+			fmt.Printf("[GSC][fetchExternals] removing stale lock %v\n", name)
+			os.Remove(name)
+			continue
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func extractFile(file *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(target, content, 0644)
+}
+
+// cacheKey returns the name of the repository in the module cache, the last element of the url followed by a hash of
+// the full url
+func cacheKey(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSuffix(path.Base(strings.TrimRight(url, "/")), ".git"))
+	return name + "-" + hex.EncodeToString(hash[:8])
+}
+
+// checkExternalURL rejects urls that git could mistake for an option or that use a transport other than https, ssh
+// and file, like the ext transport which runs arbitrary commands
+func checkExternalURL(rawURL string) error {
+	if strings.HasPrefix(rawURL, "-") {
+		return fmt.Errorf("invalid url %v", rawURL)
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %v with error %v", rawURL, err)
+	}
+	switch parsed.Scheme {
+	case "https", "ssh", "file":
+		return nil
+	default:
+		return fmt.Errorf("unsupported url %v, externals can only be fetched from https, ssh and file urls", rawURL)
+	}
+}
+
+// isObjectID returns wether the commit is a full sha1 or sha256 git object id in lowercase hex
+func isObjectID(commit string) bool {
+	if len(commit) != 40 && len(commit) != 64 {
+		return false
+	}
+	for _, r := range commit {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// runGit runs the git binary of the system in the directory and returns its output
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// never wait for credentials and never use other transports, even if a repository redirects to them
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL=https:ssh:file")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %v failed with error %v: %v", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
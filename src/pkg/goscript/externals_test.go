@@ -0,0 +1,229 @@
+package goscript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo creates a repository in the directory, commits and tags are created using git
+type gitRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newGitRepo(t *testing.T, dir string) *gitRepo {
+	t.Setenv("GIT_AUTHOR_NAME", "goscript")
+	t.Setenv("GIT_AUTHOR_EMAIL", "goscript@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "goscript")
+	t.Setenv("GIT_COMMITTER_EMAIL", "goscript@example.com")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create repository with error %v", err)
+	}
+	r := &gitRepo{t: t, dir: dir}
+	r.git("-c", "init.defaultBranch=master", "init", "--quiet")
+	return r
+}
+
+func (r *gitRepo) git(args ...string) string {
+	out, err := runGit(r.dir, args...)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit replaces the files of the repository and commits them, tagging the commit with the tags
+func (r *gitRepo) commit(files map[string]string, tags ...string) string {
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(r.dir, name), []byte(content), 0644); err != nil {
+			r.t.Fatalf("failed to write %v with error %v", name, err)
+		}
+	}
+	r.git("add", "-A")
+	r.git("commit", "--quiet", "--allow-empty", "-m", "update")
+	for _, tag := range tags {
+		r.git("tag", tag)
+	}
+	return r.git("rev-parse", "HEAD")
+}
+
+func (r *gitRepo) url() string {
+	return "file://" + filepath.ToSlash(r.dir)
+}
+
+func TestSemanticVersionConstraints(t *testing.T) {
+	cases := []struct {
+		version    string
+		constraint string
+		matches    bool
+	}{
+		{"1.2.3", "", true},
+		{"v1.2.3", "1.2.3", true},
+		{"1.2.4", "1.2.3", false},
+		{"1.2", "1.2.0", true},
+		{"1.2.3", "^1.2.3", true},
+		{"1.9.0", "^1.2.3", true},
+		{"1.2.2", "^1.2.3", false},
+		{"2.0.0", "^1.2.3", false},
+		{"0.2.5", "^0.2.1", true},
+		{"0.3.0", "^0.2.1", false},
+	}
+	for _, c := range cases {
+		version, ok := parseSemanticVersion(c.version)
+		if !ok {
+			t.Fatalf("version %v should be valid", c.version)
+		}
+		matches, err := version.matchesConstraint(c.constraint)
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		if matches != c.matches {
+			t.Fatalf("expected %v to match %v to be %v", c.version, c.constraint, c.matches)
+		}
+	}
+	for _, invalid := range []string{"", "1.2.3.4", "1.x", "v-1", "1.2.3-rc1"} {
+		if _, ok := parseSemanticVersion(invalid); ok {
+			t.Fatalf("version %v should be invalid", invalid)
+		}
+	}
+	if _, err := (semanticVersion{}).matchesConstraint("^x"); err == nil {
+		t.Fatalf("constraint ^x should be invalid")
+	}
+}
+
+func TestFetchExternals(t *testing.T) {
+	root := t.TempDir()
+	// offset is required by scale, which the application requires
+	offset := newGitRepo(t, filepath.Join(root, "offset"))
+	// files that are not goscript sources are not compiled
+	offset.commit(map[string]string{"README.md": "# offset\n\nreturns an offset", "LICENSE": "MIT License"})
+	offset.commit(map[string]string{"offset.gs": "func value() => u64 {\n    return 1\n}"}, "v0.1.0")
+	offsetCommit := offset.commit(map[string]string{"offset.gs": "func value() => u64 {\n    return 2\n}"}, "v0.1.1")
+	offset.commit(map[string]string{"offset.gs": "func value() => u64 {\n    return 3\n}"}, "v0.2.0")
+	scale := newGitRepo(t, filepath.Join(root, "scale"))
+	scaleFiles := func(factor int) map[string]string {
+		return map[string]string{
+			"README.md": "# scale\n\nscales a value",
+			"scale.gs":  fmt.Sprintf("application scale\nexternal offset from \"%v@version=^0.1.0\"\n\nimport \"ext/offset\"\n\nfunc apply(x: u64) => u64 {\n    return x * %v + offset.value()\n}", offset.url(), factor),
+		}
+	}
+	scale.commit(scaleFiles(10), "v1.0.0")
+	scaleCommit := scale.commit(scaleFiles(20), "v1.1.0", "not-a-version")
+	scale.commit(scaleFiles(30), "v2.0.0")
+	// a newer version that is not on the master branch
+	scale.git("checkout", "--quiet", "-b", "next", "v1.1.0")
+	nextCommit := scale.commit(scaleFiles(40), "v1.2.0")
+	scale.git("checkout", "--quiet", "master")
+	scale.commit(scaleFiles(50))
+
+	compile := func(directive string) (*Compiler, uint64, error) {
+		workspace := mapFS(map[string]string{
+			"main.gs": fmt.Sprintf("external scale from \"%v\"\n\nimport \"ext/scale\"\n\nfunc main() {\n    return scale.apply(2)\n}", directive),
+		})
+		compiler := NewCompiler()
+		prog, err := compiler.Compile(CompileJob{MainFilePath: "main.gs", Workspace: workspace, ModuleCachePath: filepath.Join(root, "cache")})
+		if err != nil {
+			return compiler, 0, err
+		}
+		ret, err := NewRuntime().Exec(*prog)
+		if err != nil {
+			t.Fatalf("execution failed with error %v", err)
+		}
+		return compiler, *ret.Value.(*uint64), nil
+	}
+	compiler, value, err := compile(scale.url() + "@version=^1.0.0")
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectValue(value, uint64(42))
+	externals := compiler.Externals()
+	expectLength(externals, 2, "externals")
+	expectValue(externals[0].Name, "scale")
+	expectValue(externals[0].URL, scale.url())
+	expectValue(externals[0].Branch, "master")
+	expectValue(externals[0].Tag, "v1.1.0")
+	expectValue(externals[0].Commit, scaleCommit)
+	expectValue(strings.HasSuffix(externals[0].Dir, "@"+scaleCommit), true)
+	expectValue(externals[1].Tag, "v0.1.1")
+	expectValue(externals[1].Commit, offsetCommit)
+	// the version is selected from the branch
+	compiler, value, err = compile(scale.url() + "@version=^1.0.0@branch=next")
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectValue(value, uint64(82))
+	expectValue(compiler.Externals()[0].Commit, nextCommit)
+	// new tags are fetched into the cached repository
+	scale.commit(scaleFiles(60), "v1.3.0")
+	compiler, value, err = compile(scale.url() + "@version=^1.0.0")
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectValue(value, uint64(122))
+	expectValue(compiler.Externals()[0].Tag, "v1.3.0")
+	// without a constraint the newest version is used, even though an older version was tagged later
+	_, value, err = compile(scale.url())
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectValue(value, uint64(62))
+	for _, invalid := range []string{
+		scale.url() + "@version=^3.0.0",
+		scale.url() + "@branch=missing",
+		"file://" + filepath.ToSlash(filepath.Join(root, "missing")),
+		"--upload-pack=touch " + filepath.Join(root, "pwned"),
+		"ext::sh -c touch% " + filepath.Join(root, "pwned"),
+		"http://example.com/scale",
+	} {
+		_, _, err = compile(invalid)
+		if err == nil {
+			t.Fatalf("compiling with external %v should fail", invalid)
+		}
+		fmt.Println(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pwned")); err == nil {
+		t.Fatalf("the url of an external must not be interpreted by git")
+	}
+	// vendored externals are not fetched
+	compiler = NewCompiler()
+	job := workspaceJob("imports.gs")
+	job.ModuleCachePath = filepath.Join(root, "cache")
+	_, err = compiler.Compile(job)
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectLength(compiler.Externals(), 0, "externals")
+}
+
+func TestFetchExternalsConcurrent(t *testing.T) {
+	root := t.TempDir()
+	scale := newGitRepo(t, filepath.Join(root, "scale"))
+	scale.commit(map[string]string{"scale.gs": "func apply(x: u64) => u64 {\n    return x * 10\n}"}, "v1.0.0")
+	workspace := mapFS(map[string]string{
+		"main.gs": fmt.Sprintf("external scale from \"%v@version=^1.0.0\"\n\nimport \"ext/scale\"\n\nfunc main() {\n    return scale.apply(2)\n}", scale.url()),
+	})
+	// compilations sharing a module cache fetch the same new external at the same time
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := NewCompiler().Compile(CompileJob{MainFilePath: "main.gs", Workspace: workspace, ModuleCachePath: filepath.Join(root, "cache")})
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("compilation failed with error %v", err)
+		}
+	}
+	// no temporary directories or locks are left behind
+	for _, dir := range []string{"repos", "modules"} {
+		entries, err := os.ReadDir(filepath.Join(root, "cache", dir))
+		if err != nil {
+			t.Fatalf("failed to read the module cache with error %v", err)
+		}
+		expectLength(entries, 1, dir)
+	}
+}
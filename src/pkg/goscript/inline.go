@@ -0,0 +1,239 @@
+package goscript
+
+import "fmt"
+
+/*
+	Inlining replaces calls to small leaf functions with the body of the function.
+	A function is inlined when it:
+	- does not call any other function, including builtins, which also makes it non recursive
+	- consists only of let statements and expressions followed by a single return with a value
+	- has at most CompileJob.InlineThreshold operations
+
+	Such a function has no side effects, so its body may be hoisted in front of the statement containing the call.
+	The call `let c: u64 = add(a, 1)` is rewritten to
+		let #inline_1_add_param_a: u64 = a
+		let #inline_1_add_param_b: u64 = 1
+		let #inline_1_add_result: u64 = #inline_1_add_param_a + #inline_1_add_param_b
+		let c: u64 = #inline_1_add_result
+	The locals of the inlined body are renamed by uniquifyVariables, which prevents them from shadowing the callers symbols.
+*/
+
+// inlineCalls inlines all eligible calls in the function and yields the number of inlined calls
+func (c *Compiler) inlineCalls(def *FunctionDefinition) int {
+	inlined := 0
+	operations := []*IntermediateOperation{}
+	for _, op := range def.Operations {
+		hoisted := []*IntermediateOperation{}
+		switch op.Type {
+		case IM_ASSIGN:
+			if len(op.Args) == 3 {
+				if expr, ok := op.Args[2].(*Expression); ok && expr != nil {
+					inlined += c.inlineExpression(def, expr, &hoisted)
+				}
+			}
+		case IM_FOR:
+			// only the initial value is inlined, the loop condition is evaluated on every iteration and can therefore not be hoisted
+			inlined += c.inlineExpression(def, op.Args[2].(*Expression), &hoisted)
+		case IM_RETURN, IM_EXPRESSION:
+			if expr, ok := op.Args[0].(*Expression); ok && expr != nil {
+				inlined += c.inlineExpression(def, expr, &hoisted)
+			}
+		}
+		// the hoisted operations are attributed to the statement that contained the call
+		for _, hoistedOp := range hoisted {
+			hoistedOp.Line = op.Line
+		}
+		operations = append(operations, hoisted...)
+		operations = append(operations, op)
+	}
+	def.Operations = operations
+	return inlined
+}
+
+// inlineExpression inlines all eligible calls in the expression, appending the inlined bodies to hoisted
+func (c *Compiler) inlineExpression(caller *FunctionDefinition, expr *Expression, hoisted *[]*IntermediateOperation) int {
+	inlined := 0
+	if expr.Operator == BO_FUNCTION_CALL_PLACEHOLDER {
+		placeholder := expr.Value.Value.(*FunctionCallPlaceholder)
+		// inline the arguments first, so nested calls are hoisted in evaluation order
+		for _, arg := range placeholder.Args {
+			inlined += c.inlineExpression(caller, arg, hoisted)
+		}
+		callee := c.funcsByName[placeholder.Name]
+		if callee == nil {
+			// builtins cannot be inlined
+			return inlined
+		}
+		if reason := c.inlineRejection(callee); reason != "" {
+			c.reportInline("cannot inline %v into %v: %v", callee.Name, caller.Name, reason)
+			return inlined
+		}
+		if len(placeholder.Args) != len(callee.Accepts) {
+			// the argument count is reported by checkCalls
+			return inlined
+		}
+		// hoisting the arguments must not reorder any side effects of the statement
+		for _, arg := range placeholder.Args {
+			if containsCall(arg) {
+				c.reportInline("cannot inline %v into %v: arguments contain calls that cannot be inlined", callee.Name, caller.Name)
+				return inlined
+			}
+		}
+		c.inlineCall(expr, callee, hoisted)
+		c.reportInline("inline %v into %v", callee.Name, caller.Name)
+		return inlined + 1
+	}
+	if expr.LeftExpression != nil {
+		inlined += c.inlineExpression(caller, expr.LeftExpression, hoisted)
+	}
+	if expr.RightExpression != nil {
+		inlined += c.inlineExpression(caller, expr.RightExpression, hoisted)
+	}
+	return inlined
+}
+
+// inlineCall appends a renamed copy of the body of the callee to hoisted and replaces the call with its result
+func (c *Compiler) inlineCall(call *Expression, callee *FunctionDefinition, hoisted *[]*IntermediateOperation) {
+	c.inlineCount++
+	body := cloneFunction(callee, fmt.Sprintf("inline_%v_%v", c.inlineCount, callee.Name))
+	c.uniquifyVariables(body)
+	// bind the arguments to the renamed parameters
+	placeholder := call.Value.Value.(*FunctionCallPlaceholder)
+	for i, param := range body.Accepts {
+		*hoisted = append(*hoisted, &IntermediateOperation{
+			Type: IM_ASSIGN,
+			Args: []any{param.Name, param.Type, placeholder.Args[i]},
+		})
+	}
+	// copy the body, binding the returned value to a result symbol
+	result := "#" + body.Name + "_result"
+	for _, op := range body.Operations {
+		switch op.Type {
+		case IM_ASSIGN, IM_EXPRESSION:
+			*hoisted = append(*hoisted, op)
+		case IM_RETURN:
+			*hoisted = append(*hoisted, &IntermediateOperation{
+				Type: IM_ASSIGN,
+				Args: []any{result, body.Returns, op.Args[0]},
+			})
+		}
+	}
+	// replace the call with a reference to the result
+	call.Operator = BO_VSYMBOL_PLACEHOLDER
+	call.Value = &BinaryTypedValue{Type: BT_NOTYPE, Value: result}
+	call.Args = nil
+}
+
+// inlineRejection returns the reason why the function cannot be inlined or an empty string if it can be inlined
+func (c *Compiler) inlineRejection(def *FunctionDefinition) string {
+	size := 0
+	returns := 0
+	for _, op := range def.Operations {
+		switch op.Type {
+		case IM_NOP:
+			continue
+		case IM_ASSIGN:
+			if len(op.Args) != 3 {
+				return "uninitialized variable"
+			}
+			if expr, ok := op.Args[2].(*Expression); !ok || expr == nil {
+				return "uninitialized variable"
+			}
+		case IM_EXPRESSION:
+		case IM_RETURN:
+			if expr, ok := op.Args[0].(*Expression); !ok || expr == nil {
+				return "does not return a value"
+			}
+			returns++
+		default:
+			return "contains control flow"
+		}
+		if returns > 0 && op.Type != IM_RETURN {
+			return "contains unreachable code"
+		}
+		for _, expr := range operationExpressions(op) {
+			if containsCall(expr) {
+				return "not a leaf function"
+			}
+		}
+		size++
+	}
+	if returns != 1 {
+		return "does not return a value"
+	}
+	if size > c.inlineThreshold {
+		return fmt.Sprintf("size %v exceeds the inline threshold of %v", size, c.inlineThreshold)
+	}
+	return ""
+}
+
+func (c *Compiler) reportInline(format string, args ...any) {
+	if c.verbose {
+		fmt.Printf("[GSC][inline] "+format+"\n", args...)
+	}
+}
+
+// containsCall checks if the expression contains a call to a function or builtin
+func containsCall(expr *Expression) bool {
+	if expr.Operator == BO_FUNCTION_CALL_PLACEHOLDER {
+		return true
+	}
+	if expr.LeftExpression != nil && containsCall(expr.LeftExpression) {
+		return true
+	}
+	return expr.RightExpression != nil && containsCall(expr.RightExpression)
+}
+
+// cloneFunction creates a deep copy of the function with a new name
+func cloneFunction(def *FunctionDefinition, name string) *FunctionDefinition {
+	clone := &FunctionDefinition{
+		Name:       name,
+		Accepts:    []*IntermediateVar{},
+		Returns:    def.Returns,
+		Operations: []*IntermediateOperation{},
+	}
+	for _, param := range def.Accepts {
+		clone.Accepts = append(clone.Accepts, &IntermediateVar{Name: param.Name, Type: param.Type})
+	}
+	for _, op := range def.Operations {
+		args := make([]any, len(op.Args))
+		for i, arg := range op.Args {
+			if expr, ok := arg.(*Expression); ok && expr != nil {
+				args[i] = cloneExpression(expr)
+				continue
+			}
+			args[i] = arg
+		}
+		clone.Operations = append(clone.Operations, &IntermediateOperation{Type: op.Type, Args: args, Line: op.Line})
+	}
+	return clone
+}
+
+// cloneExpression creates a deep copy of an unresolved expression, operators receive their own result storage
+func cloneExpression(expr *Expression) *Expression {
+	clone := &Expression{
+		Operator: expr.Operator,
+		Ref:      expr.Ref,
+	}
+	if expr.LeftExpression != nil {
+		clone.LeftExpression = cloneExpression(expr.LeftExpression)
+	}
+	if expr.RightExpression != nil {
+		clone.RightExpression = cloneExpression(expr.RightExpression)
+	}
+	if expr.Value != nil {
+		clone.Value = &BinaryTypedValue{Type: expr.Value.Type, Value: expr.Value.Value}
+		switch {
+		case expr.Operator == BO_FUNCTION_CALL_PLACEHOLDER:
+			placeholder := expr.Value.Value.(*FunctionCallPlaceholder)
+			args := []*Expression{}
+			for _, arg := range placeholder.Args {
+				args = append(args, cloneExpression(arg))
+			}
+			clone.Value.Value = &FunctionCallPlaceholder{Name: placeholder.Name, SymbolName: placeholder.SymbolName, Args: args}
+		case expr.LeftExpression != nil:
+			clone.Value.Value = defaultValuePtrOf(expr.Value.Type)
+		}
+	}
+	return clone
+}
@@ -0,0 +1,69 @@
+package goscript
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompileInline(t *testing.T) {
+	for _, threshold := range []int{0, 8} {
+		compiler := NewCompiler()
+		prog, err := compiler.Compile(CompileJob{
+			MainFilePath:       filepath.Join(TEST_WORKSPACE, "inline.gs"),
+			LocalWorkspaceRoot: TEST_WORKSPACE,
+			VendorPath:         TEST_VENDOR,
+			StandardLibPath:    TEST_STANDARD,
+			InlineThreshold:    threshold,
+			Verbose:            true,
+		})
+		if err != nil {
+			t.Fatalf("compilation failed with error %v", err)
+		}
+		fmt.Println(prog)
+		if threshold > 0 {
+			// every function was inlined, so only main remains
+			expectLength(mapKeys(compiler.funcBaseByName), 1, "all called functions should have been inlined")
+			for _, op := range prog.Operations {
+				if op.Type == RETURN && op.Args[0].(*Expression).Operator == BO_FUNCTION_CALL {
+					t.Fatalf("call to square should have been inlined")
+				}
+			}
+		}
+		rt := NewRuntime()
+		ret, err := rt.Exec(*prog)
+		if err != nil {
+			t.Fatalf("execution failed with error %v", err)
+		}
+		expectValue(*ret.Value.(*uint64), uint64(81))
+	}
+}
+
+func TestInlineRejection(t *testing.T) {
+	compiler := NewCompiler()
+	compiler.inlineThreshold = 1
+	square := parseFunction(UnparsedFunction{
+		Name:    "#fn_0_main_square",
+		Args:    "x: u64",
+		Returns: "u64",
+		Body:    "let y: u64 = x * x\nreturn y\n",
+	})
+	expectValue(compiler.inlineRejection(square), "size 2 exceeds the inline threshold of 1")
+	compiler.inlineThreshold = 2
+	expectValue(compiler.inlineRejection(square), "")
+	logln := parseFunction(UnparsedFunction{
+		Name:    "#fn_0_main_logln",
+		Args:    "msg: str",
+		Returns: "u64",
+		Body:    "println(msg)\nreturn 0\n",
+	})
+	expectValue(compiler.inlineRejection(logln), "not a leaf function")
+}
+
+func mapKeys[K comparable, V any](m map[K]V) []K {
+	keys := []K{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
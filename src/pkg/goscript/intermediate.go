@@ -106,9 +106,11 @@ const (
 	IM_RETURN          IntermediateOperationType = 6
 	IM_FOREACH         IntermediateOperationType = 7
 	IM_EXPRESSION      IntermediateOperationType = 8
+	IM_CONTINUE        IntermediateOperationType = 9
 )
 
 type IntermediateOperation struct {
 	Type IntermediateOperationType
 	Args []any
+	Line int // the index of the line in the function body this operation was parsed from
 }
@@ -0,0 +1,194 @@
+package goscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+/*
+	The lockfile makes builds with externals reproducible. It is written next to the application file, with the
+	extension of the application file replaced by .lock, and records every external that was fetched into the module
+	cache (see externals.go) with the commit it was extracted from and a hash of its content, and every vendored
+	external with the hash of its content.
+
+	While an external is locked, the locked commit is used instead of selecting the newest matching tag again, and the
+	module is only fetched if the commit is not in the module cache yet. The content of the module must match the hash
+	in the lockfile, otherwise the compilation fails with ErrLockfileMismatch. The lock of an external is only replaced
+	if its directive changed such that the locked version no longer satisfies it, externals that are no longer required
+	are removed from the lockfile. Deleting the lockfile resolves all externals again.
+
+	The content of a vendored external must match the hash in the lockfile as well, so changes to the vendor directory
+	do not go unnoticed, this includes vendoring an external that was locked when it was fetched. The lockfile is verified on every compilation, also if externals are not fetched because the
+	compilation has no module cache. Lockfiles of applications that are not read from the disk are verified, but
+	cannot be written.
+*/
+
+// ErrLockfileMismatch is returned when the content of an external does not match its lock
+var ErrLockfileMismatch = errors.New("external module does not match the lockfile")
+
+// Lockfile records the externals of an application
+type Lockfile struct {
+	Externals []LockedExternal
+}
+
+// LockedExternal is the version of an external that an application is built with
+type LockedExternal struct {
+	Name   string
+	URL    string
+	Branch string
+	Tag    string // the version tag of the commit, empty if the branch had no tags
+	Commit string
+	Hash   string // the hash of the content of the module, see hashModuleContent
+	// vendored externals have no tag and commit, they are only locked by the hash of their content
+	Vendored bool `json:"Vendored,omitempty"`
+}
+
+// lockfileName returns the path of the lockfile of the application file
+func lockfileName(mainPath string) string {
+	return mainPath[:len(mainPath)-len(path.Ext(mainPath))] + ".lock"
+}
+
+// readLockfile reads the lockfile at the path of the file system, the lockfile is nil if it does not exist
+func readLockfile(fsys fs.FS, name string) (*Lockfile, error) {
+	buff, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile %v with error %v", name, err)
+	}
+	lock := &Lockfile{}
+	if err := json.Unmarshal(buff, lock); err != nil {
+		return nil, fmt.Errorf("failed to decode lockfile %v with error %v", name, err)
+	}
+	for _, external := range lock.Externals {
+		if !external.Vendored && !isObjectID(external.Commit) {
+			return nil, fmt.Errorf("lockfile %v locks external %v at invalid commit %v", name, external.Name, external.Commit)
+		}
+	}
+	return lock, nil
+}
+
+// encode returns the lockfile as indented JSON
+func (l *Lockfile) encode() ([]byte, error) {
+	buff, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lockfile with error %v", err)
+	}
+	return append(buff, '\n'), nil
+}
+
+// lockedByName returns the locked externals by their name, the map is empty if there is no lockfile
+func (l *Lockfile) lockedByName() map[string]*LockedExternal {
+	locked := make(map[string]*LockedExternal)
+	if l == nil {
+		return locked
+	}
+	for i := range l.Externals {
+		locked[l.Externals[i].Name] = &l.Externals[i]
+	}
+	return locked
+}
+
+// lockVendored returns the locks of the required externals that are vendored, which are directories of the vendor
+// file system named like the external
+func lockVendored(vendor fs.FS, required []*ExternalModuleSource) ([]LockedExternal, error) {
+	locks := []LockedExternal{}
+	seen := make(map[string]bool)
+	for _, external := range required {
+		if seen[external.Name] {
+			continue
+		}
+		seen[external.Name] = true
+		hash, err := hashModuleContent(vendor, external.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash vendored external %v with error %v", external.Name, err)
+		}
+		branch := external.Branch
+		if branch == "" {
+			branch = "master"
+		}
+		locks = append(locks, LockedExternal{Name: external.Name, URL: external.URL, Branch: branch, Hash: hash, Vendored: true})
+	}
+	return locks, nil
+}
+
+// verifyLockfile checks the content of the fetched and vendored externals against their locks and returns the
+// lockfile of these externals, the error wraps ErrLockfileMismatch if the content of an external changed
+func verifyLockfile(lock *Lockfile, lockPath string, externals []*ExternalModule, vendored []LockedExternal) (*Lockfile, error) {
+	locked := lock.lockedByName()
+	updated := &Lockfile{Externals: []LockedExternal{}}
+	for _, module := range externals {
+		if previous := locked[module.Name]; previous != nil && previous.URL == module.URL && previous.Commit == module.Commit && previous.Hash != module.Hash {
+			return nil, fmt.Errorf("%w: the content of external %v at commit %v has hash %v, but %v locks hash %v (delete %v to fetch it again)", ErrLockfileMismatch, module.Name, module.Commit, module.Hash, lockPath, previous.Hash, module.Dir)
+		}
+		updated.Externals = append(updated.Externals, LockedExternal{
+			Name:   module.Name,
+			URL:    module.URL,
+			Branch: module.Branch,
+			Tag:    module.Tag,
+			Commit: module.Commit,
+			Hash:   module.Hash,
+		})
+	}
+	for _, module := range vendored {
+		if previous := locked[module.Name]; previous != nil && previous.Hash != module.Hash {
+			return nil, fmt.Errorf("%w: the content of vendored external %v has hash %v, but %v locks hash %v (remove its lock to accept the change)", ErrLockfileMismatch, module.Name, module.Hash, lockPath, previous.Hash)
+		}
+		updated.Externals = append(updated.Externals, module)
+	}
+	return updated, nil
+}
+
+// writeLockfile writes the lockfile to the path unless its content did not change
+func writeLockfile(lock *Lockfile, previous *Lockfile, lockPath string) error {
+	buff, err := lock.encode()
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		if old, err := previous.encode(); err == nil && bytes.Equal(old, buff) {
+			return nil
+		}
+	}
+	if err := os.WriteFile(lockPath, buff, 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile with error %v", err)
+	}
+	fmt.Printf("[GSC][lockfile] wrote %v externals to %v\n", len(lock.Externals), lockPath)
+	return nil
+}
+
+// hashModuleContent returns the sha256 hash of the paths and contents of all files of the module in the directory of
+// the file system, in lexical order. The paths are relative to the module, so a vendored copy of a module has the
+// same hash as the module.
+func hashModuleContent(fsys fs.FS, dir string) (string, error) {
+	hash := sha256.New()
+	err := fs.WalkDir(fsys, dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		relative := name
+		if dir != "." {
+			relative = strings.TrimPrefix(name, dir+"/")
+		}
+		fileHash := sha256.Sum256(content)
+		fmt.Fprintf(hash, "%x  %v\n", fileHash, relative)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash module with error %v", err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,227 @@
+package goscript
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockfile(t *testing.T) {
+	root := t.TempDir()
+	repo := newGitRepo(t, filepath.Join(root, "scale"))
+	files := func(factor int) map[string]string {
+		return map[string]string{"scale.gs": fmt.Sprintf("func apply(x: u64) => u64 {\n    return x * %v\n}", factor)}
+	}
+	repo.commit(files(10), "v1.0.0")
+	lockedCommit := repo.commit(files(20), "v1.1.0")
+	workspace := filepath.Join(root, "app")
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		t.Fatalf("failed to create workspace with error %v", err)
+	}
+	mainPath := filepath.Join(workspace, "main.gs")
+	compile := func(constraint string) (*Compiler, uint64, error) {
+		source := "func main() {\n    return 1\n}"
+		if constraint != "" {
+			source = fmt.Sprintf("external scale from \"%v@version=%v\"\n\nimport \"ext/scale\"\n\nfunc main() {\n    return scale.apply(2)\n}", repo.url(), constraint)
+		}
+		if err := os.WriteFile(mainPath, []byte(source), 0644); err != nil {
+			t.Fatalf("failed to write main file with error %v", err)
+		}
+		compiler := NewCompiler()
+		prog, err := compiler.Compile(CompileJob{MainFilePath: mainPath, LocalWorkspaceRoot: workspace, ModuleCachePath: filepath.Join(root, "cache")})
+		if err != nil {
+			return compiler, 0, err
+		}
+		ret, err := NewRuntime().Exec(*prog)
+		if err != nil {
+			t.Fatalf("execution failed with error %v", err)
+		}
+		return compiler, *ret.Value.(*uint64), nil
+	}
+	readLock := func() *Lockfile {
+		lock, err := readLockfile(os.DirFS(workspace), "main.lock")
+		if err != nil || lock == nil {
+			t.Fatalf("failed to read the lockfile with error %v", err)
+		}
+		return lock
+	}
+	compiler, value, err := compile("^1.0.0")
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectValue(value, uint64(40))
+	lock := readLock()
+	expectLength(lock.Externals, 1, "locked externals")
+	expectValue(lock.Externals[0], LockedExternal{
+		Name:   "scale",
+		URL:    repo.url(),
+		Branch: "master",
+		Tag:    "v1.1.0",
+		Commit: lockedCommit,
+		Hash:   compiler.Externals()[0].Hash,
+	})
+	expectValue(strings.HasPrefix(lock.Externals[0].Hash, "sha256:"), true)
+	// a newer version does not change the build while the lock satisfies the directive
+	repo.commit(files(30), "v1.2.0")
+	_, value, err = compile("^1.0.0")
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectValue(value, uint64(40))
+	expectValue(readLock().Externals[0].Commit, lockedCommit)
+	// modified content in the module cache is detected
+	moduleFile := filepath.Join(compiler.Externals()[0].Dir, "scale.gs")
+	if err := os.WriteFile(moduleFile, []byte(files(50)["scale.gs"]), 0644); err != nil {
+		t.Fatalf("failed to modify the module with error %v", err)
+	}
+	_, _, err = compile("^1.0.0")
+	if !errors.Is(err, ErrLockfileMismatch) {
+		t.Fatalf("expected a lockfile mismatch but got %v", err)
+	}
+	fmt.Println(err)
+	if err := os.WriteFile(moduleFile, []byte(files(20)["scale.gs"]), 0644); err != nil {
+		t.Fatalf("failed to restore the module with error %v", err)
+	}
+	// a directive the lock does not satisfy selects and locks a new version
+	_, value, err = compile("^1.2.0")
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectValue(value, uint64(60))
+	expectValue(readLock().Externals[0].Tag, "v1.2.0")
+	// externals that are no longer required are removed
+	if _, _, err = compile(""); err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	expectLength(readLock().Externals, 0, "locked externals")
+}
+
+func TestLockfileFS(t *testing.T) {
+	root := t.TempDir()
+	repo := newGitRepo(t, filepath.Join(root, "scale"))
+	commit := repo.commit(map[string]string{"scale.gs": "func apply(x: u64) => u64 {\n    return x * 10\n}"}, "v1.0.0")
+	lock := &Lockfile{Externals: []LockedExternal{{Name: "scale", URL: repo.url(), Branch: "master", Tag: "v1.0.0", Commit: commit, Hash: "sha256:0"}}}
+	buff, err := lock.encode()
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	// the lockfile of a workspace that is not on disk is verified
+	_, err = NewCompiler().Compile(CompileJob{
+		MainFilePath: "app/main.gs",
+		Workspace: mapFS(map[string]string{
+			"app/main.gs":   fmt.Sprintf("external scale from \"%v@version=^1.0.0\"\n\nimport \"ext/scale\"\n\nfunc main() {\n    return scale.apply(2)\n}", repo.url()),
+			"app/main.lock": string(buff),
+		}),
+		ModuleCachePath: filepath.Join(root, "cache"),
+	})
+	if !errors.Is(err, ErrLockfileMismatch) {
+		t.Fatalf("expected a lockfile mismatch but got %v", err)
+	}
+	expectValue(strings.Contains(err.Error(), "app/main.lock locks hash sha256:0"), true)
+	expectValue(lockfileName("app/main.gs"), "app/main.lock")
+	expectValue(lockfileName("main"), "main.lock")
+	// locked commits that are not object ids could escape the module cache or be read as an option by git
+	for _, invalid := range []string{"../../../escape", "--output=/tmp/escape", "HEAD", strings.ToUpper(commit), commit[:39], ""} {
+		lock.Externals[0].Commit = invalid
+		buff, err := lock.encode()
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		if _, err := readLockfile(mapFS(map[string]string{"main.lock": string(buff)}), "main.lock"); err == nil {
+			t.Fatalf("reading a lockfile with commit %v should fail", invalid)
+		}
+		expectValue(lockSatisfies(&lock.Externals[0], repo.url(), "master", "^1.0.0"), false)
+		if _, err := (&moduleCache{root: root}).moduleDir("scale", invalid); err == nil {
+			t.Fatalf("commit %v should not have a module directory", invalid)
+		}
+	}
+}
+
+func TestLockfileVendored(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "app")
+	vendor := filepath.Join(root, "vendor")
+	for _, dir := range []string{workspace, filepath.Join(vendor, "scale")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create directory with error %v", err)
+		}
+	}
+	vendoredFile := filepath.Join(vendor, "scale", "scale.gs")
+	writeScale := func(factor int) {
+		if err := os.WriteFile(vendoredFile, []byte(fmt.Sprintf("func apply(x: u64) => u64 {\n    return x * %v\n}", factor)), 0644); err != nil {
+			t.Fatalf("failed to write vendored module with error %v", err)
+		}
+	}
+	writeScale(10)
+	source := "external scale from \"https://example.com/scale@version=^1.0.0\"\n\nimport \"ext/scale\"\n\nfunc main() {\n    return scale.apply(2)\n}"
+	mainPath := filepath.Join(workspace, "main.gs")
+	if err := os.WriteFile(mainPath, []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write main file with error %v", err)
+	}
+	// the lockfile is verified and written without a module cache
+	compile := func() error {
+		_, err := NewCompiler().Compile(CompileJob{MainFilePath: mainPath, LocalWorkspaceRoot: workspace, VendorPath: vendor})
+		return err
+	}
+	if err := compile(); err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	lock, err := readLockfile(os.DirFS(workspace), "main.lock")
+	if err != nil || lock == nil {
+		t.Fatalf("failed to read the lockfile with error %v", err)
+	}
+	hash, err := hashModuleContent(os.DirFS(vendor), "scale")
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	expectLength(lock.Externals, 1, "locked externals")
+	expectValue(lock.Externals[0], LockedExternal{Name: "scale", URL: "https://example.com/scale", Branch: "master", Hash: hash, Vendored: true})
+	// the vendored module has the hash of the module itself, not of its location
+	moduleHash, err := hashModuleContent(os.DirFS(filepath.Join(vendor, "scale")), ".")
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	expectValue(moduleHash, hash)
+	// modified vendored content is detected
+	writeScale(20)
+	if err = compile(); !errors.Is(err, ErrLockfileMismatch) {
+		t.Fatalf("expected a lockfile mismatch but got %v", err)
+	}
+	fmt.Println(err)
+	// also if the workspace is not on disk
+	_, err = NewCompiler().Compile(CompileJob{
+		MainFilePath: "main.gs",
+		Workspace:    mapFS(map[string]string{"main.gs": source, "main.lock": mustReadFile(t, filepath.Join(workspace, "main.lock"))}),
+		Vendor:       os.DirFS(vendor),
+	})
+	if !errors.Is(err, ErrLockfileMismatch) {
+		t.Fatalf("expected a lockfile mismatch but got %v", err)
+	}
+	writeScale(10)
+	if err := compile(); err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	// vendoring an external that was locked when it was fetched must not replace its lock silently
+	fetched := &Lockfile{Externals: []LockedExternal{{Name: "scale", URL: "https://example.com/scale", Branch: "master", Tag: "v1.0.0", Commit: strings.Repeat("a", 40), Hash: "sha256:0"}}}
+	buff, err := fetched.encode()
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "main.lock"), buff, 0644); err != nil {
+		t.Fatalf("failed to write lockfile with error %v", err)
+	}
+	if err = compile(); !errors.Is(err, ErrLockfileMismatch) {
+		t.Fatalf("expected a lockfile mismatch but got %v", err)
+	}
+}
+
+func mustReadFile(t *testing.T, name string) string {
+	buff, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("failed to read %v with error %v", name, err)
+	}
+	return string(buff)
+}
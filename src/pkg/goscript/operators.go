@@ -585,3 +585,44 @@ func indirectCast[RT Numeric](value *BinaryTypedValue) RT {
 		panic(fmt.Sprintf("unknown type %v in indirect cast", value.Type))
 	}
 }
+
+// castNumeric converts a numeric value to the specified numeric type and yields a pointer to the converted value
+func castNumeric(value *BinaryTypedValue, target BinaryType) any {
+	switch target {
+	case BT_INT8:
+		conv := indirectCast[int8](value)
+		return &conv
+	case BT_INT16:
+		conv := indirectCast[int16](value)
+		return &conv
+	case BT_INT32:
+		conv := indirectCast[int32](value)
+		return &conv
+	case BT_INT64:
+		conv := indirectCast[int64](value)
+		return &conv
+	case BT_UINT8:
+		conv := indirectCast[uint8](value)
+		return &conv
+	case BT_UINT16:
+		conv := indirectCast[uint16](value)
+		return &conv
+	case BT_UINT32:
+		conv := indirectCast[uint32](value)
+		return &conv
+	case BT_UINT64:
+		conv := indirectCast[uint64](value)
+		return &conv
+	case BT_BYTE:
+		conv := indirectCast[byte](value)
+		return &conv
+	case BT_FLOAT32:
+		conv := indirectCast[float32](value)
+		return &conv
+	case BT_FLOAT64:
+		conv := indirectCast[float64](value)
+		return &conv
+	default:
+		panic(fmt.Sprintf("cannot cast to non numeric type %v", target))
+	}
+}
@@ -1,11 +0,0 @@
-package goscript
-
-// this package will be temporarily used during development to have easy configuration
-
-var VENDORPATH = "../../../goscript/gs_vendor"
-
-var STDPATH = "../../../goscript/gs_standard"
-
-var TESTS = "../../../goscript/gs_workspace"
-
-var DEBUG_DUMP_FQSC = true
@@ -3,8 +3,8 @@ package goscript
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -67,10 +67,6 @@ func generateFQSC(source *ApplicationSource) (string, error) {
 	fmt.Println("[GSC][genFQSC] blobs merged into FQSC successfully")
 	fmt.Printf("[GSC][STAGE_COMPLETION] fqsc generation completed in %s\n", time.Since(start))
 	fullFQSC += "\n>"
-	// dump fqsc to a file if FQSC debugging is enabled
-	if DEBUG_DUMP_FQSC {
-		os.WriteFile("debug_dump.fqsc", []byte(fullFQSC), 0600)
-	}
 	return fullFQSC, nil
 }
 
@@ -188,9 +184,15 @@ func prefixFunctions(source string, prefix string, name string) string {
 	}
 	// perform the replacements
 	source = FUNC_NAME_REGEX.ReplaceAllString(source, fmt.Sprintf(">\nfunc #fn_%v_%v_$1(", prefix, name))
-	// now fix all calls to the replaced function
+	// now fix all calls to the replaced function, in a stable order so the output is reproducible
+	oldNames := []string{}
+	for oldName := range replacements {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
 	// now match against calls to the functions
-	for oldName, newName := range replacements {
+	for _, oldName := range oldNames {
+		newName := replacements[oldName]
 		// first get a string mask
 		mask := getStringMask(source)
 		// compile a regex for the calls to the old name
@@ -203,9 +205,9 @@ func prefixFunctions(source string, prefix string, name string) string {
 			if mask[indexMatches[i][0]+1] {
 				continue
 			}
-			// generate the new symbol to replace the current one
+			// generate the new symbol to replace the current one, the first character of the match is kept
 			source = source[:indexMatches[i][0]+delta+1] + newName + "(" + source[indexMatches[i][1]+delta:]
-			delta += len(newName+"(") - (indexMatches[i][1] - indexMatches[i][0] + 1)
+			delta += len(newName+"(") - (indexMatches[i][1] - indexMatches[i][0] - 1)
 		}
 	}
 	return source
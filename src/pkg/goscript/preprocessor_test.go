@@ -1,12 +1,11 @@
 package goscript
 
 import (
-	"path/filepath"
 	"testing"
 )
 
 func TestGenerateFQSC(t *testing.T) {
-	ret, err := discoverSources(filepath.Join(TESTS, "imports.gs"), TESTS)
+	ret, err := discoverSources(workspaceJob("imports.gs"))
 	if err != nil {
 		t.Fatalf("sourcewalk failed with error %v", err)
 	}
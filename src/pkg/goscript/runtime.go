@@ -10,11 +10,22 @@ func NewRuntime() *Runtime {
 	return &Runtime{}
 }
 
+// Runtime executes a program. The symbol table and scope stack always belong to the frame of the function that is currently
+// executing, the frames of its callers are saved on the call stack.
+// A Runtime is not safe for concurrent use, use one Runtime per goroutine to execute the same Program concurrently.
 type Runtime struct {
 	SymbolTable      []*BinaryTypedValue
 	SymbolScopeStack [][]int // [scope depth][symbols]
 	ProgramCounter   int
 	Program          Program
+	CallStack        []Frame
+}
+
+// Frame is the saved state of a function that is waiting for a call to return
+type Frame struct {
+	SymbolTable      []*BinaryTypedValue // the local symbols of the function, indexed by frame relative symbol references
+	SymbolScopeStack [][]int             // the scopes that were open in the function
+	ReturnPC         int                 // the pc of the operation that made the call
 }
 
 // reset will reset the state of the runtime
@@ -22,6 +33,27 @@ func (r *Runtime) reset() {
 	r.ProgramCounter = 0
 	r.SymbolTable = []*BinaryTypedValue{}
 	r.SymbolScopeStack = make([][]int, 1)
+	r.CallStack = []Frame{}
+}
+
+// pushFrame saves the frame of the current function on the call stack and creates an empty frame for the callee
+func (r *Runtime) pushFrame() {
+	r.CallStack = append(r.CallStack, Frame{
+		SymbolTable:      r.SymbolTable,
+		SymbolScopeStack: r.SymbolScopeStack,
+		ReturnPC:         r.ProgramCounter,
+	})
+	r.SymbolTable = make([]*BinaryTypedValue, r.Program.SymbolTableSize)
+	r.SymbolScopeStack = make([][]int, 1)
+}
+
+// popFrame discards the frame of the current function and restores the frame of its caller
+func (r *Runtime) popFrame() {
+	frame := r.CallStack[len(r.CallStack)-1]
+	r.CallStack = r.CallStack[:len(r.CallStack)-1]
+	r.SymbolTable = frame.SymbolTable
+	r.SymbolScopeStack = frame.SymbolScopeStack
+	r.ProgramCounter = frame.ReturnPC
 }
 
 func (r *Runtime) enterScope() {
@@ -42,18 +74,26 @@ func (r *Runtime) exitScope() {
 	r.SymbolScopeStack = r.SymbolScopeStack[:len(r.SymbolScopeStack)-1]
 }
 
-// Exec will reset the runtime and then run the specified program until it completes
-func (r *Runtime) Exec(program Program) any {
+// Exec will reset the runtime and then run the specified program until it completes.
+// If the execution fails, a *RuntimeError describing the failure is returned.
+func (r *Runtime) Exec(program Program) (value *BinaryTypedValue, err error) {
 	// completely reset the runtime
 	r.reset()
 	// save our program
 	r.Program = program
 	// build a symbol table of the requested size
 	r.SymbolTable = make([]*BinaryTypedValue, program.SymbolTableSize)
+	// convert any failure during the execution into a runtime error
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			value = nil
+			err = r.newRuntimeError(recovered)
+		}
+	}()
 	// execute our program until the main function returns
 	returnValue := r.execUntilReturn()
 	// exit with this value
-	return returnValue
+	return returnValue, nil
 }
 
 // execUntilReturn will keep executing instructions until a return is hit in the current scope, and then return the value passed to the return
@@ -183,7 +223,7 @@ func (r *Runtime) unlinkedAssign(target *BinaryTypedValue, value *BinaryTypedVal
 		*target.Value.(*bool) = *value.Value.(*bool)
 	case BT_LIST:
 		// assign the underlying value of value to the underlying value of target
-		*target.Value.(*[]*BinaryTypedValue) = *value.Value.(*[]*BinaryTypedValue)
+		*target.Value.(*[]*BinaryTypedValue) = r.copyList(*value.Value.(*[]*BinaryTypedValue))
 	case BT_NULL:
 		target.Type = BT_NULL
 		target.Value = nil
@@ -192,83 +232,83 @@ func (r *Runtime) unlinkedAssign(target *BinaryTypedValue, value *BinaryTypedVal
 	}
 }
 
+// copyList creates a deep copy of the list
+func (r *Runtime) copyList(list []*BinaryTypedValue) []*BinaryTypedValue {
+	copied := make([]*BinaryTypedValue, len(list))
+	for i, element := range list {
+		// elements without a value have nothing to share
+		if element.Value == nil {
+			copied[i] = &BinaryTypedValue{Type: element.Type}
+			continue
+		}
+		copied[i] = r.unlink(element)
+	}
+	return copied
+}
+
+// unlink returns a copy of the value that does not share its underlying value with the original
 func (r *Runtime) unlink(value *BinaryTypedValue) *BinaryTypedValue {
 	switch value.Type {
 	case BT_INT8:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*int8)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_INT16:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*int16)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_INT32:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*int32)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_INT64:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*int64)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_UINT8:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*uint8)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_UINT16:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*uint16)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_UINT32:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*uint32)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_UINT64:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*uint64)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_BYTE:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*byte)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_FLOAT32:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*float32)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_FLOAT64:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*float64)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_STRING:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*string)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_CHAR:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*rune)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_BOOLEAN:
 		// cast the value's type to its underlying type
 		underlying := *value.Value.(*bool)
-		value.Value = &underlying
-		return value
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_LIST:
-		// cast the value's type to its underlying type
-		underlying := *value.Value.(*[]*BinaryTypedValue)
-		value.Value = &underlying
-		return value
+		// copy the elements, so the copy does not share them with the original list
+		underlying := r.copyList(*value.Value.(*[]*BinaryTypedValue))
+		return &BinaryTypedValue{Type: value.Type, Value: &underlying}
 	case BT_NOTYPE:
 		return value
 	case BT_NULL:
@@ -318,13 +358,17 @@ func (r *Runtime) execBind(operation *BinaryOperation) {
 	symbolRef := operation.Args[0].(int)
 	// get the symbol type from arg0
 	symType := operation.Args[1].(BinaryType)
+	// a symbol that is still bound is being rebound by a loop or a tail call and already belongs to a scope
+	rebind := r.SymbolTable[symbolRef] != nil
 	// initialize the symbol
 	r.SymbolTable[symbolRef] = &BinaryTypedValue{
 		Type:  symType,
 		Value: defaultValuePtrOf(symType),
 	}
 	// save the symbol reference to the current scope
-	r.SymbolScopeStack[len(r.SymbolScopeStack)-1] = append(r.SymbolScopeStack[len(r.SymbolScopeStack)-1], symbolRef)
+	if !rebind {
+		r.SymbolScopeStack[len(r.SymbolScopeStack)-1] = append(r.SymbolScopeStack[len(r.SymbolScopeStack)-1], symbolRef)
+	}
 }
 
 func defaultValuePtrOf(valueType BinaryType) any {
@@ -424,9 +468,22 @@ func (r *Runtime) ResolveExpression(e *Expression) *BinaryTypedValue {
 		left := r.ResolveExpression(e.LeftExpression)
 		// then resolve the right expression
 		right := r.ResolveExpression(e.RightExpression)
-		// finally apply the operator
-		return applyOperator(left, right, e.Operator, e.Value)
+		// finally apply the operator, writing the result into new storage since the expression must not be modified
+		return applyOperator(left, right, e.Operator, newResult(e))
+	}
+}
+
+// newResult allocates the value an operator writes its result into
+func newResult(e *Expression) *BinaryTypedValue {
+	if e.Value == nil {
+		return &BinaryTypedValue{Type: BT_NOTYPE}
+	}
+	resultType := e.Value.Type
+	if resultType.isNumeric() {
+		return &BinaryTypedValue{Type: resultType, Value: defaultValuePtrOf(resultType)}
 	}
+	// comparison operators replace the value entirely
+	return &BinaryTypedValue{Type: resultType}
 }
 
 // indexIntoExpression will index into the following expression, assuming it is an array and has been type checked
@@ -730,24 +787,23 @@ func (r *Runtime) builtinLen(args []*FunctionArgument) *BinaryTypedValue {
 
 // execFunctionExpression will execute the expression as a function, assuming that it has been type checked before
 func (r *Runtime) execFunctionExpression(e *Expression) *BinaryTypedValue {
-	// save the current pc so we can return here later
-	returnPC := r.ProgramCounter
+	// resolve the arguments in the frame of the caller, copying them so the callee cannot modify the callers symbols
+	args := make([]*BinaryTypedValue, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = r.unlink(r.ResolveExpression(arg.Expression))
+	}
+	// save the frame of the caller and create a new frame for the callee
+	r.pushFrame()
+	// bind the parameter symbols of the callee to the arguments
+	for i, arg := range e.Args {
+		r.SymbolTable[arg.SymbolRef] = args[i]
+		r.SymbolScopeStack[0] = append(r.SymbolScopeStack[0], arg.SymbolRef)
+	}
 	// jump to the appropriate section
 	r.ProgramCounter = e.Ref
-	// open a new scope
-	r.enterScope()
-	// perform the appropriate argument mapping
-	for _, arg := range e.Args {
-		// resolve the argument expression
-		argResolution := r.ResolveExpression(arg.Expression)
-		// set the symbol in the local scope
-		r.SymbolTable[arg.SymbolRef].Value = argResolution.Value
-	}
 	// execute until this top level function returns
-	e.Value = r.execUntilReturn()
-	// exit the scope
-	r.exitScope()
-	// return to the original place in the code
-	r.ProgramCounter = returnPC
-	return e.Value
+	returnValue := r.execUntilReturn()
+	// restore the frame of the caller, which also returns to the original place in the code
+	r.popFrame()
+	return returnValue
 }
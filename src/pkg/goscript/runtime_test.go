@@ -2,6 +2,7 @@ package goscript
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -22,7 +23,9 @@ func TestAssignConstant(t *testing.T) {
 		SymbolTableSize: 2,
 	}
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	v := runtime.SymbolTable[1].Value.(*uint8)
 	if *v != 11 {
 		t.Fatalf("symbol should have been 11 but was %v", *v)
@@ -45,7 +48,9 @@ func TestAssignArrayConstant(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	v := *runtime.SymbolTable[1].Value.(*[]*BinaryTypedValue)
 	if *v[0].Value.(*uint8) != 11 {
 		t.Fatalf("symbol should have been 11 but was %v", *v[0].Value.(*uint8))
@@ -71,7 +76,9 @@ func TestArrayIndexInto(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	v := *runtime.SymbolTable[2].Value.(*uint8)
 	if v != 11 {
 		t.Fatalf("symbol should have been 11 but was %v", v)
@@ -95,7 +102,9 @@ func TestArrayGrow(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	v := *runtime.SymbolTable[1].Value.(*[]*BinaryTypedValue)
 	if len(v) != 11 {
 		t.Fatalf("symbol should have had length 11 but had length %v", len(v))
@@ -119,7 +128,9 @@ func TestArrayShrink(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	v := *runtime.SymbolTable[1].Value.(*[]*BinaryTypedValue)
 	if len(v) != 1 {
 		t.Fatalf("symbol should have had length 1 but had length %v", len(v))
@@ -143,8 +154,9 @@ func TestAssignFunctionReturnValue(t *testing.T) {
 	testProgram := Program{
 		Operations: []BinaryOperation{
 			NewBindOp(1, BT_UINT8),
-			NewBindOp(2, BT_UINT8),
-			NewAssignExpressionOp(1, NewFunctionExpression(3, []*FunctionArgument{})), // assign the return value of the function at pc1 to the symbol 1
+			NewAssignExpressionOp(1, NewFunctionExpression(3, []*FunctionArgument{})), // assign the return value of the function at pc3 to the symbol 1
+			NewReturnValueOp(NewVSymbolExpression(1)),                                 // return the value of the symbol 1
+			NewBindOp(2, BT_UINT8),                                                    // bind the local symbol 2 in the frame of the function
 			NewAssignExpressionOp(2, NewConstantExpression(&eleven, BT_UINT8)),        // assign the constant 11 to the local symbol 2
 			NewReturnValueOp(NewVSymbolExpression(2)),                                 // return the value of the symbol 2
 		},
@@ -152,7 +164,9 @@ func TestAssignFunctionReturnValue(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	v := runtime.SymbolTable[1].Value.(*uint8)
 	if *v != 11 {
 		t.Fatalf("symbol should have been 11 but was %v", *v)
@@ -192,50 +206,32 @@ func TestAssignFunctionReturnValue(t *testing.T) {
 */
 func TestLoopAssign(t *testing.T) {
 
-	zero := uint64(0)
-	zero2 := uint64(0)
-	zero3 := uint64(0)
-	one := uint64(1)
-	billion := uint64(10000000)
-	falsePtr := false
-	testProgram := Program{
-		Operations: []BinaryOperation{
-			NewBindOp(1, BT_UINT64),
-			NewAssignExpressionOp(1, NewFunctionExpression(3, []*FunctionArgument{})), // let a: uint8 = getLoopIteratorAfter10()
-			NewReturnValueOp(NewVSymbolExpression(1)),
-			NewBindOp(2, BT_UINT64),
-			NewAssignExpressionOp(2, NewConstantExpression(&zero2, BT_UINT64)), // let b: uint64 = 0
-			NewEnterScope(), // enter loop scope
-			NewBindOp(3, BT_UINT64),
-			NewAssignExpressionOp(3, NewConstantExpression(&zero3, BT_UINT64)), // let i: uint64 = 0
-			NewJumpIfNotOp(12, &Expression{ // break out of loop if i < 10
-				LeftExpression:  NewVSymbolExpression(3),
-				RightExpression: NewConstantExpression(&billion, BT_UINT64),
-				Operator:        BO_LESSER,
-				Value: &BinaryTypedValue{
-					Value: &falsePtr,
-				},
-			}),
-			NewAssignExpressionOp(2, NewVSymbolExpression(3)), // b = i
-			NewAssignExpressionOp(3, &Expression{ // i++
-				LeftExpression:  NewVSymbolExpression(3),
-				RightExpression: NewConstantExpression(&one, BT_UINT64),
-				Operator:        BO_PLUS,
-				Value: &BinaryTypedValue{
-					Type:  BT_UINT64,
-					Value: &zero,
-				},
-			}),
-			NewJumpOp(8),     // go back to the start of the loop
-			NewExitScopeOp(), // exit the loop scope
-			NewReturnValueOp(NewVSymbolExpression(2)), // return b
-		},
-		SymbolTableSize: 4,
-	}
+	testProgram := *assemble(t, `
+		.symbols 4
+			BIND $1.a, UINT64
+			ASSIGN $1.a, call @getLoopIteratorAfter10()
+			RETURN $1.a
+		getLoopIteratorAfter10:
+			BIND $2.b, UINT64
+			ASSIGN $2.b, UINT64 0
+			ENTER_SCOPE                                      ; enter loop scope
+			BIND $3.i, UINT64
+			ASSIGN $3.i, UINT64 0
+		loop:
+			JUMP_IF_NOT ($3.i < UINT64 10000000), @exit      ; break out of loop if i < 10000000
+			ASSIGN $2.b, $3.i
+			ASSIGN $3.i, ($3.i + UINT64 1):UINT64
+			JUMP @loop                                       ; go back to the start of the loop
+		exit:
+			EXIT_SCOPE                                       ; exit the loop scope
+			RETURN $2.b
+	`)
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
 	start := time.Now()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	fmt.Printf("completed in %s\n", time.Since(start))
 	fmt.Printf("%+v\n", runtime.SymbolTable)
 	fmt.Printf("%+v\n", runtime.SymbolScopeStack)
@@ -267,7 +263,9 @@ func TestPrintBuiltin(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 }
 
 func TestPrintlnBuiltin(t *testing.T) {
@@ -291,7 +289,9 @@ func TestPrintlnBuiltin(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 }
 
 func TestPrintfBuiltin(t *testing.T) {
@@ -321,7 +321,9 @@ func TestPrintfBuiltin(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 }
 
 func TestNumericTypecastU64ToI64(t *testing.T) {
@@ -349,7 +351,9 @@ func TestNumericTypecastU64ToI64(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	fmt.Println(runtime.SymbolTable[2].String())
 	_ = runtime.SymbolTable[2].Value.(*int64)
 }
@@ -379,7 +383,9 @@ func TestNumericToStringTypecast(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	fmt.Println(runtime.SymbolTable[2].String())
 	_ = runtime.SymbolTable[2].Value.(*string)
 }
@@ -409,7 +415,9 @@ func TestNumericToCharTypecast(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	fmt.Println(runtime.SymbolTable[2].String())
 	_ = runtime.SymbolTable[2].Value.(*rune)
 }
@@ -438,7 +446,118 @@ func TestIndexIntoAndCast(t *testing.T) {
 	}
 	fmt.Println(testProgram.String())
 	runtime := NewRuntime()
-	runtime.Exec(testProgram)
+	if _, err := runtime.Exec(testProgram); err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
 	fmt.Println(runtime.SymbolTable[2].String())
 	_ = runtime.SymbolTable[2].Value.(*rune)
 }
+
+func TestRecursiveFibonacci(t *testing.T) {
+	ret := compileAndRun(t, "fib.gs")
+	// fib(20) recurses into both fib(19) and fib(18), so every frame must keep its own n
+	expectValue(*ret.Value.(*uint64), uint64(6765))
+}
+
+func TestMutualRecursion(t *testing.T) {
+	ret := compileAndRun(t, "mutual.gs")
+	expectValue(*ret.Value.(*bool), true)
+}
+
+func compileAndRun(t *testing.T, file string) *BinaryTypedValue {
+	compiler := NewCompiler()
+	prog, err := compiler.Compile(CompileJob{
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, file),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
+	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	fmt.Println(prog)
+	runtime := NewRuntime()
+	ret, err := runtime.Exec(*prog)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectLength(runtime.CallStack, 0, "all frames should have been popped")
+	return ret
+}
+
+func TestConcurrentExecution(t *testing.T) {
+	compiler := NewCompiler()
+	prog, err := compiler.Compile(CompileJob{
+		MainFilePath:       filepath.Join(TEST_WORKSPACE, "fib.gs"),
+		LocalWorkspaceRoot: TEST_WORKSPACE,
+		VendorPath:         TEST_VENDOR,
+		StandardLibPath:    TEST_STANDARD,
+	})
+	if err != nil {
+		t.Fatalf("compilation failed with error %v", err)
+	}
+	// run the same program in many runtimes at once, run with -race to detect any modification of the program
+	results := make(chan uint64)
+	for i := 0; i < 16; i++ {
+		go func() {
+			runtime := NewRuntime()
+			ret, err := runtime.Exec(*prog)
+			if err != nil {
+				t.Errorf("execution failed with error %v", err)
+				results <- 0
+				return
+			}
+			results <- *ret.Value.(*uint64)
+		}()
+	}
+	for i := 0; i < 16; i++ {
+		expectValue(<-results, uint64(6765))
+	}
+}
+
+/*
+	func main() {
+		let a: u64 = fail()
+		return a
+	}
+
+	func fail() => u64 {
+		let b: list<u64> = []
+		return b[5]
+	}
+*/
+func TestRuntimeError(t *testing.T) {
+	five := uint64(5)
+	testProgram := Program{
+		Operations: []BinaryOperation{
+			NewBindOp(1, BT_UINT64),
+			NewAssignExpressionOp(1, NewFunctionExpression(3, []*FunctionArgument{})),
+			NewReturnValueOp(NewVSymbolExpression(1)),
+			NewBindOp(0, BT_LIST),
+			NewAssignExpressionOp(0, NewArrayExpression([]*BinaryTypedValue{})),
+			NewReturnValueOp(NewIndexIntoExpression(0, NewConstantExpression(&five, BT_UINT64))), // index out of range
+		},
+		SymbolTableSize: 2,
+		Debug: &DebugInfo{
+			Functions: []FunctionInfo{
+				{Name: "main", Base: 0, End: 3},
+				{Name: "fail", Base: 3, End: 6},
+			},
+		},
+	}
+	runtime := NewRuntime()
+	value, err := runtime.Exec(testProgram)
+	if value != nil || err == nil {
+		t.Fatalf("execution should have failed but returned %v", value)
+	}
+	fmt.Println(err)
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected a runtime error but got %T", err)
+	}
+	expectValue(runtimeErr.PC, 5)
+	expectValue(runtimeErr.Operation, testProgram.Operations[5].String())
+	expectLength(runtimeErr.StackTrace, 2, "the stack trace should contain fail and main")
+	expectValue(runtimeErr.StackTrace[0], "fail")
+	expectValue(runtimeErr.StackTrace[1], "main")
+}
@@ -0,0 +1,141 @@
+package goscript
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
+)
+
+/*
+	Containers can be signed with an ed25519 key. The signature covers the magic bytes, the format version, the header
+	length and the header exactly as they are stored, and the header includes the checksum of the payload, so any
+	modification of the container invalidates it. The signature itself follows the header (see container.go), outside
+	of the bytes it covers, so the header never has to be encoded again to verify it. The public key of the signer is
+	stored in the header, the runtime decides whether it trusts the signer using a TrustPolicy.
+
+	Keys are stored in PEM files, private keys as PKCS #8 "PRIVATE KEY" blocks and public keys as PKIX "PUBLIC KEY"
+	blocks. A file of trusted keys may contain any number of public keys.
+*/
+
+// ErrUntrustedProgram is wrapped by all errors returned for containers that are rejected by a TrustPolicy
+var ErrUntrustedProgram = errors.New("untrusted program")
+
+// TrustPolicy specifies which programs a runtime is willing to execute
+type TrustPolicy struct {
+	TrustedKeys      []ed25519.PublicKey // if not empty, signed programs must be signed by one of these keys
+	RequireSignature bool                // refuse unsigned programs, signed programs must be signed by one of the TrustedKeys
+}
+
+// checkSigner checks that the policy accepts a program signed by the key, which is nil for unsigned programs
+func (t TrustPolicy) checkSigner(signer ed25519.PublicKey) error {
+	if signer == nil {
+		if t.RequireSignature {
+			return fmt.Errorf("%w: the program is not signed", ErrUntrustedProgram)
+		}
+		return nil
+	}
+	if len(t.TrustedKeys) == 0 && !t.RequireSignature {
+		return nil
+	}
+	for _, key := range t.TrustedKeys {
+		if key.Equal(signer) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: the program is signed by key %x, which is not trusted", ErrUntrustedProgram, []byte(signer))
+}
+
+// verifyContainerSignature verifies the signature over the preamble, which ends with the encoded header
+func verifyContainerSignature(preamble []byte, header *encoding.ContainerHeader, signature []byte) error {
+	if len(header.PublicKey) != ed25519.PublicKeySize || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: the signature is malformed", ErrUntrustedProgram)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(header.PublicKey), preamble, signature) {
+		return fmt.Errorf("%w: the signature is invalid, the program was modified after it was signed", ErrUntrustedProgram)
+	}
+	return nil
+}
+
+// GenerateSigningKey writes a new private key to the path and its public key to the path with the suffix .pub
+func GenerateSigningKey(path string) (ed25519.PublicKey, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key with error %v", err)
+	}
+	encPrivate, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key with error %v", err)
+	}
+	encPublic, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key with error %v", err)
+	}
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encPrivate}), 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write private key with error %v", err)
+	}
+	err = os.WriteFile(path+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encPublic}), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write public key with error %v", err)
+	}
+	return public, nil
+}
+
+// LoadSigningKey reads an ed25519 private key from a PEM file
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	buff, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key with error %v", err)
+	}
+	block, _ := pem.Decode(buff)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%v does not contain a PEM encoded private key", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %v with error %v", path, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %v is a %T, not an ed25519 key", path, key)
+	}
+	return private, nil
+}
+
+// LoadTrustedKeys reads all ed25519 public keys from a PEM file
+func LoadTrustedKeys(path string) ([]ed25519.PublicKey, error) {
+	buff, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys with error %v", err)
+	}
+	keys := []ed25519.PublicKey{}
+	for {
+		var block *pem.Block
+		block, buff = pem.Decode(buff)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trusted key in %v with error %v", path, err)
+		}
+		public, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("trusted key in %v is a %T, not an ed25519 key", path, key)
+		}
+		keys = append(keys, public)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%v does not contain any PEM encoded public keys", path)
+	}
+	return keys, nil
+}
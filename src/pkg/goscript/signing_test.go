@@ -0,0 +1,232 @@
+package goscript
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yoshi-Exeler/goscript/src/pkg/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+// generateKey generates a signing key in a temporary directory and loads it back
+func generateKey(t *testing.T, name string) (ed25519.PrivateKey, string) {
+	path := filepath.Join(t.TempDir(), name)
+	public, err := GenerateSigningKey(path)
+	if err != nil {
+		t.Fatalf("generating key failed with error %v", err)
+	}
+	private, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("loading the signing key failed with error %v", err)
+	}
+	if !public.Equal(private.Public()) {
+		t.Fatalf("the loaded signing key does not match the generated key")
+	}
+	return private, path + ".pub"
+}
+
+func TestSignedContainer(t *testing.T) {
+	build, buildPub := generateKey(t, "build")
+	other, otherPub := generateKey(t, "other")
+	prog := workspacePrograms(t)["fib.gs"]
+	signed, err := EncodeContainer("fib", prog, ContainerOptions{SigningKey: build, Compression: CT_ZLIB})
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	unsigned, err := EncodeContainer("fib", prog, ContainerOptions{})
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	// a key ring may contain multiple keys
+	keyRing := filepath.Join(t.TempDir(), "trusted.pem")
+	buildKey, _ := os.ReadFile(buildPub)
+	otherKey, _ := os.ReadFile(otherPub)
+	if err := os.WriteFile(keyRing, append(otherKey, buildKey...), 0600); err != nil {
+		t.Fatalf("writing key ring failed with error %v", err)
+	}
+	trusted, err := LoadTrustedKeys(keyRing)
+	if err != nil {
+		t.Fatalf("loading trusted keys failed with error %v", err)
+	}
+	expectLength(trusted, 2, "trusted keys")
+	onlyOther, err := LoadTrustedKeys(otherPub)
+	if err != nil {
+		t.Fatalf("loading trusted keys failed with error %v", err)
+	}
+	accepted := []TrustPolicy{{}, {TrustedKeys: trusted}, {TrustedKeys: trusted, RequireSignature: true}}
+	for _, policy := range accepted {
+		container, err := DecodeTrustedContainer(signed, policy)
+		if err != nil {
+			t.Fatalf("decoding the signed container failed with error %v", err)
+		}
+		expectValue(string(container.Signer), string(build.Public().(ed25519.PublicKey)))
+		ret, err := NewRuntime().Exec(*container.Program)
+		if err != nil {
+			t.Fatalf("execution failed with error %v", err)
+		}
+		expectValue(*ret.Value.(*uint64), uint64(6765))
+	}
+	container, err := DecodeTrustedContainer(unsigned, TrustPolicy{TrustedKeys: trusted})
+	if err != nil {
+		t.Fatalf("an unsigned container should be accepted unless signatures are required but got %v", err)
+	}
+	expectValue(container.Signer == nil, true)
+	rejected := map[string]struct {
+		buff   []byte
+		policy TrustPolicy
+	}{
+		"unsigned":          {unsigned, TrustPolicy{TrustedKeys: trusted, RequireSignature: true}},
+		"untrusted":         {signed, TrustPolicy{TrustedKeys: onlyOther}},
+		"no trusted keys":   {signed, TrustPolicy{RequireSignature: true}},
+		"untrusted, strict": {signed, TrustPolicy{TrustedKeys: onlyOther, RequireSignature: true}},
+	}
+	for name, test := range rejected {
+		_, err := DecodeTrustedContainer(test.buff, test.policy)
+		if !errors.Is(err, ErrUntrustedProgram) {
+			t.Fatalf("decoding the %v container should fail with an untrusted error but got %v", name, err)
+		}
+		fmt.Printf("%v: %v\n", name, err)
+	}
+	// re-signing with another key must not be mistaken for the build key
+	resigned, err := EncodeContainer("fib", prog, ContainerOptions{SigningKey: other})
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	if _, err := DecodeTrustedContainer(resigned, TrustPolicy{TrustedKeys: trusted[1:2], RequireSignature: true}); !errors.Is(err, ErrUntrustedProgram) {
+		t.Fatalf("a program signed by another key should be rejected but got %v", err)
+	}
+}
+
+func TestSignedContainerTampered(t *testing.T) {
+	key, _ := generateKey(t, "build")
+	signed, err := EncodeContainer("fib", workspacePrograms(t)["fib.gs"], ContainerOptions{SigningKey: key})
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	headerEnd := containerPreambleSize + int(binary.LittleEndian.Uint32(signed[6:10]))
+	header := &encoding.ContainerHeader{}
+	if err := proto.Unmarshal(signed[containerPreambleSize:headerEnd], header); err != nil {
+		t.Fatalf("decoding the header failed with error %v", err)
+	}
+	signature := signed[headerEnd : headerEnd+ed25519.SignatureSize]
+	payload := signed[headerEnd+ed25519.SignatureSize:]
+	// tamper encodes the modified header followed by the signature and the payload
+	tamper := func(modify func(header *encoding.ContainerHeader), signature []byte) []byte {
+		modified := proto.Clone(header).(*encoding.ContainerHeader)
+		modify(modified)
+		buff, err := encodeContainer(BYTECODE_FORMAT_VERSION, modified, append(append([]byte{}, signature...), payload...), nil)
+		if err != nil {
+			t.Fatalf("encoding failed with error %v", err)
+		}
+		return buff
+	}
+	attacker, _ := generateKey(t, "attacker")
+	invalidSignature := append([]byte{}, signature...)
+	invalidSignature[0] ^= 0xff
+	tampered := map[string][]byte{
+		"renamed":           tamper(func(header *encoding.ContainerHeader) { header.Application = "evil" }, signature),
+		"other signer":      tamper(func(header *encoding.ContainerHeader) { header.PublicKey = attacker.Public().(ed25519.PublicKey) }, signature),
+		"invalid key":       tamper(func(header *encoding.ContainerHeader) { header.PublicKey = header.PublicKey[1:] }, signature),
+		"invalid signature": tamper(func(header *encoding.ContainerHeader) {}, invalidSignature),
+		"no signature":      signed[:headerEnd],
+	}
+	for name, buff := range tampered {
+		_, err := DecodeContainer(buff)
+		if !errors.Is(err, ErrUntrustedProgram) {
+			t.Fatalf("decoding the %v container should fail with an untrusted error but got %v", name, err)
+		}
+		fmt.Printf("%v: %v\n", name, err)
+	}
+	// stripping the signature turns the program into an unsigned one, which a strict runtime refuses
+	stripped := tamper(func(header *encoding.ContainerHeader) { header.Flags &^= uint32(CF_SIGNED) }, nil)
+	if _, err := DecodeTrustedContainer(stripped, TrustPolicy{RequireSignature: true}); !errors.Is(err, ErrUntrustedProgram) {
+		t.Fatalf("a program without signature should be rejected but got %v", err)
+	}
+	// the payload is covered by the checksum, which is covered by the signature
+	corrupted := append([]byte{}, signed...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	if _, err := DecodeContainer(corrupted); !errors.Is(err, ErrIncompatibleContainer) {
+		t.Fatalf("a corrupted program should be rejected but got %v", err)
+	}
+}
+
+func TestSignedContainerRawHeader(t *testing.T) {
+	key, _ := generateKey(t, "build")
+	payload, err := EncodeProgram(workspacePrograms(t)["fib.gs"])
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	checksum := sha256.Sum256(payload)
+	// the fields of the header are encoded in reverse order, which re-encoding the decoded header would not reproduce
+	first, _ := proto.Marshal(&encoding.ContainerHeader{PublicKey: key.Public().(ed25519.PublicKey)})
+	second, _ := proto.Marshal(&encoding.ContainerHeader{CompilerVersion: COMPILER_VERSION, Flags: uint32(CF_SIGNED), Checksum: checksum[:]})
+	encHeader := append(first, second...)
+	buff := make([]byte, containerPreambleSize)
+	copy(buff, containerMagic)
+	binary.LittleEndian.PutUint16(buff[4:6], BYTECODE_FORMAT_VERSION)
+	binary.LittleEndian.PutUint32(buff[6:10], uint32(len(encHeader)))
+	buff = append(buff, encHeader...)
+	buff = append(buff, ed25519.Sign(key, buff)...)
+	container, err := DecodeTrustedContainer(append(buff, payload...), TrustPolicy{RequireSignature: true, TrustedKeys: []ed25519.PublicKey{key.Public().(ed25519.PublicKey)}})
+	if err != nil {
+		t.Fatalf("decoding failed with error %v", err)
+	}
+	expectValue(container.Signer.Equal(key.Public()), true)
+}
+
+func TestInspectContainer(t *testing.T) {
+	key, _ := generateKey(t, "build")
+	signed, err := EncodeContainer("fib", workspacePrograms(t)["fib.gs"], ContainerOptions{SigningKey: key, Compression: CT_FLATE})
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	inspection, err := InspectContainer(signed)
+	if err != nil {
+		t.Fatalf("inspection failed with error %v", err)
+	}
+	expectValue(inspection.Container.Application, "fib")
+	expectValue(inspection.Container.Compression, CT_FLATE)
+	expectValue(inspection.Container.Signer.Equal(key.Public()), true)
+	expectValue(inspection.Container.Program == nil, true)
+	expectValue(inspection.UncompressedSize > 0, true)
+	if inspection.ChecksumError != nil || inspection.SignatureError != nil {
+		t.Fatalf("the container should be valid but got %v and %v", inspection.ChecksumError, inspection.SignatureError)
+	}
+	// containers that cannot be decoded are inspected instead of rejected
+	corrupted := append([]byte{}, signed...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	binary.LittleEndian.PutUint16(corrupted[4:6], BYTECODE_FORMAT_VERSION+1)
+	inspection, err = InspectContainer(corrupted)
+	if err != nil {
+		t.Fatalf("inspection failed with error %v", err)
+	}
+	expectValue(inspection.Container.FormatVersion, BYTECODE_FORMAT_VERSION+1)
+	expectValue(errors.Is(inspection.ChecksumError, ErrIncompatibleContainer), true)
+	expectValue(errors.Is(inspection.SignatureError, ErrUntrustedProgram), true)
+	if _, err := InspectContainer(signed[:containerPreambleSize]); !errors.Is(err, ErrIncompatibleContainer) {
+		t.Fatalf("inspecting a container without header should fail but got %v", err)
+	}
+}
+
+func TestLoadInvalidKeys(t *testing.T) {
+	_, public := generateKey(t, "build")
+	if _, err := LoadSigningKey(public); err == nil {
+		t.Fatalf("loading a public key as signing key should fail")
+	}
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("no keys here"), 0600); err != nil {
+		t.Fatalf("writing file failed with error %v", err)
+	}
+	if _, err := LoadTrustedKeys(empty); err == nil {
+		t.Fatalf("loading trusted keys from a file without keys should fail")
+	}
+	if _, err := LoadSigningKey(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Fatalf("loading a missing signing key should fail")
+	}
+}
@@ -20,6 +20,7 @@ const (
 	STRUCT          GSKeyword = "struct"
 	CONST           GSKeyword = "const"
 	BREAK           GSKeyword = "break"
+	CONTINUE        GSKeyword = "continue"
 	CLOSING_BRACKET GSKeyword = "}"
 	// these will be implemented once the compiler generally works
 	// EXPORTED GSKeyword = "exported"
@@ -28,11 +29,10 @@ const (
 	// DEFAULT  GSKeyword = "default"
 	// ASYNC    GSKeyword = "async"
 	// AWAIT    GSKeyword = "await"
-	// CONTINUE GSKeyword = "continue"
 )
 
 // iterable list of all keywords
-var KEYWORDS = [...]GSKeyword{FOR, FOREACH, LET, FUNC, GSK_RETURN, STRUCT, CONST, BREAK, CLOSING_BRACKET}
+var KEYWORDS = [...]GSKeyword{FOR, FOREACH, LET, FUNC, GSK_RETURN, STRUCT, CONST, BREAK, CONTINUE, CLOSING_BRACKET}
 
 // regex that matches any symbol in goscript
 var SYMBOL_NAME = regexp.MustCompile(`(?m)[a-zA-Z_]{1}[a-zA-Z0-9_]*`)
@@ -140,8 +140,9 @@ func parseFunctionBody(body string) []*IntermediateOperation {
 	ret := []*IntermediateOperation{}
 	// parse each line of the function body
 	lines := strings.Split(body, "\n")
-	for _, line := range lines {
+	for idx, line := range lines {
 		op := parseLine(line)
+		op.Line = idx
 		ret = append(ret, &op)
 	}
 	return ret
@@ -183,6 +184,8 @@ func parseKeyWordLine(line string, keyword string) IntermediateOperation {
 		return parseForeachLine(line)
 	case "break":
 		return parseBreakLine()
+	case "continue":
+		return parseContinueLine()
 	case "return":
 		return parseReturnLine(line)
 	case "}":
@@ -334,8 +337,6 @@ func realizeFunctionCall(token ExpressionToken) *Expression {
 
 var FUNCTION_ARG_REGEX = regexp.MustCompile(`(?m)(\(.*\))`)
 
-var ARG_SPLIT_REGEX = regexp.MustCompile(`(?m)[\s,(]+([^,\s()]*)`)
-
 func parseArgumentExpressions(exprs []string) []*Expression {
 	res := []*Expression{}
 	for _, expr := range exprs {
@@ -346,13 +347,28 @@ func parseArgumentExpressions(exprs []string) []*Expression {
 
 func getFunctionArgs(expr string) []string {
 	res := []string{}
-	argsMatch := FUNCTION_ARG_REGEX.FindString(expr)
-	argMatches := ARG_SPLIT_REGEX.FindAllStringSubmatch(argsMatch, -1)
-	for _, match := range argMatches {
-		if len(match) != 2 {
-			panic("invalid regex match in getFunctionArgs, this should never happen")
+	argsMatch := stripBrackets(FUNCTION_ARG_REGEX.FindString(expr))
+	// split the arguments on all commas that are neither nested in brackets nor part of a string
+	depth := 0
+	inString := false
+	current := ""
+	for _, char := range argsMatch {
+		switch {
+		case char == '"':
+			inString = !inString
+		case char == '(' && !inString:
+			depth++
+		case char == ')' && !inString:
+			depth--
+		case char == ',' && !inString && depth == 0:
+			res = append(res, strings.TrimSpace(current))
+			current = ""
+			continue
 		}
-		res = append(res, strings.TrimSpace(match[1]))
+		current += string(char)
+	}
+	if len(strings.TrimSpace(current)) > 0 || len(res) > 0 {
+		res = append(res, strings.TrimSpace(current))
 	}
 	return res
 }
@@ -837,6 +853,13 @@ func parseBreakLine() IntermediateOperation {
 	}
 }
 
+func parseContinueLine() IntermediateOperation {
+	return IntermediateOperation{
+		Type: IM_CONTINUE,
+		Args: []any{},
+	}
+}
+
 // match return statements 'return test'
 // G1 matches the name of the symbol being returned
 var RETURN_LINE_REGEX = regexp.MustCompile(`(?m)return\s?(.*)?$`)
@@ -919,7 +942,7 @@ func parseArguments(args string) []*IntermediateVar {
 			panic(fmt.Sprintf("typed variable token %v has an invalid segment length (expected 2 but got %v)%v", varWithName, len(words), words))
 		}
 		current := IntermediateVar{
-			Name: words[0],
+			Name: strings.TrimSuffix(words[0], ":"),
 			Type: parseTypeWithConstraint(words[1], VALID_TYPE),
 		}
 		ret = append(ret, &current)
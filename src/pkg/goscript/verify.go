@@ -0,0 +1,287 @@
+package goscript
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/*
+	The runtime trusts the program it executes, an invalid program fails with a go panic at some point during its
+	execution. Verify checks a program before it is executed, which is required for programs from untrusted sources:
+
+	- every operation has the arguments the runtime expects for its type (see operationArgs)
+	- symbol references are within the symbol table, jump targets and function references are within the program
+	- expressions use known operators, builtins exist and are called with the right number of arguments
+	- every function enters and exits scopes in a balanced way, every operation is reached with the same scope depth
+	  on every path, no path exits the scope of its function and no path runs past the end of the program
+
+	Functions begin at pc 0 (main) and at every target of a BO_FUNCTION_CALL.
+*/
+
+// ErrInvalidProgram is wrapped by all errors returned by Verify
+var ErrInvalidProgram = errors.New("invalid program")
+
+// bindableTypes are the types a symbol can be bound to, they must match defaultValuePtrOf
+var bindableTypes = map[BinaryType]bool{
+	BT_INT8: true, BT_INT16: true, BT_INT32: true, BT_INT64: true,
+	BT_UINT8: true, BT_UINT16: true, BT_UINT32: true, BT_UINT64: true,
+	BT_BYTE: true, BT_FLOAT32: true, BT_FLOAT64: true, BT_STRING: true, BT_CHAR: true, BT_LIST: true, BT_NOTYPE: true,
+}
+
+// VARIADIC is the arity of builtins that take at least one argument
+const VARIADIC = -1
+
+// builtinArity is the number of arguments of every builtin that is implemented by the runtime
+var builtinArity = map[BuiltinFunction]int{
+	BF_LEN: 1, BF_PRINT: 1, BF_PRINTLN: 1, BF_PRINTF: VARIADIC, BF_INPUT: 0, BF_INPUTLN: 0,
+	BF_TOUINT8: 1, BF_TOUINT16: 1, BF_TOUINT32: 1, BF_TOUINT64: 1,
+	BF_TOINT8: 1, BF_TOINT16: 1, BF_TOINT32: 1, BF_TOINT64: 1,
+	BF_TOFLOAT32: 1, BF_TOFLOAT64: 1, BF_TOSTRING: 1, BF_TOCHAR: 1, BF_TOBYTE: 1,
+}
+
+type verifier struct {
+	program   *Program
+	functions map[int]bool // the base addresses of all functions
+}
+
+// Verify checks that the program can be executed by the runtime without failing due to malformed bytecode
+func Verify(program *Program) error {
+	v := &verifier{program: program, functions: map[int]bool{0: true}}
+	if len(program.Operations) == 0 {
+		return fmt.Errorf("%w: the program has no operations", ErrInvalidProgram)
+	}
+	if program.SymbolTableSize < 0 {
+		return fmt.Errorf("%w: negative symbol table size %v", ErrInvalidProgram, program.SymbolTableSize)
+	}
+	for pc := range program.Operations {
+		if err := v.verifyOperation(&program.Operations[pc]); err != nil {
+			return v.errorAt(pc, err)
+		}
+	}
+	return v.verifyScopes()
+}
+
+// errorAt wraps the error with the position of the operation
+func (v *verifier) errorAt(pc int, err error) error {
+	if v.program.Debug != nil {
+		return fmt.Errorf("%w at [%v] %v: %v", ErrInvalidProgram, pc, v.program.Debug.describe(pc), err)
+	}
+	return fmt.Errorf("%w at [%v]: %v", ErrInvalidProgram, pc, err)
+}
+
+func (v *verifier) verifyOperation(op *BinaryOperation) error {
+	kinds, ok := operationArgs[op.Type]
+	if !ok {
+		return fmt.Errorf("unknown operation type %v", op.Type)
+	}
+	if len(op.Args) != len(kinds) {
+		return fmt.Errorf("operation type %v expects %v arguments but has %v", op.Type, len(kinds), len(op.Args))
+	}
+	for i, kind := range kinds {
+		switch kind {
+		case AK_INT:
+			if _, ok := op.Args[i].(int); !ok {
+				return fmt.Errorf("argument %v of operation type %v must be an int but is %T", i, op.Type, op.Args[i])
+			}
+		case AK_TYPE:
+			if _, ok := op.Args[i].(BinaryType); !ok {
+				return fmt.Errorf("argument %v of operation type %v must be a type but is %T", i, op.Type, op.Args[i])
+			}
+		case AK_EXPRESSION:
+			expr, ok := op.Args[i].(*Expression)
+			if !ok || expr == nil {
+				return fmt.Errorf("argument %v of operation type %v must be an expression but is %T", i, op.Type, op.Args[i])
+			}
+			if err := v.verifyExpression(expr); err != nil {
+				return err
+			}
+		}
+	}
+	switch op.Type {
+	case ASSIGN, INDEX_ASSIGN:
+		return v.verifySymbol(op.Args[0].(int))
+	case BIND:
+		if err := v.verifySymbol(op.Args[0].(int)); err != nil {
+			return err
+		}
+		return verifyBindable(op.Args[1].(BinaryType))
+	case GROW:
+		if err := v.verifySymbol(op.Args[0].(int)); err != nil {
+			return err
+		}
+		if op.Args[1].(int) < 0 {
+			return fmt.Errorf("cannot grow by a negative amount %v", op.Args[1])
+		}
+		return verifyBindable(op.Args[2].(BinaryType))
+	case SHRINK:
+		if err := v.verifySymbol(op.Args[0].(int)); err != nil {
+			return err
+		}
+		if op.Args[1].(int) < 0 {
+			return fmt.Errorf("cannot shrink by a negative amount %v", op.Args[1])
+		}
+	case JUMP:
+		return v.verifyJumpTarget(op.Args[0].(int))
+	case JUMP_IF, JUMP_IF_NOT:
+		return v.verifyJumpTarget(op.Args[1].(int))
+	}
+	return nil
+}
+
+func (v *verifier) verifyExpression(expr *Expression) error {
+	switch expr.Operator {
+	case BO_CONSTANT:
+		if expr.Value == nil {
+			return fmt.Errorf("constant expression without value")
+		}
+		// the encoder checks that the go type of the value matches its type
+		if _, err := encodeTypedValue(expr.Value); err != nil {
+			return err
+		}
+	case BO_VSYMBOL:
+		return v.verifySymbol(expr.Ref)
+	case BO_FUNCTION_CALL:
+		if expr.Ref < 0 || expr.Ref >= len(v.program.Operations) {
+			return fmt.Errorf("call to function at [%v] outside of the program", expr.Ref)
+		}
+		v.functions[expr.Ref] = true
+		return v.verifyArgs(expr.Args, true)
+	case BO_BUILTIN_CALL:
+		builtin := BuiltinFunction(expr.Ref)
+		arity, ok := builtinArity[builtin]
+		if !ok {
+			return fmt.Errorf("unknown builtin %v", expr.Ref)
+		}
+		if (arity == VARIADIC && len(expr.Args) == 0) || (arity != VARIADIC && len(expr.Args) != arity) {
+			return fmt.Errorf("builtin %v called with %v arguments", expr.Ref, len(expr.Args))
+		}
+		return v.verifyArgs(expr.Args, false)
+	case BO_INDEX_INTO:
+		if err := v.verifySymbol(expr.Ref); err != nil {
+			return err
+		}
+		if expr.Value == nil {
+			return fmt.Errorf("index expression without index")
+		}
+		index, ok := expr.Value.Value.(*Expression)
+		if !ok || index == nil {
+			return fmt.Errorf("index expression without index")
+		}
+		return v.verifyExpression(index)
+	case BO_NULLEXPR:
+	case BO_PLUS, BO_MINUS, BO_MULTIPLY, BO_DIVIDE, BO_EQUALS, BO_GREATER, BO_LESSER, BO_GREATER_EQUALS, BO_LESSER_EQUALS:
+		// arithmetic operators write their result into a value of the result type, see newResult
+		arithmetic := expr.Operator == BO_PLUS || expr.Operator == BO_MINUS || expr.Operator == BO_MULTIPLY || expr.Operator == BO_DIVIDE
+		if arithmetic && (expr.Value == nil || !expr.Value.Type.isNumeric()) {
+			return fmt.Errorf("operator %v requires a numeric result type", expr.Operator)
+		}
+		if expr.LeftExpression == nil || expr.RightExpression == nil {
+			return fmt.Errorf("operator %v requires two operands", expr.Operator)
+		}
+		if err := v.verifyExpression(expr.LeftExpression); err != nil {
+			return err
+		}
+		return v.verifyExpression(expr.RightExpression)
+	default:
+		return fmt.Errorf("unknown operator %v", byte(expr.Operator))
+	}
+	return nil
+}
+
+// verifyArgs verifies the arguments of a call, the parameter symbols of function calls must exist in the callee
+func (v *verifier) verifyArgs(args []*FunctionArgument, function bool) error {
+	for _, arg := range args {
+		if arg == nil || arg.Expression == nil {
+			return fmt.Errorf("call with a missing argument")
+		}
+		if function {
+			if err := v.verifySymbol(arg.SymbolRef); err != nil {
+				return err
+			}
+		}
+		if err := v.verifyExpression(arg.Expression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *verifier) verifySymbol(ref int) error {
+	if ref < 0 || ref >= v.program.SymbolTableSize {
+		return fmt.Errorf("symbol %v outside of the symbol table of size %v", ref, v.program.SymbolTableSize)
+	}
+	return nil
+}
+
+// verifyJumpTarget checks the target of a jump, which is the pc before the operation that is executed next
+func (v *verifier) verifyJumpTarget(target int) error {
+	if target+1 < 0 || target+1 >= len(v.program.Operations) {
+		return fmt.Errorf("jump to [%v] outside of the program", target+1)
+	}
+	return nil
+}
+
+func verifyBindable(symbolType BinaryType) error {
+	if !bindableTypes[symbolType] {
+		return fmt.Errorf("cannot bind a symbol of type %v", byte(symbolType))
+	}
+	return nil
+}
+
+// verifyScopes follows every path through every function, tracking the number of scopes that are open
+func (v *verifier) verifyScopes() error {
+	depths := make([]int, len(v.program.Operations))
+	for pc := range depths {
+		depths[pc] = -1
+	}
+	bases := make([]int, 0, len(v.functions))
+	for base := range v.functions {
+		bases = append(bases, base)
+	}
+	sort.Ints(bases)
+	for _, base := range bases {
+		if depths[base] > 0 {
+			return v.errorAt(base, fmt.Errorf("function is reached with %v open scopes", depths[base]))
+		}
+		if depths[base] == 0 {
+			continue
+		}
+		depths[base] = 0
+		pending := []int{base}
+		for len(pending) > 0 {
+			pc := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			op := &v.program.Operations[pc]
+			depth := depths[pc]
+			successors := []int{pc + 1}
+			switch op.Type {
+			case ENTER_SCOPE:
+				depth++
+			case EXIT_SCOPE:
+				if depth == 0 {
+					return v.errorAt(pc, fmt.Errorf("exits the scope of the function"))
+				}
+				depth--
+			case RETURN:
+				successors = nil
+			case JUMP:
+				successors = []int{op.Args[0].(int) + 1}
+			case JUMP_IF, JUMP_IF_NOT:
+				successors = append(successors, op.Args[1].(int)+1)
+			}
+			for _, next := range successors {
+				if next >= len(v.program.Operations) {
+					return v.errorAt(pc, fmt.Errorf("execution continues past the end of the program"))
+				}
+				if depths[next] == -1 {
+					depths[next] = depth
+					pending = append(pending, next)
+				} else if depths[next] != depth {
+					return v.errorAt(next, fmt.Errorf("reached with %v and %v open scopes", depths[next], depth))
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package goscript
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestVerifyWorkspace(t *testing.T) {
+	for name, prog := range workspacePrograms(t) {
+		if err := Verify(prog); err != nil {
+			t.Fatalf("compiled program %v should be valid but got %v", name, err)
+		}
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	constant := func() *Expression { return NewConstantExpression(ptr(uint64(1)), BT_UINT64) }
+	condition := func() *Expression {
+		return &Expression{Operator: BO_LESSER, LeftExpression: NewVSymbolExpression(0), RightExpression: constant()}
+	}
+	ret := NewReturnValueOp(constant())
+	invalid := map[string][]BinaryOperation{
+		"empty":                  {},
+		"unknown operation":      {{Type: 99}, ret},
+		"missing argument":       {{Type: JUMP}, ret},
+		"string argument":        {{Type: JUMP, Args: []any{"0"}}, ret},
+		"nil expression":         {{Type: EXPRESSION, Args: []any{(*Expression)(nil)}}, ret},
+		"type as symbol":         {{Type: BIND, Args: []any{BT_UINT64, BT_UINT64}}, ret},
+		"symbol out of range":    {NewBindOp(1, BT_UINT64), ret},
+		"negative symbol":        {NewAssignExpressionOp(-1, constant()), ret},
+		"unbindable type":        {NewBindOp(0, BT_BOOLEAN), ret},
+		"negative grow":          {NewGrowOperation(0, -1, BT_UINT64), ret},
+		"jump out of range":      {NewJumpOp(5), ret},
+		"jump before program":    {NewJumpIfOp(-1, condition()), ret},
+		"undefined symbol read":  {NewReturnValueOp(NewVSymbolExpression(3))},
+		"constant mismatch":      {NewReturnValueOp(NewConstantExpression(ptr("1"), BT_UINT64))},
+		"constant without value": {NewReturnValueOp(&Expression{Operator: BO_CONSTANT})},
+		"unknown operator":       {NewReturnValueOp(&Expression{Operator: 99})},
+		"missing operand":        {NewReturnValueOp(&Expression{Operator: BO_PLUS, LeftExpression: constant()})},
+		"placeholder":            {NewReturnValueOp(&Expression{Operator: BO_FUNCTION_CALL_PLACEHOLDER})},
+		"function out of range":  {NewReturnValueOp(NewFunctionExpression(7, nil))},
+		"parameter out of range": {
+			NewReturnValueOp(NewFunctionExpression(1, []*FunctionArgument{{Expression: constant(), SymbolRef: 4}})), ret,
+		},
+		"unknown builtin":       {NewReturnValueOp(&Expression{Operator: BO_BUILTIN_CALL, Ref: 99})},
+		"unimplemented builtin": {NewReturnValueOp(&Expression{Operator: BO_BUILTIN_CALL, Ref: int(BF_MAX), Args: []*FunctionArgument{{Expression: constant()}}})},
+		"builtin arity":         {NewReturnValueOp(&Expression{Operator: BO_BUILTIN_CALL, Ref: int(BF_LEN)})},
+		"printf without format": {NewReturnValueOp(&Expression{Operator: BO_BUILTIN_CALL, Ref: int(BF_PRINTF)})},
+		"index without index":   {NewReturnValueOp(&Expression{Operator: BO_INDEX_INTO, Ref: 0})},
+		"exit function scope":   {NewExitScopeOp(), ret},
+		"falls off the end":     {NewEnterScope(), NewExitScopeOp()},
+		"unbalanced branches": {
+			NewJumpIfNotOp(2, condition()), // skip the ENTER_SCOPE if the condition is false
+			NewEnterScope(),
+			ret,
+		},
+		"unbalanced loop": {
+			NewEnterScope(),
+			NewJumpOp(0), // re-enter the scope forever
+		},
+		"function in scope": {
+			NewEnterScope(),
+			NewExpressionOp(NewFunctionExpression(2, nil)),
+			ret, // the callee begins here, but it is also reached from main with an open scope
+		},
+		"arithmetic without result type": {NewReturnValueOp(&Expression{Operator: BO_PLUS, LeftExpression: constant(), RightExpression: constant()})},
+	}
+	for name, ops := range invalid {
+		err := Verify(&Program{Operations: ops, SymbolTableSize: 1})
+		if !errors.Is(err, ErrInvalidProgram) {
+			t.Fatalf("verifying the program with %v should fail but got %v", name, err)
+		}
+		fmt.Printf("%v: %v\n", name, err)
+	}
+	if err := Verify(&Program{Operations: []BinaryOperation{ret}, SymbolTableSize: -1}); !errors.Is(err, ErrInvalidProgram) {
+		t.Fatalf("verifying a program with a negative symbol table size should fail but got %v", err)
+	}
+}
+
+func TestVerifyValid(t *testing.T) {
+	constant := NewConstantExpression(ptr(uint64(1)), BT_UINT64)
+	condition := &Expression{Operator: BO_LESSER, LeftExpression: NewVSymbolExpression(0), RightExpression: constant}
+	prog := &Program{SymbolTableSize: 2, Operations: []BinaryOperation{
+		NewBindOp(0, BT_UINT64),
+		NewEnterScope(),
+		NewJumpIfNotOp(5, condition), // leave the loop
+		NewAssignExpressionOp(0, NewFunctionExpression(7, []*FunctionArgument{{Expression: NewVSymbolExpression(0), SymbolRef: 1}})),
+		NewJumpOp(2), // back to the condition
+		NewExitScopeOp(),
+		NewReturnValueOp(NewVSymbolExpression(0)),
+		// the callee returns from within a scope, which is discarded with its frame
+		NewEnterScope(),
+		NewExpressionOp(&Expression{Operator: BO_BUILTIN_CALL, Ref: int(BF_PRINTF), Args: []*FunctionArgument{{Expression: NewConstantExpression(ptr("%v\n"), BT_STRING)}, {Expression: NewVSymbolExpression(1)}}}),
+		NewReturnValueOp(&Expression{Operator: BO_PLUS, LeftExpression: NewVSymbolExpression(1), RightExpression: constant, Value: &BinaryTypedValue{Type: BT_UINT64}}),
+	}}
+	if err := Verify(prog); err != nil {
+		t.Fatalf("the program should be valid but got %v", err)
+	}
+	ret, err := NewRuntime().Exec(*prog)
+	if err != nil {
+		t.Fatalf("execution failed with error %v", err)
+	}
+	expectValue(*ret.Value.(*uint64), uint64(1))
+}
+
+func TestVerifyReportsPosition(t *testing.T) {
+	prog := workspacePrograms(t)["debug.gs"]
+	pc := prog.Debug.Functions[len(prog.Debug.Functions)-1].Base
+	prog.Operations[pc] = NewJumpOp(len(prog.Operations))
+	err := Verify(prog)
+	if !errors.Is(err, ErrInvalidProgram) {
+		t.Fatalf("verifying the modified program should fail but got %v", err)
+	}
+	expectValue(err.Error(), fmt.Sprintf("invalid program at [%v] %v: jump to [%v] outside of the program", pc, prog.Debug.describe(pc), len(prog.Operations)))
+}
+
+func TestVerifyCorrupted(t *testing.T) {
+	encoded, err := EncodeProgram(workspacePrograms(t)["fib.gs"])
+	if err != nil {
+		t.Fatalf("encoding failed with error %v", err)
+	}
+	// the verifier must never panic, whatever the decoder accepts
+	for i := 0; i < len(encoded); i++ {
+		corrupted := append([]byte{}, encoded...)
+		corrupted[i] ^= 0xff
+		if prog, err := DecodeProgram(corrupted); err == nil {
+			Verify(prog)
+		}
+		if prog, err := DecodeProgram(encoded[:i]); err == nil {
+			Verify(prog)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package goscript
+
+import (
+	"fmt"
+	"sort"
+)
+
+type WarningType byte
+
+const (
+	WT_UNUSED_VARIABLE  WarningType = 1 // a variable is declared but never read
+	WT_UNUSED_PARAMETER WarningType = 2 // a function parameter is never read
+	WT_UNUSED_IMPORT    WarningType = 3 // a module is imported but none of its symbols are referenced
+)
+
+// CompilerWarning describes a problem in the source code that does not prevent compilation
+type CompilerWarning struct {
+	Type     WarningType // what kind of problem was found
+	Location string      // the function or file in which the problem was found
+	Name     string      // the name of the offending symbol or import
+}
+
+func (w CompilerWarning) String() string {
+	switch w.Type {
+	case WT_UNUSED_VARIABLE:
+		return fmt.Sprintf("%v: variable %v is declared but never used", w.Location, w.Name)
+	case WT_UNUSED_PARAMETER:
+		return fmt.Sprintf("%v: parameter %v is never used", w.Location, w.Name)
+	case WT_UNUSED_IMPORT:
+		return fmt.Sprintf("%v: module %v is imported but never used", w.Location, w.Name)
+	default:
+		return fmt.Sprintf("%v: unknown warning for %v", w.Location, w.Name)
+	}
+}
+
+// findUnusedSymbols emits a warning for every parameter and variable of the function that is never read
+func (c *Compiler) findUnusedSymbols(def *FunctionDefinition) {
+	used := make(map[string]bool)
+	for _, op := range def.Operations {
+		for _, expr := range operationExpressions(op) {
+			collectSymbolNames(expr, used)
+		}
+	}
+	for _, param := range def.Accepts {
+		if !used[param.Name] {
+			c.warn(CompilerWarning{Type: WT_UNUSED_PARAMETER, Location: def.Name, Name: param.Name})
+		}
+	}
+	for _, op := range def.Operations {
+		if op.Type != IM_ASSIGN && op.Type != IM_FOR {
+			continue
+		}
+		if name := op.Args[0].(string); !used[name] {
+			c.warn(CompilerWarning{Type: WT_UNUSED_VARIABLE, Location: def.Name, Name: name})
+		}
+	}
+}
+
+// collectSymbolNames adds the names of all symbols referenced in the expression to the out map
+func collectSymbolNames(expr *Expression, out map[string]bool) {
+	switch expr.Operator {
+	case BO_VSYMBOL_PLACEHOLDER:
+		out[expr.Value.Value.(string)] = true
+	case BO_FUNCTION_CALL_PLACEHOLDER:
+		for _, arg := range expr.Value.Value.(*FunctionCallPlaceholder).Args {
+			if arg != nil {
+				collectSymbolNames(arg, out)
+			}
+		}
+	}
+	if expr.LeftExpression != nil {
+		collectSymbolNames(expr.LeftExpression, out)
+	}
+	if expr.RightExpression != nil {
+		collectSymbolNames(expr.RightExpression, out)
+	}
+}
+
+// findUnusedImports returns a warning for every import of the main file or a module whose symbols are never referenced.
+// This must run before the preprocessor rewrites the module references.
+func findUnusedImports(source *ApplicationSource) []CompilerWarning {
+	warnings := unusedImportsOf(source.ApplicationFile.Path, source.ApplicationFile.Content, source.ApplicationFile.Imports)
+	for _, mod := range source.Modules {
+		content := ""
+		for _, file := range mod.Files {
+			content += file.Content
+		}
+		imports, err := getImportsFromSourceText(content)
+		if err != nil {
+			// the dependency resolver has already parsed these imports, so this cannot happen
+			continue
+		}
+		byAlias := make(map[string]*ImportDirective)
+		for _, imp := range imports {
+			byAlias[imp.Alias] = imp
+		}
+		warnings = append(warnings, unusedImportsOf(mod.ImportPath, content, byAlias)...)
+	}
+	return warnings
+}
+
+func unusedImportsOf(location string, content string, imports map[string]*ImportDirective) []CompilerWarning {
+	// collect the aliases of all external symbols that are referenced outside of strings
+	mask := getStringMask(content)
+	used := make(map[string]bool)
+	for _, match := range EXTERNAL_SYMBOL_REGEX.FindAllStringSubmatchIndex(content, -1) {
+		if mask[match[0]] {
+			continue
+		}
+		used[content[match[2]:match[3]]] = true
+	}
+	aliases := []string{}
+	for alias := range imports {
+		if !used[alias] {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	warnings := []CompilerWarning{}
+	for _, alias := range aliases {
+		warnings = append(warnings, CompilerWarning{Type: WT_UNUSED_IMPORT, Location: location, Name: imports[alias].RawDirective})
+	}
+	return warnings
+}